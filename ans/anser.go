@@ -1,7 +1,7 @@
 package ans
 
 import (
-	"encoding/binary"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -23,25 +23,41 @@ type IAnswer interface {
 	Write(int32, *[]byte, int32) error
 	//
 	Disconnect(cid int32) error
+	// 以 TLS 加密連線(須於 Listen 之前呼叫)
+	UseTLS(config *tls.Config) error
+	// 停止監聽(Unix domain socket 將一併移除 socket 檔案)
+	StopListen() error
 }
 
-func NewAnser(socketType define.SocketType, laddr *net.TCPAddr, nConnect int32, nWork int32) (IAnswer, error) {
+func NewAnser(socketType define.SocketType, laddr net.Addr, nConnect int32, nWork int32) (IAnswer, error) {
 	switch socketType {
 	case define.Tcp0:
 		return NewTcp0Anser(laddr, nConnect, nWork)
 	case define.Http:
 		return NewHttpAnser(laddr, nConnect, nWork)
+	case define.WebSocket:
+		return NewWebSocketAnser(laddr, nConnect, nWork)
+	case define.Udp:
+		return NewUdpAnser(laddr, nConnect, nWork)
+	case define.Line:
+		return NewLineAnser(laddr, nConnect, nWork)
 	default:
+		// 由 base.RegisterCodec 註冊的自定義協定
+		if factory, ok := base.GetCodec(socketType); ok {
+			return NewCodecAnser(laddr, nConnect, nWork, factory)
+		}
 		return nil, fmt.Errorf("invalid socket type: %v", socketType)
 	}
 }
 
 type Anser struct {
-	// 連線位置
-	laddr *net.TCPAddr
+	// 連線位置(*net.TCPAddr 或 Unix domain socket 的 *net.UnixAddr)
+	laddr net.Addr
 	// 監聽連線物件
-	listener *net.TCPListener
-	// 讀取超時
+	listener net.Listener
+	// TLS 設置(非 TLS 連線時為 nil)
+	tlsConfig *tls.Config
+	// 讀取超時(0 表示不超時)
 	ReadTimeout time.Duration
 	// ==================================================
 	// 連線列表
@@ -64,8 +80,6 @@ type Anser struct {
 	preConn *base.Conn
 	// 數據讀取緩存
 	readBuffer []byte
-	// 位元組順序 (Byte Order)，即 位元組 的排列順序
-	order binary.ByteOrder
 
 	// ==================================================
 	// 連線緩存
@@ -93,15 +107,33 @@ type Anser struct {
 
 	// 當前連線是否應斷線
 	shouldCloseFunc func(error) bool
+
+	// 釋放連線物件前呼叫(可為 nil)，用於重置各連線的協定狀態
+	disconnectFunc func(cid int32)
 }
 
-func newAnser(laddr *net.TCPAddr, nConnect int32, nWork int32) (*Anser, error) {
-	listener, err := net.ListenTCP("tcp", laddr)
+func newAnser(laddr net.Addr, nConnect int32, nWork int32) (*Anser, error) {
+	var listener net.Listener
+	var err error
+
+	switch addr := laddr.(type) {
+	case *net.TCPAddr:
+		listener, err = net.ListenTCP("tcp", addr)
+	case *net.UnixAddr:
+		listener, err = base.ListenUnix(addr, utils.GosConfig.UnixSocketMode)
+	default:
+		err = errors.Errorf("Unsupported address type %T.", laddr)
+	}
 
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to listen at port %d.", laddr.Port)
+		return nil, errors.Wrapf(err, "Failed to listen at %s.", laddr)
 	}
 
+	return newAnserWithListener(laddr, listener, nConnect, nWork), nil
+}
+
+// 以 listener 接受連線(如 base.UdpListener 所建立的虛擬連線)
+func newAnserWithListener(laddr net.Addr, listener net.Listener, nConnect int32, nWork int32) *Anser {
 	a := &Anser{
 		laddr:      laddr,
 		listener:   listener,
@@ -110,7 +142,6 @@ func newAnser(laddr *net.TCPAddr, nConnect int32, nWork int32) (*Anser, error) {
 		maxConn:    nConnect,
 		conns:      base.NewConn(0, utils.GosConfig.ConnBufferSize),
 		readBuffer: make([]byte, utils.GosConfig.AnswerReadBuffer),
-		order:      binary.LittleEndian,
 		connBuffer: make(chan net.Conn, nWork),
 		works:      base.NewWork(0),
 	}
@@ -135,15 +166,21 @@ func newAnser(laddr *net.TCPAddr, nConnect int32, nWork int32) (*Anser, error) {
 		a.lastWork = nextWork
 	}
 
-	return a, nil
+	return a
 }
 
 // 監聽連線並註冊
 func (a *Anser) Listen() {
 	for {
-		conn, err := a.listener.AcceptTCP()
+		conn, err := a.listener.Accept()
 
 		if err != nil {
+			// 已停止監聽
+			if errors.Is(err, net.ErrClosed) {
+				utils.Info("停止監聽: %s", a.laddr)
+				return
+			}
+
 			utils.Error("接受客戶端連接異常: %+v", err.Error())
 			continue
 		}
@@ -155,6 +192,34 @@ func (a *Anser) Listen() {
 	}
 }
 
+// 以 TLS 加密連線(須於 Listen 之前呼叫)，config 須設置 Certificates 或 GetCertificate(如 base.CertReloader)
+// 驗證客戶端憑證(mTLS)時，設置 config.ClientAuth = tls.RequireAndVerifyClientCert 以及 config.ClientCAs
+func (a *Anser) UseTLS(config *tls.Config) error {
+	if config == nil {
+		return errors.New("TLS config is nil.")
+	}
+
+	if a.tlsConfig != nil {
+		return errors.Errorf("TLS is already used at %s.", a.laddr)
+	}
+
+	if len(config.Certificates) == 0 && config.GetCertificate == nil && config.GetConfigForClient == nil {
+		return errors.New("TLS config has no certificate.")
+	}
+
+	a.tlsConfig = config
+	a.listener = tls.NewListener(a.listener, config)
+	return nil
+}
+
+// 停止監聽，Listen 將隨之返回(已建立的連線不受影響)；Unix domain socket 將一併移除 socket 檔案
+func (a *Anser) StopListen() error {
+	if err := a.listener.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to close listener at %s.", a.laddr)
+	}
+	return nil
+}
+
 // 持續檢查是否有未完成的工作，若有，則呼叫外部定義的 workHandler 函式
 func (a *Anser) Handler() {
 
@@ -198,9 +263,9 @@ func (a *Anser) checkConnection() {
 				utils.Info("Conn(%d)", a.emptyConn.GetId())
 				// a.emptyConn.Index = a.index
 				a.emptyConn.NetConn = netConn
-				a.emptyConn.NetConn.SetReadDeadline(time.Now().Add(a.ReadTimeout))
+				a.emptyConn.NetConn.SetReadDeadline(a.readDeadline())
 				a.emptyConn.State = define.Connected
-				go a.emptyConn.Handler()
+				a.emptyConn.Start()
 
 				// 更新空連線指標位置
 				a.updateEmptyConn()
@@ -209,7 +274,9 @@ func (a *Anser) checkConnection() {
 				a.nConn += 1
 				a.index += 1
 			} else {
-				utils.Warn("TODO: 需要加開伺服器")
+				// 連線數已達上限，關閉連線以釋放資源(UDP 的虛擬連線將一併從 UdpListener 移除)
+				utils.Warn("TODO: 需要加開伺服器，連線數已達上限，關閉來自 %s 的連線", netConn.RemoteAddr())
+				netConn.Close()
 			}
 		default:
 			return
@@ -217,6 +284,14 @@ func (a *Anser) checkConnection() {
 	}
 }
 
+// 根據 ReadTimeout 計算讀取期限，ReadTimeout 為 0 時返回零值(不超時)
+func (a *Anser) readDeadline() time.Time {
+	if a.ReadTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(a.ReadTimeout)
+}
+
 // 連線處理
 func (a *Anser) connectedHandler() {
 	var packet *base.Packet
@@ -225,7 +300,7 @@ func (a *Anser) connectedHandler() {
 	// TODO: 處理主動斷線
 	select {
 	// 封包事件
-	case packet = <-a.currConn.ReadCh:
+	case packet = <-a.currConn.ReadChannel():
 
 		// 封包讀取發生異常
 		if packet.Error != nil {
@@ -262,7 +337,7 @@ func (a *Anser) connectedHandler() {
 		a.currConn.SetReadBuffer(packet)
 
 		// 更新斷線時間(NOTE: 若斷線時間與客戶端睡眠時間相同，會變成讀取錯誤，而非 timeout 錯誤，造成誤判)
-		err = a.currConn.NetConn.SetReadDeadline(time.Now().Add(a.ReadTimeout))
+		err = a.currConn.NetConn.SetReadDeadline(a.readDeadline())
 
 		if err != nil {
 			utils.Error("DeadlineError: %+v", err)
@@ -313,6 +388,10 @@ func (a *Anser) disconnectHandler() {
 			utils.Info("cid: %d", a.currConn.GetId())
 			a.nConn -= 1
 
+			if a.disconnectFunc != nil {
+				a.disconnectFunc(a.currConn.GetId())
+			}
+
 			if a.preConn == nil {
 				// 更新連線物件起始位置
 				a.conns = a.currConn.Next
@@ -357,6 +436,11 @@ func (a *Anser) Disconnect(cid int32) error {
 	if c == nil {
 		return errors.Errorf("Not found cid %d", cid)
 	}
+
+	if a.disconnectFunc != nil {
+		a.disconnectFunc(cid)
+	}
+
 	c.Release()
 	return nil
 }
@@ -393,6 +477,13 @@ func (a *Anser) dealWork() {
 		case base.WORK_DONE:
 			finished = a.relinkWork(finished, true)
 		case base.WORK_NEED_PROCESS:
+			// 提供 TLS 連線狀態，使工作處理函式可取得對方的憑證
+			if a.tlsConfig != nil && a.currWork.TLS == nil {
+				if c := a.getConn(a.currWork.Index); c != nil {
+					a.currWork.TLS = c.TLSState()
+				}
+			}
+
 			// 對工作進行處理
 			a.workHandler(a.currWork)
 
@@ -405,14 +496,14 @@ func (a *Anser) dealWork() {
 				yet = a.relinkWork(yet, false)
 			case base.WORK_OUTPUT:
 				// 將向客戶端傳輸數據，寫入 writeBuffer
-				a.writeFunc(a.currWork.Index, &a.currWork.Data, a.currWork.Length)
+				a.writeWork()
 
 				// 將完成的工作加入 finished，並更新 work 所指向的工作結構
 				finished = a.relinkWork(finished, true)
 			}
 		case base.WORK_OUTPUT:
 			// 將向客戶端傳輸數據，寫入 writeBuffer
-			a.writeFunc(a.currWork.Index, &a.currWork.Data, a.currWork.Length)
+			a.writeWork()
 
 			// 將完成的工作加入 finished，並更新 work 所指向的工作結構
 			finished = a.relinkWork(finished, true)
@@ -441,6 +532,22 @@ func (a *Anser) dealWork() {
 	}
 }
 
+// 寫出工作的數據，寫出失敗(如 Codec 無法封裝數據)時，立即中斷該連線，避免對方持續等待回應
+func (a *Anser) writeWork() {
+	err := a.writeFunc(a.currWork.Index, &a.currWork.Data, a.currWork.Length)
+
+	if err == nil {
+		return
+	}
+
+	utils.Error("Conn(%d) failed to write work: %+v", a.currWork.Index, err)
+
+	if c := a.getConn(a.currWork.Index); c != nil && c.State == define.Connected {
+		c.State = define.Disconnect
+		c.SetDisconnectTime(0)
+	}
+}
+
 func (a *Anser) Write(cid int32, data *[]byte, length int32) error {
 	c := a.getConn(cid)
 
@@ -0,0 +1,133 @@
+package ans
+
+import (
+	"net"
+	"time"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// CodecAnser
+// 以 base.Codec 切分與封裝訊息的自定義協定，每個訊息作為一個 base.Work 交由 workHandler 處理
+// 工作以 Send 寫出的數據，以及 Write 寫出的數據，皆由該連線的 Codec 封裝
+// ====================================================================================================
+
+type CodecAnser struct {
+	*Anser
+	codecs    []base.Codec
+	currCodec base.Codec
+}
+
+func NewCodecAnser(laddr net.Addr, nConnect int32, nWork int32, factory base.CodecFactory) (IAnswer, error) {
+	if factory == nil {
+		return nil, errors.New("Failed to new CodecAnser: codec factory is nil.")
+	}
+
+	var err error
+	a := &CodecAnser{
+		codecs:    make([]base.Codec, nConnect),
+		currCodec: nil,
+	}
+
+	// ===== Anser =====
+	a.Anser, err = newAnser(laddr, nConnect, nWork)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new CodecAnser.")
+	}
+
+	a.Anser.ReadTimeout = utils.GosConfig.CodecReadTimeout
+
+	// ===== Codec =====
+	var i int32
+
+	for i = 0; i < nConnect; i++ {
+		a.codecs[i] = factory()
+	}
+
+	//////////////////////////////////////////////////
+	// 自定義函式
+	//////////////////////////////////////////////////
+	a.readFunc = a.read
+	a.writeFunc = a.write
+	a.shouldCloseFunc = a.shouldClose
+	a.disconnectFunc = a.onDisconnect
+	return a, nil
+}
+
+// 監聽連線並註冊
+func (a *CodecAnser) Listen() {
+	a.Anser.Listen()
+}
+
+func (a *CodecAnser) read() bool {
+	cid := a.currConn.GetId()
+	a.currCodec = a.codecs[cid]
+
+	// 工作結構不足時，保留至下次迴圈
+	for a.currWork != nil {
+		message, ok, err := a.currCodec.Decode(a.currConn, &a.readBuffer)
+
+		// 數據不合法，立即斷線
+		if err != nil {
+			utils.Error("Conn(%d) %+v", cid, err)
+			a.currConn.State = define.Disconnect
+			a.currConn.SetDisconnectTime(0)
+			return false
+		}
+
+		if !ok {
+			break
+		}
+
+		a.currWork.Index = cid
+		a.currWork.RequestTime = time.Now().UTC()
+		a.currWork.State = base.WORK_NEED_PROCESS
+		a.currWork.Body.AddRawData(message)
+		a.currWork.Body.ResetIndex()
+
+		// 指向下一個工作結構
+		a.currWork = a.currWork.Next
+	}
+
+	return true
+}
+
+func (a *CodecAnser) write(cid int32, data *[]byte, length int32) error {
+	return a.Write(cid, data, length)
+}
+
+// 供外部寫出數據，由該連線的 Codec 封裝
+func (a *CodecAnser) Write(cid int32, data *[]byte, length int32) error {
+	if cid < 0 || int(cid) >= len(a.codecs) {
+		return errors.Errorf("There is no cid equals to %d.", cid)
+	}
+
+	frame, err := a.codecs[cid].Encode((*data)[:length])
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write to conn(%d).", cid)
+	}
+
+	return a.Anser.Write(cid, &frame, int32(len(frame)))
+}
+
+// 由外部定義 workHandler，定義如何處理工作
+func (a *CodecAnser) SetWorkHandler(handler func(*base.Work)) {
+	a.Anser.workHandler = handler
+}
+
+// 釋放連線物件前，重置讀取狀態
+func (a *CodecAnser) onDisconnect(cid int32) {
+	a.codecs[cid].Reset()
+}
+
+// 當前連線是否應斷線
+func (a *CodecAnser) shouldClose(err error) bool {
+	return a.Anser.shouldClose(err)
+}
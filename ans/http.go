@@ -2,9 +2,10 @@ package ans
 
 import (
 	"fmt"
+	"html/template"
 	"net"
+	"net/textproto"
 	"path"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,7 +18,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-type HandlerFunc func(c *ghttp.Context)
+type HandlerFunc = ghttp.HandlerFunc
 type HandlerChain []HandlerFunc
 
 // ====================================================================================================
@@ -28,8 +29,14 @@ type HttpAnser struct {
 	*Anser
 	*Router
 
-	// key1: Method(Get/Post); key2: node number of EndPoint; value: []*EndPoint
+	// 所有已註冊的 EndPoint(依註冊順序)
 	EndPointHandlers []*EndPoint
+	// 路由前綴樹，用於根據請求路徑尋找 EndPoint
+	tree *routeTree
+	// 當前請求的路徑參數(重複使用以避免每次請求都重新分配)
+	params []routeParam
+	// 全域中介函式，在所有請求(包含 404 與 405)的處理函式之前執行
+	middlewares HandlerChain
 
 	// ==================================================
 	// Context
@@ -40,14 +47,26 @@ type HttpAnser struct {
 	contexts    []*ghttp.Context
 	context     *ghttp.Context
 
+	// 將串流數據編碼為分塊後寫入連線
+	chunkWriter *chunkWriter
+	// 將已知長度的串流數據直接寫入連線
+	rawWriter *rawWriter
+	// 供 Context.HTML 使用的 HTML 模板
+	htmlTemplate *template.Template
+
 	// Temp variables
 	lineString string
 }
 
-func NewHttpAnser(laddr *net.TCPAddr, nConnect int32, nWork int32) (IAnswer, error) {
+func NewHttpAnser(laddr net.Addr, nConnect int32, nWork int32) (IAnswer, error) {
 	var err error
 	a := &HttpAnser{
 		EndPointHandlers: []*EndPoint{},
+		tree:             newRouteTree(),
+		params:           []routeParam{},
+		middlewares:      HandlerChain{},
+		chunkWriter:      &chunkWriter{},
+		rawWriter:        &rawWriter{},
 		contexts:         make([]*ghttp.Context, nConnect),
 		context:          nil,
 		contextPool:      sync.Pool{New: func() any { return ghttp.NewContext(-1) }},
@@ -81,9 +100,44 @@ func NewHttpAnser(laddr *net.TCPAddr, nConnect int32, nWork int32) (IAnswer, err
 	a.readFunc = a.read
 	a.writeFunc = a.write
 	a.shouldCloseFunc = a.shouldClose
+	a.disconnectFunc = a.onDisconnect
 	return a, nil
 }
 
+// 註冊全域中介函式，將在所有請求(包含 404 與 405)的處理函式之前，依註冊順序執行
+func (a *HttpAnser) Use(handlers ...HandlerFunc) {
+	a.middlewares = append(a.middlewares, handlers...)
+}
+
+// 設置供 Context.HTML 使用的 HTML 模板
+func (a *HttpAnser) SetHTMLTemplate(t *template.Template) {
+	a.htmlTemplate = t
+}
+
+// 載入符合 pattern 的 HTML 模板檔案(同 template.ParseGlob)
+func (a *HttpAnser) LoadHTMLGlob(pattern string) error {
+	t, err := template.ParseGlob(pattern)
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to load html templates from %s", pattern)
+	}
+
+	a.htmlTemplate = t
+	return nil
+}
+
+// 載入指定的 HTML 模板檔案(同 template.ParseFiles)
+func (a *HttpAnser) LoadHTMLFiles(files ...string) error {
+	t, err := template.ParseFiles(files...)
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to load html templates from %v", files)
+	}
+
+	a.htmlTemplate = t
+	return nil
+}
+
 // 監聽連線並註冊
 func (a *HttpAnser) Listen() {
 	a.SetWorkHandler()
@@ -94,23 +148,41 @@ func (a *HttpAnser) read() bool {
 	// 根據 Conn 的 Id，存取對應的 httpConn
 	a.context = a.contexts[a.currConn.GetId()]
 
+	// 串流寫出 Body 期間，不讀取後續的請求
+	if a.context.State == ghttp.WRITE_STREAM {
+		a.writeStream()
+		return false
+	}
+
+	// 沒有空閒的工作結構，等待下次迴圈再處理
+	if a.currWork == nil {
+		return false
+	}
+
 	// 讀取 第一行(ex: GET /foo/bar HTTP/1.1)
 	if a.context.State == ghttp.READ_FIRST_LINE {
-		if a.currConn.CheckReadable(a.context.HasLineData) {
+		if a.currConn.CheckReadable(a.context.Request.HasLineData) {
 			a.currConn.Read(&a.readBuffer, a.context.Request.ReadLength)
+			a.context.AddHeaderLength(a.context.Request.ReadLength)
+
+			if a.headerTooLarge(0) {
+				return false
+			}
 
 			// 拆分第一行數據
 			a.lineString = strings.TrimRight(string(a.readBuffer[:a.context.Request.ReadLength]), "\r\n")
 			utils.Info("firstLine: %s", a.lineString)
 
 			if a.context.ParseFirstReqLine(a.lineString) {
-				if a.context.Method == ghttp.MethodGet {
-					// 解析第一行數據中的請求路徑
-					a.context.ParseQuery()
-				}
+				// 解析第一行數據中的請求路徑(任何 Method 都可能帶有 GET 參數)
+				a.context.ParseQuery()
 				a.context.State = ghttp.READ_HEADER
 				utils.Debug("State: READ_FIRST_LINE -> READ_HEADER")
 			}
+
+		} else if a.headerTooLarge(a.currConn.ReadableLength) {
+			// 尚未讀到換行，但已超過長度上限
+			return false
 		}
 	}
 
@@ -119,9 +191,14 @@ func (a *HttpAnser) read() bool {
 		var key, value string
 		var ok bool
 
-		for a.context.State == ghttp.READ_HEADER && a.currConn.CheckReadable(a.context.HasLineData) {
+		for a.context.State == ghttp.READ_HEADER && a.currConn.CheckReadable(a.context.Request.HasLineData) {
 			// 讀取一行數據
 			a.currConn.Read(&a.readBuffer, a.context.Request.ReadLength)
+			a.context.AddHeaderLength(a.context.Request.ReadLength)
+
+			if a.headerTooLarge(0) {
+				return false
+			}
 
 			// mustHaveFieldNameColon ensures that, per RFC 7230, the field-name is on a single line,
 			// so the first line must contain a colon.
@@ -131,6 +208,9 @@ func (a *HttpAnser) read() bool {
 			key, value, ok = strings.Cut(a.lineString, ghttp.COLON)
 
 			if ok {
+				// 標頭名稱不區分大小寫，統一轉換為標準格式(ex: content-length -> Content-Length)
+				key = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key))
+
 				// 持續讀取 Header
 				if _, ok := a.context.Request.Header[key]; !ok {
 					a.context.Request.Header[key] = []string{}
@@ -145,15 +225,30 @@ func (a *HttpAnser) read() bool {
 			} else {
 				// 當前這行數據不包含":"，結束 Header 的讀取
 
-				// Header 中包含 Content-Length，狀態值設為 2，等待讀取後續數據
-				if contentLength, ok := a.context.Request.Header["Content-Length"]; ok {
-					length, err := strconv.Atoi(contentLength[0])
+				// 根據請求內容與請求數上限，決定回應後是否維持連線
+				a.context.UpdateKeepAlive(utils.GosConfig.HttpMaxKeepAliveRequests)
+
+				// Header 中宣告了分塊傳輸，等待讀取分塊數據(優先於 Content-Length)
+				if ghttp.IsChunked(a.context.Request.Header) {
+					a.context.State = ghttp.READ_CHUNK_SIZE
+					utils.Debug("State: READ_HEADER -> READ_CHUNK_SIZE")
+
+				} else if contentLength, ok := a.context.Request.Header["Content-Length"]; ok {
+					// Header 中包含 Content-Length，狀態值設為 2，等待讀取後續數據
+					length, err := strconv.ParseInt(contentLength[0], 10, 64)
 					// fmt.Printf("(a *HttpAnser) Read | Content-Length: %d\n", length)
 					utils.Debug("Content-Length: %d", length)
 
-					if err != nil {
+					if err != nil || length < 0 {
 						// fmt.Printf("(a *HttpAnser) Read | Content-Length err: %+v\n", err)
 						utils.Error("Content-Length err: %+v", err)
+						a.rejectRequest(ghttp.StatusBadRequest, "Invalid Content-Length.")
+						return false
+					}
+
+					if length > int64(utils.GosConfig.HttpMaxBodySize) {
+						utils.Warn("Conn(%d) Content-Length %d exceeds %d", a.currConn.GetId(), length, utils.GosConfig.HttpMaxBodySize)
+						a.rejectRequest(ghttp.StatusRequestEntityTooLarge, "Request Entity Too Large")
 						return false
 					}
 
@@ -178,20 +273,62 @@ func (a *HttpAnser) read() bool {
 				}
 			}
 		}
+
+		// 尚未讀到換行，但已超過長度上限
+		if a.context.State == ghttp.READ_HEADER && a.headerTooLarge(a.currConn.ReadableLength) {
+			return false
+		}
+	}
+
+	// 讀取分塊傳輸的 Body 數據
+	if a.context.State.IsReadingChunk() {
+		done, err := a.context.ReadChunkedRequest(a.currConn, &a.readBuffer, utils.GosConfig.HttpMaxBodySize)
+
+		if err != nil {
+			utils.Error("Conn(%d) chunk err: %+v", a.currConn.GetId(), err)
+
+			switch errors.Cause(err) {
+			case ghttp.ErrChunkedBodyTooLarge:
+				a.rejectRequest(ghttp.StatusRequestEntityTooLarge, "Request Entity Too Large")
+			case ghttp.ErrChunkedBodyStorage:
+				a.rejectRequest(ghttp.StatusInternalServerError, "Internal Server Error")
+			default:
+				a.rejectRequest(ghttp.StatusBadRequest, "Malformed chunked encoding.")
+			}
+			return false
+		}
+
+		if done {
+			// 考慮分包問題，收到完整一包數據傳完才傳到應用層
+			a.currWork.Index = a.currConn.GetId()
+			a.currWork.RequestTime = time.Now().UTC()
+			a.currWork.State = base.WORK_NEED_PROCESS
+
+			// 指向下一個工作結構
+			a.currWork = a.currWork.Next
+
+			// 等待數據寫出
+			a.context.State = ghttp.WRITE_RESPONSE
+			utils.Debug("State: READ_CHUNK_TRAILER -> WRITE_RESPONSE")
+			return false
+		}
 	}
 
 	// 讀取 Body 數據
 	if a.context.State == ghttp.READ_BODY {
-		if a.currConn.CheckReadable(a.context.HasEnoughData) {
-			// 將傳入的數據，加入工作緩存中
-			a.currConn.Read(&a.readBuffer, a.context.Request.ReadLength)
-			utils.Debug("Body 數據: %s", string(a.readBuffer[:a.context.Request.ReadLength]))
+		done, err := a.readBody()
+
+		if err != nil {
+			utils.Error("Conn(%d) body err: %+v", a.currConn.GetId(), err)
+			a.rejectRequest(ghttp.StatusInternalServerError, "Internal Server Error")
+			return false
+		}
 
+		if done {
 			// 考慮分包問題，收到完整一包數據傳完才傳到應用層
 			a.currWork.Index = a.currConn.GetId()
 			a.currWork.RequestTime = time.Now().UTC()
 			a.currWork.State = base.WORK_NEED_PROCESS
-			a.context.Request.SetBody(a.readBuffer, a.context.Request.ReadLength)
 
 			// 指向下一個工作結構
 			a.currWork = a.currWork.Next
@@ -206,6 +343,68 @@ func (a *HttpAnser) read() bool {
 	return true
 }
 
+// 讀取長度為 Content-Length 的 Body 數據，Body 可能大於讀取緩衝，因此有多少數據就先讀取多少，全部讀取完畢時返回 true
+func (a *HttpAnser) readBody() (bool, error) {
+	var size int32
+
+	for a.context.Request.ReadLength > 0 {
+		size = a.context.Request.ReadLength
+
+		if size > a.currConn.ReadableLength {
+			size = a.currConn.ReadableLength
+		}
+
+		if size > int32(len(a.readBuffer)) {
+			size = int32(len(a.readBuffer))
+		}
+
+		if size == 0 {
+			return false, nil
+		}
+
+		a.currConn.Read(&a.readBuffer, size)
+
+		if err := a.context.Request.WriteBody(a.readBuffer[:size]); err != nil {
+			return false, errors.Wrap(err, "Failed to write body.")
+		}
+
+		a.context.Request.ReadLength -= size
+	}
+
+	return true, nil
+}
+
+// 第一行與 Header 的長度(包含尚未讀到換行的 pending 個 bytes)超過 HttpMaxHeaderSize 時，回應 431 並斷線
+// 避免不含換行的數據填滿讀取緩衝，使連線停止讀取而無法超時
+func (a *HttpAnser) headerTooLarge(pending int32) bool {
+	if a.context.GetHeaderLength()+pending <= utils.GosConfig.HttpMaxHeaderSize {
+		return false
+	}
+
+	utils.Warn("Conn(%d) header exceeds %d bytes", a.currConn.GetId(), utils.GosConfig.HttpMaxHeaderSize)
+	a.rejectRequest(ghttp.StatusRequestHeaderFieldsTooLarge, "Request Header Fields Too Large")
+	return true
+}
+
+// 在讀取階段發現請求有誤時，直接回應錯誤訊息並於寫出後關閉連線
+func (a *HttpAnser) rejectRequest(code int32, msg string) {
+	a.currWork.Index = a.currConn.GetId()
+	a.currWork.RequestTime = time.Now().UTC()
+	a.context.Cid = a.currConn.GetId()
+	a.context.Wid = a.currWork.GetId()
+	a.context.KeepAlive = false
+	a.context.Json(code, ghttp.H{
+		"error": msg,
+	})
+	a.Send(a.context)
+
+	// 指向下一個工作結構
+	a.currWork = a.currWork.Next
+
+	// 等待數據寫出
+	a.context.State = ghttp.WRITE_RESPONSE
+}
+
 func (a *HttpAnser) write(cid int32, data *[]byte, length int32) error {
 	// 取得對應的連線結構
 	a.currConn = a.getConn(cid)
@@ -216,11 +415,57 @@ func (a *HttpAnser) write(cid int32, data *[]byte, length int32) error {
 
 	a.currConn.SetWriteBuffer(data, length)
 
-	// 完成數據複製到寫出緩存
-	a.context.State = ghttp.FINISH_RESPONSE
+	if a.contexts[cid].IsStreaming() {
+		// 已寫出 Header，後續由 writeStream 持續寫出 Body
+		a.contexts[cid].State = ghttp.WRITE_STREAM
+
+		// Server-Sent Events 的客戶端不會再送出數據，取消讀取超時，斷線改由讀寫錯誤得知
+		if a.contexts[cid].IsEventStream() {
+			if err := a.currConn.NetConn.SetReadDeadline(time.Time{}); err != nil {
+				utils.Error("Conn(%d) failed to clear read deadline: %+v", cid, err)
+			}
+		}
+	} else {
+		// 完成數據複製到寫出緩存
+		a.contexts[cid].State = ghttp.FINISH_RESPONSE
+	}
 	return nil
 }
 
+// 寫出緩存的數據量低於緩衝長度的一半時，才繼續生成串流數據，避免寫出緩存無限制地增長
+func (a *HttpAnser) writeStream() {
+	if a.currConn.WritableLength > a.currConn.BufferLength/2 {
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			// Header 已寫出，無法再回應錯誤，以不完整的分塊傳輸結束並關閉連線
+			utils.Error("Conn(%d) stream recover err: %+v", a.currConn.GetId(), err)
+			a.context.KeepAlive = false
+			a.context.State = ghttp.FINISH_RESPONSE
+		}
+	}()
+
+	// 已知長度的串流(StreamContent)直接寫出數據，其餘以分塊傳輸寫出
+	if !a.context.Response.IsChunked() {
+		a.rawWriter.conn = a.currConn
+
+		if !a.context.StreamStep(a.rawWriter) {
+			a.context.State = ghttp.FINISH_RESPONSE
+		}
+		return
+	}
+
+	a.chunkWriter.conn = a.currConn
+
+	if !a.context.StreamStep(a.chunkWriter) {
+		bs := ghttp.EncodeLastChunk(a.context.Response.Trailer)
+		a.currConn.SetWriteBuffer(&bs, int32(len(bs)))
+		a.context.State = ghttp.FINISH_RESPONSE
+	}
+}
+
 // 由外部定義 workHandler，定義如何處理工作
 func (a *HttpAnser) SetWorkHandler() {
 	// 在此將通用的 Work 轉換成 Http 專用的 Context
@@ -234,72 +479,69 @@ func (a *HttpAnser) SetWorkHandler() {
 		a.context = a.contexts[w.Index]
 		a.context.Cid = w.Index
 		a.context.Wid = w.GetId()
+		a.context.TLS = w.TLS
+		a.context.SetHTMLTemplate(a.htmlTemplate)
 		utils.Debug("Cid: %d, Wid: %d", a.context.Cid, a.context.Wid)
-		var key string
-		var value any
-		var unmatched bool = true
-		var nSplit int32
 		var splits []string
 
 		if a.context.Query == "" || a.context.Query == "/" {
-			nSplit = 1
 			splits = []string{""}
 		} else {
 			a.context.Query = strings.TrimSuffix(a.context.Query, "/")
 			splits = strings.Split(a.context.Query, "/")
-			nSplit = int32(len(splits))
 		}
 
-		for _, endpoint := range a.EndPointHandlers {
-			if endpoint.nNode == nSplit {
-				if handlers, ok := endpoint.Handlers[a.context.Method]; ok {
-					if endpoint.Macth(splits) {
-						utils.Debug("endpoint path: %s", endpoint.path)
-						unmatched = false
-						if a.context.Method == ghttp.MethodOptions {
-							a.optionsRequestHandler(w, a.context, endpoint.options)
-						} else {
-							for key, value = range endpoint.params {
-								if _, ok = a.context.Params[key]; !ok {
-									a.context.Params[key] = fmt.Sprintf("%v", value)
-								}
-								if _, ok = a.context.Values[key]; !ok {
-									a.context.Values[key] = value
-								}
-							}
-							for _, function := range handlers {
-								function(a.context)
-							}
-							// TODO: Unit test 檢查 Response
-							// 檢查 Response 是否需要寫出
-							if a.context.Code != -1 {
-								a.Send(a.context)
-							} else {
-								a.Finish(a.context)
-							}
-						}
-						break
-					}
-				}
+		a.params = a.params[:0]
+		endpoint, allowed := a.tree.lookup(a.context.Method, splits, &a.params)
+
+		if endpoint == nil {
+			if len(allowed) > 0 {
+				// 路徑存在，但不支援此 Method
+				a.context.SetHandlers(a.middlewares, HandlerChain{func(c *ghttp.Context) {
+					a.methodNotAllowedHandler(c, allowed)
+				}})
+			} else {
+				a.context.SetHandlers(a.middlewares, HandlerChain{a.notFoundHandler})
+			}
+		} else {
+			utils.Debug("endpoint path: %s", endpoint.path)
+
+			// 路徑參數只屬於當前請求，並優先於同名的 GET 參數
+			for _, param := range a.params {
+				a.context.SetPathParam(param.key, param.raw, param.value)
+			}
+
+			if a.context.Method == ghttp.MethodOptions {
+				// 全域中介函式 -> Router 的中介函式 -> 自動生成的 OPTIONS 回應(中介函式可透過 Abort 取代，如 CORS 預檢請求)
+				options := endpoint.options
+				a.context.SetHandlers(a.middlewares, endpoint.Handlers[ghttp.MethodOptions], HandlerChain{func(c *ghttp.Context) {
+					a.optionsRequestHandler(c, options)
+				}})
+			} else {
+				// 全域中介函式 -> 端點處理函式(包含 Router 的中介函式)
+				a.context.SetHandlers(a.middlewares, endpoint.Handlers[a.context.Method])
 			}
 		}
-		if unmatched {
-			a.errorRequestHandler(a.context, "Unmatched endpoint.")
+
+		// 依序執行處理函式，中介函式可透過 Next 或 Abort 控制流程
+		a.context.Next()
+
+		// TODO: Unit test 檢查 Response
+		// 檢查 Response 是否需要寫出
+		if a.context.Code != -1 {
+			a.Send(a.context)
+		} else {
+			a.Finish(a.context)
 		}
 	}
 }
 
-func (a *HttpAnser) optionsRequestHandler(w *base.Work, c *ghttp.Context, options []string) {
-	a.context.Response.SetHeader("Allow", strings.Join(options, ", "))
-	a.context.Response.SetHeader("Connection", "close")
-	a.context.Status(ghttp.StatusOK)
-	a.context.Response.BodyLength = 0
-	a.context.Response.SetContentLength()
-	// 將 Response 回傳數據轉換成 Work 傳遞的格式
-	bs := a.context.ToResponseData()
-	w.Body.Clear()
-	w.Body.AddRawData(bs)
-	w.Send()
+// 自動回應 OPTIONS 請求，列出路徑所支援的 Method
+func (a *HttpAnser) optionsRequestHandler(c *ghttp.Context, options []string) {
+	c.Response.Header.Set("Allow", strings.Join(options, ", "))
+	c.Status(ghttp.StatusOK)
+	c.Response.BodyLength = 0
+	c.Response.SetContentLength()
 }
 
 func (a *HttpAnser) errorRequestHandler(c *ghttp.Context, msg string) {
@@ -310,6 +552,23 @@ func (a *HttpAnser) errorRequestHandler(c *ghttp.Context, msg string) {
 	a.Send(c)
 }
 
+// 沒有任何路徑匹配的 EndPoint
+func (a *HttpAnser) notFoundHandler(c *ghttp.Context) {
+	utils.Warn("method: %s, query: %s", c.Method, c.Query)
+	c.Json(ghttp.StatusNotFound, ghttp.H{
+		"error": "Not Found",
+	})
+}
+
+// 路徑匹配的 EndPoint 不支援請求的 Method
+func (a *HttpAnser) methodNotAllowedHandler(c *ghttp.Context, options []string) {
+	utils.Warn("method: %s, query: %s", c.Method, c.Query)
+	c.Json(ghttp.StatusMethodNotAllowed, ghttp.H{
+		"error": "Method Not Allowed",
+	})
+	c.Response.SetHeader("Allow", strings.Join(options, ", "))
+}
+
 func (a *HttpAnser) serverErrorHandler(c *ghttp.Context, msg string) {
 	utils.Error("method: %s, query: %s", c.Method, c.Query)
 	c.Json(ghttp.StatusInternalServerError, ghttp.H{
@@ -326,6 +585,22 @@ func (a *HttpAnser) shouldClose(err error) bool {
 		return true
 	}
 	if a.context.State == ghttp.FINISH_RESPONSE && a.currConn.WritableLength == 0 {
+		if a.context.KeepAlive {
+			utils.Debug("Conn(%d) 完成數據寫出，維持連線等待下一個請求", a.currConn.GetId())
+
+			// 重置 Context，已在 readBuffer 中的後續請求(pipelining)，將於下次迴圈依序處理
+			a.context.Reset()
+
+			// 閒置超過 HttpKeepAliveTimeout 未收到新的請求，將因讀取超時而斷線
+			err = a.currConn.NetConn.SetReadDeadline(time.Now().Add(utils.GosConfig.HttpKeepAliveTimeout))
+
+			if err != nil {
+				utils.Error("Conn(%d) failed to set keep-alive deadline: %+v", a.currConn.GetId(), err)
+				a.context.Release()
+				return true
+			}
+			return false
+		}
 		utils.Info("Conn(%d) 完成數據寫出，準備關閉連線", a.currConn.GetId())
 		a.context.Release()
 		return true
@@ -333,17 +608,43 @@ func (a *HttpAnser) shouldClose(err error) bool {
 	return false
 }
 
+// 連線中斷時重置 Context(如通知 Server-Sent Events 的客戶端已斷線)
+func (a *HttpAnser) onDisconnect(cid int32) {
+	a.contexts[cid].Release()
+}
+
+// 根據 Context 是否維持連線，設置 Connection 相關標頭
+func (a *HttpAnser) setConnectionHeader(c *ghttp.Context) {
+	keepAlive := c.KeepAlive
+
+	// 從 contextPool 取得的 Context，需根據原始連線的 Context 來判斷
+	if c.GetId() == -1 && c.Cid >= 0 && int(c.Cid) < len(a.contexts) {
+		keepAlive = a.contexts[c.Cid].KeepAlive
+	}
+
+	if keepAlive {
+		c.Response.Header["Connection"] = []string{"keep-alive"}
+		c.Response.Header["Keep-Alive"] = []string{fmt.Sprintf("timeout=%d, max=%d",
+			int(utils.GosConfig.HttpKeepAliveTimeout.Seconds()),
+			utils.GosConfig.HttpMaxKeepAliveRequests)}
+	} else {
+		c.Response.Header["Connection"] = []string{"close"}
+		delete(c.Response.Header, "Keep-Alive")
+	}
+}
+
 func (a *HttpAnser) GetContext(cid int32) *ghttp.Context {
 	if cid == -1 {
 		a.context = a.contextPool.Get().(*ghttp.Context)
 	} else {
 		a.context = a.contexts[cid]
 	}
+	a.context.SetHTMLTemplate(a.htmlTemplate)
 	return a.context
 }
 
 func (a *HttpAnser) Send(c *ghttp.Context) {
-	c.Response.SetHeader("Connection", "close")
+	a.setConnectionHeader(c)
 
 	// 將 Response 回傳數據轉換成 Work 傳遞的格式
 	bs := c.ToResponseData()
@@ -359,8 +660,9 @@ func (a *HttpAnser) Send(c *ghttp.Context) {
 	w.Send()
 	utils.Debug("Wid: %d, w: %+v", c.Wid, w)
 
-	// 若 Context 是從 contextPool 中取得，id 會是 -1，因此需要回收
+	// 若 Context 是從 contextPool 中取得，id 會是 -1，因此需要重置後回收
 	if c.GetId() == -1 {
+		c.Release()
 		a.contextPool.Put(c)
 	}
 }
@@ -372,6 +674,30 @@ func (a *HttpAnser) Finish(c *ghttp.Context) {
 	w.Finish()
 }
 
+// 將寫入的數據編碼為一個分塊後，直接加入連線的寫出緩存
+type chunkWriter struct {
+	conn *base.Conn
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	// 長度為 0 的分塊代表傳輸結束，因此略過空數據
+	if len(p) == 0 {
+		return 0, nil
+	}
+	bs := ghttp.EncodeChunk(p)
+	w.conn.SetWriteBuffer(&bs, int32(len(bs)))
+	return len(p), nil
+}
+
+type rawWriter struct {
+	conn *base.Conn
+}
+
+func (w *rawWriter) Write(p []byte) (int, error) {
+	w.conn.SetWriteBuffer(&p, int32(len(p)))
+	return len(p), nil
+}
+
 // ====================================================================================================
 // Router
 // ====================================================================================================
@@ -418,34 +744,31 @@ func (r *Router) DELETE(path string, handlers ...HandlerFunc) {
 }
 
 func (r *Router) handle(method string, path string, handlers ...HandlerFunc) {
-	var endpoint *EndPoint
 	fullPath := r.combinePath(path)
-	isExists := false
+	nodes := r.combineNodes(path)
+	tn := r.HttpAnser.tree.insert(nodes)
 
-	for _, endpoint = range r.HttpAnser.EndPointHandlers {
-		if endpoint.path == fullPath {
-			isExists = true
-			break
-		}
+	if tn == nil {
+		utils.Error("Failed to register %s %s", method, fullPath)
+		return
 	}
 
-	if !isExists {
-		endpoint = NewEndPoint()
-		endpoint.path = fullPath
-		nodes := r.combineNodes(path)
-		endpoint.InitNodes(nodes)
-		r.HttpAnser.EndPointHandlers = append(r.HttpAnser.EndPointHandlers, endpoint)
+	if tn.endpoint == nil {
+		tn.endpoint = NewEndPoint()
+		tn.endpoint.path = fullPath
+		tn.endpoint.InitNodes(nodes)
+		// OPTIONS 請求沿用首次註冊此路徑的 Router 的中介函式(如 CORS)
+		tn.endpoint.Handlers[ghttp.MethodOptions] = r.combineHandlers(nil)
+		r.HttpAnser.EndPointHandlers = append(r.HttpAnser.EndPointHandlers, tn.endpoint)
 	}
 
+	endpoint := tn.endpoint
+
 	if _, ok := endpoint.Handlers[method]; !ok {
 		endpoint.options = append(endpoint.options, method)
 	}
 
 	endpoint.Handlers[method] = r.combineHandlers(handlers)
-	sort.SliceStable(r.HttpAnser.EndPointHandlers, func(i, j int) bool {
-		// True 的話，會被排到前面
-		return r.HttpAnser.EndPointHandlers[i].priority > r.HttpAnser.EndPointHandlers[j].priority
-	})
 }
 
 func (r *Router) combinePath(relativePath string) string {
@@ -479,11 +802,8 @@ func (r *Router) combineHandlers(handlers HandlerChain) HandlerChain {
 // EndPoint
 // ====================================================================================================
 type EndPoint struct {
-	path     string
-	nodes    []*node
-	nNode    int32
-	priority float32
-	params   map[string]any
+	path  string
+	nodes []*node
 	// key: HttpMethod(GET/POST/...), value: handler functions
 	Handlers map[string]HandlerChain
 	options  []string
@@ -491,10 +811,7 @@ type EndPoint struct {
 
 func NewEndPoint() *EndPoint {
 	ep := &EndPoint{
-		nodes:    []*node{},
-		nNode:    0,
-		priority: 0,
-		params:   make(map[string]any),
+		nodes: []*node{},
 		Handlers: map[string]HandlerChain{
 			ghttp.MethodOptions: {},
 		},
@@ -504,41 +821,13 @@ func NewEndPoint() *EndPoint {
 }
 
 func (ep *EndPoint) InitNodes(nodes []*node) {
-	var n *node
-	for _, n = range nodes {
-		if n.isParam {
-			if n.routeType == "int" || n.routeType == "uint" || n.routeType == "float" {
-				ep.priority += 0.5
-			}
-		} else {
-			ep.priority += 1.0
-		}
-		ep.nodes = append(ep.nodes, n)
-	}
-	ep.nNode = int32(len(ep.nodes))
+	ep.nodes = append(ep.nodes, nodes...)
 }
 
-func (ep *EndPoint) Macth(routes []string) bool {
-	if ep.nNode != int32(len(routes)) {
-		return false
-	}
-	var n *node
-	for i, route := range routes {
-		n = ep.nodes[i]
-		if !n.match(route) {
-			return false
-		}
-	}
-	for _, n := range ep.nodes {
-		if n.isParam {
-			ep.SetParam(n.route, n.value)
-		}
-	}
-	return true
-}
-
-func (ep *EndPoint) SetParam(key string, value any) {
-	ep.params[key] = value
+// 是否有註冊 method 的處理函式
+func (ep *EndPoint) hasMethod(method string) bool {
+	_, ok := ep.Handlers[method]
+	return ok
 }
 
 // ====================================================================================================
@@ -548,7 +837,8 @@ type node struct {
 	route     string
 	routeType string
 	isParam   bool
-	value     any
+	// 萬用片段(ex: *filepath)，匹配剩餘的所有路徑片段
+	isCatchAll bool
 }
 
 func newNode(route string) *node {
@@ -556,6 +846,10 @@ func newNode(route string) *node {
 	if strings.HasPrefix(route, "<") && strings.HasSuffix(route, ">") {
 		n.isParam = true
 		route = route[1 : len(route)-1]
+	} else if strings.HasPrefix(route, "*") && len(route) > 1 {
+		n.isParam = true
+		n.isCatchAll = true
+		route = route[1:]
 	}
 	routes := strings.Split(route, " ")
 	n.route = routes[0]
@@ -565,34 +859,39 @@ func newNode(route string) *node {
 	return n
 }
 
-func (n *node) match(route string) bool {
+// 檢查路徑片段是否匹配，若為參數節點，同時返回根據路由型別轉換後的數值
+func (n *node) match(route string) (any, bool) {
 	if n.isParam {
 		switch n.routeType {
 		case "int":
 			i, err := strconv.ParseInt(route, 10, 64)
 			if err != nil {
-				return false
+				return nil, false
 			}
-			n.value = i
+			return i, true
 		case "uint":
 			i, err := strconv.ParseUint(route, 10, 64)
 			if err != nil {
-				return false
+				return nil, false
 			}
-			n.value = i
+			return i, true
 		case "float":
 			f, err := strconv.ParseFloat(route, 64)
 			if err != nil {
-				return false
+				return nil, false
 			}
-			n.value = f
+			return f, true
 		case "string", "":
-			n.value = route
+			return route, true
 		default:
-			return false
+			return nil, false
 		}
-		return true
 	} else {
-		return route == n.route
+		return nil, route == n.route
 	}
 }
+
+// 是否為具型別的參數(匹配時優先於字串參數)
+func (n *node) isTyped() bool {
+	return n.isParam && !n.isCatchAll && (n.routeType == "int" || n.routeType == "uint" || n.routeType == "float")
+}
@@ -0,0 +1,68 @@
+package ans
+
+import (
+	"net"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// LineAnser
+// 以分隔字元切分的文字協定，每一行(不含分隔字元)作為一個 base.Work 交由 workHandler 處理
+// 工作以 Send 寫出的數據，以及 Write、WriteLine 寫出的數據，將自動加上分隔字元
+// ====================================================================================================
+
+type LineAnser struct {
+	*CodecAnser
+	// 各連線共用的行格式
+	format *base.LineFormat
+}
+
+func NewLineAnser(laddr net.Addr, nConnect int32, nWork int32) (IAnswer, error) {
+	a := &LineAnser{
+		format: base.NewLineFormat(),
+	}
+
+	anser, err := NewCodecAnser(laddr, nConnect, nWork, func() base.Codec {
+		return base.NewLineCodec(a.format)
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new LineAnser.")
+	}
+
+	a.CodecAnser = anser.(*CodecAnser)
+	a.Anser.ReadTimeout = utils.GosConfig.LineReadTimeout
+	return a, nil
+}
+
+// 設置行的格式(須於 Listen 之前呼叫)
+func (a *LineAnser) SetLineFormat(format *base.LineFormat) error {
+	if format == nil {
+		return errors.New("Line format is nil.")
+	}
+
+	// 一行數據須可完整放入連線的讀取緩存以及 readBuffer
+	limit := a.conns.BufferLength - define.MTU
+
+	if size := int32(len(a.readBuffer)); size < limit {
+		limit = size
+	}
+
+	if err := format.Check(limit); err != nil {
+		return errors.Wrapf(err, "Failed to set line format at %s.", a.laddr)
+	}
+
+	*a.format = *format
+	return nil
+}
+
+// 寫出一行文字
+func (a *LineAnser) WriteLine(cid int32, line string) error {
+	data := []byte(line)
+	return a.Write(cid, &data, int32(len(data)))
+}
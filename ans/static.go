@@ -0,0 +1,396 @@
+package ans
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Static
+// 以 GET/HEAD 提供靜態檔案，支援 ETag、Last-Modified、條件式請求(304)與單一範圍請求(206)
+// ====================================================================================================
+type StaticConfig struct {
+	// 請求目錄時所回應的首頁檔案(空字串表示不提供)
+	Index string
+	// 找不到檔案時，以根目錄的 Index 回應(單頁應用程式的前端路由)
+	SPAFallback bool
+}
+
+// 將 dir 目錄下的檔案，提供於 prefix 路徑之下
+func (r *Router) Static(prefix string, dir string) {
+	r.StaticFS(prefix, os.DirFS(dir))
+}
+
+// 將 fsys(如 embed.FS)中的檔案，提供於 prefix 路徑之下
+func (r *Router) StaticFS(prefix string, fsys fs.FS) {
+	r.StaticWithConfig(prefix, fsys, StaticConfig{
+		Index:       "index.html",
+		SPAFallback: false,
+	})
+}
+
+// 根據 config，將 fsys 中的檔案提供於 prefix 路徑之下
+func (r *Router) StaticWithConfig(prefix string, fsys fs.FS, config StaticConfig) {
+	// key: 檔案路徑, value: *staticETagEntry
+	etags := &sync.Map{}
+	handler := func(c *ghttp.Context) {
+		serveStatic(c, fsys, c.PathParams["filepath"], config, etags)
+	}
+	relativePath := path.Join(prefix, "*filepath")
+	r.GET(relativePath, handler)
+	r.HEAD(relativePath, handler)
+}
+
+func serveStatic(c *ghttp.Context, fsys fs.FS, name string, config StaticConfig, etags *sync.Map) {
+	name, ok := cleanStaticPath(name)
+
+	if !ok {
+		utils.Warn("Invalid static path: %s", c.Query)
+		c.Json(ghttp.StatusBadRequest, ghttp.H{
+			"error": "Bad Request",
+		})
+		return
+	}
+
+	file, info, name, err := openStatic(fsys, name, config.Index)
+
+	if err != nil && config.SPAFallback && config.Index != "" {
+		file, info, name, err = openStatic(fsys, config.Index, "")
+	}
+
+	if err != nil {
+		utils.Warn("Static file not found: %s, err: %+v", name, err)
+		c.Json(ghttp.StatusNotFound, ghttp.H{
+			"error": "Not Found",
+		})
+		return
+	}
+
+	// 交由 StreamContent 寫出後，檔案於傳輸結束(或連線中斷)時才關閉
+	closeFile := true
+	defer func() {
+		if closeFile {
+			file.Close()
+		}
+	}()
+
+	// 先根據檔案資訊處理條件式請求，需要回應 Body 時才讀取檔案內容
+	content, err := seekable(file)
+
+	if err != nil {
+		staticError(c, info, err)
+		return
+	}
+
+	modTime := info.ModTime()
+	etag, err := staticETag(etags, name, info, content)
+
+	if err != nil {
+		staticError(c, info, err)
+		return
+	}
+
+	c.Response.Header.Set("ETag", etag)
+	c.Response.Header.Set("Accept-Ranges", "bytes")
+
+	// embed.FS 中的檔案沒有修改時間
+	if !modTime.IsZero() {
+		c.Response.Header.Set("Last-Modified", modTime.UTC().Format(ghttp.TimeFormat))
+	}
+
+	if isNotModified(c, etag, modTime) {
+		c.Status(ghttp.StatusNotModified)
+		c.Response.BodyLength = 0
+		return
+	}
+
+	contentType, err := staticContentType(info, content)
+
+	if err != nil {
+		staticError(c, info, err)
+		return
+	}
+
+	size := info.Size()
+	code, start, length := int32(ghttp.StatusOK), int64(0), size
+	rangeHeader := c.Request.Header.Get("Range")
+
+	if rangeHeader != "" && matchIfRange(c, etag, modTime) {
+		first, last, err := parseRange(rangeHeader, size)
+
+		if err != nil {
+			utils.Warn("Invalid range %s: %+v", rangeHeader, err)
+			c.Response.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			c.Data(ghttp.StatusRequestedRangeNotSatisfiable, ghttp.MIMEPlain, []byte{})
+			return
+		}
+
+		// first < 0 表示不支援的範圍格式(如多重範圍)，回應完整的檔案
+		if first >= 0 {
+			c.Response.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, size))
+			code, start, length = int32(ghttp.StatusPartialContent), first, last-first+1
+		}
+	}
+
+	// 只讀取需要的範圍，並逐段寫出，避免將整個檔案讀入記憶體
+	closeFile = false
+	c.StreamContent(code, contentType, staticReader{
+		Reader: io.NewSectionReader(content, start, length),
+		Closer: file,
+	}, length)
+}
+
+// 讀取檔案中的特定範圍，並於串流結束時關閉檔案
+type staticReader struct {
+	io.Reader
+	io.Closer
+}
+
+// 可 Seek 並可隨機讀取的檔案內容
+type staticContent interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+// 讀取檔案失敗時，回應 500
+func staticError(c *ghttp.Context, info fs.FileInfo, err error) {
+	utils.Error("Failed to read static file %s: %+v", info.Name(), err)
+	c.Json(ghttp.StatusInternalServerError, ghttp.H{
+		"error": "Internal Server Error",
+	})
+}
+
+// os.DirFS 與 embed.FS 的檔案皆可直接 Seek 與隨機讀取，其他 fs.FS 的檔案則讀取至記憶體中
+func seekable(file fs.File) (staticContent, error) {
+	if content, ok := file.(staticContent); ok {
+		return content, nil
+	}
+
+	data, err := io.ReadAll(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+// 根據副檔名決定 Content-Type，無法判斷時才讀取檔案開頭的數據(最多 512 bytes)來判斷
+func staticContentType(info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if contentType := mime.TypeByExtension(path.Ext(info.Name())); contentType != "" {
+		return contentType, nil
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return ghttp.DetectContentType(info.Name(), head[:n]), nil
+}
+
+// 將請求路徑轉換為 fs.FS 的路徑，包含 .. 等跳脫 fsys 的路徑時返回 false
+func cleanStaticPath(name string) (string, bool) {
+	name, err := url.PathUnescape(name)
+
+	if err != nil || strings.ContainsAny(name, "\\\x00") {
+		return "", false
+	}
+
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", false
+		}
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	if name == "" {
+		name = "."
+	}
+
+	return name, fs.ValidPath(name)
+}
+
+// 開啟檔案，name 為目錄時開啟目錄中的 index(index 為空字串時視為不存在)，並返回實際開啟的檔案路徑
+func openStatic(fsys fs.FS, name string, index string) (fs.File, fs.FileInfo, string, error) {
+	file, err := fsys.Open(name)
+
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return nil, nil, "", err
+	}
+
+	if info.IsDir() {
+		file.Close()
+
+		if index == "" {
+			return nil, nil, "", errors.Errorf("%s is a directory", name)
+		}
+
+		return openStatic(fsys, path.Join(name, index), "")
+	}
+
+	return file, info, name, nil
+}
+
+// 根據檔案內容生成的 ETag，以及生成時的檔案大小
+type staticETagEntry struct {
+	size int64
+	etag string
+}
+
+// 有修改時間時，根據檔案大小與修改時間生成 ETag，否則根據檔案內容生成，並以檔案路徑快取於 etags 中，
+// 避免每次請求都讀取整個檔案(檔案大小改變時重新生成)
+func staticETag(etags *sync.Map, name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano()), nil
+	}
+
+	if value, ok := etags.Load(name); ok {
+		if entry := value.(*staticETagEntry); entry.size == info.Size() {
+			return entry.etag, nil
+		}
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:8]) + `"`
+	etags.Store(name, &staticETagEntry{size: info.Size(), etag: etag})
+	return etag, nil
+}
+
+// 根據 If-None-Match(優先)或 If-Modified-Since，判斷客戶端的快取是否仍有效
+func isNotModified(c *ghttp.Context, etag string, modTime time.Time) bool {
+	if inm := c.Request.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := c.Request.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		t, err := time.Parse(ghttp.TimeFormat, ims)
+		// Last-Modified 只精確到秒
+		return err == nil && !modTime.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// 沒有 If-Range，或 If-Range 與檔案的 ETag/Last-Modified 相符時，才處理範圍請求
+func matchIfRange(c *ghttp.Context, etag string, modTime time.Time) bool {
+	ifRange := c.Request.Header.Get("If-Range")
+
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, `"`) {
+		return ifRange == etag
+	}
+
+	t, err := time.Parse(ghttp.TimeFormat, ifRange)
+	return err == nil && !modTime.IsZero() && modTime.Truncate(time.Second).Equal(t)
+}
+
+// 解析單一範圍(bytes=start-end, bytes=start-, bytes=-suffix)，返回包含 end 的範圍
+// 多重範圍等不支援的格式返回 start = -1，範圍無法滿足時返回錯誤
+func parseRange(header string, size int64) (start int64, end int64, err error) {
+	if !strings.HasPrefix(header, "bytes=") {
+		return -1, -1, nil
+	}
+
+	if size == 0 {
+		return 0, 0, errors.Errorf("Unsatisfiable range: %s, size: 0", header)
+	}
+
+	spec := strings.TrimPrefix(header, "bytes=")
+
+	if strings.Contains(spec, ",") {
+		return -1, -1, nil
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+
+	if !ok {
+		return 0, 0, errors.Errorf("Invalid range: %s", header)
+	}
+
+	if first == "" {
+		// 最後 suffix 個 bytes
+		suffix, err := strconv.ParseInt(last, 10, 64)
+
+		if err != nil || suffix <= 0 {
+			return 0, 0, errors.Errorf("Invalid suffix range: %s", header)
+		}
+
+		if suffix > size {
+			suffix = size
+		}
+
+		return size - suffix, size - 1, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, errors.Errorf("Unsatisfiable range: %s, size: %d", header, size)
+	}
+
+	end = size - 1
+
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+
+		if err != nil || end < start {
+			return 0, 0, errors.Errorf("Invalid range: %s", header)
+		}
+
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end, nil
+}
@@ -5,8 +5,12 @@ import (
 	"time"
 
 	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/base/gproto"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
 
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 )
 
 // ====================================================================================================
@@ -17,9 +21,13 @@ type Tcp0Anser struct {
 	*Anser
 	tcp0s    []*base.Tcp0
 	currTcp0 *base.Tcp0
+	// 長度前綴的格式
+	framing *base.Tcp0Framing
+	// protobuf 訊息(未設置時為 nil)
+	messages *gproto.Messages
 }
 
-func NewTcp0Anser(laddr *net.TCPAddr, nConnect int32, nWork int32) (IAnswer, error) {
+func NewTcp0Anser(laddr net.Addr, nConnect int32, nWork int32) (IAnswer, error) {
 	var err error
 	a := &Tcp0Anser{
 		tcp0s:    make([]*base.Tcp0, nConnect),
@@ -28,12 +36,13 @@ func NewTcp0Anser(laddr *net.TCPAddr, nConnect int32, nWork int32) (IAnswer, err
 
 	// ===== Anser =====
 	a.Anser, err = newAnser(laddr, nConnect, nWork)
-	a.Anser.ReadTimeout = 5000 * time.Millisecond
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to new Tcp0Anser.")
 	}
 
+	a.Anser.ReadTimeout = 5000 * time.Millisecond
+
 	// ===== Tcp0 =====
 	var i int32
 
@@ -41,6 +50,10 @@ func NewTcp0Anser(laddr *net.TCPAddr, nConnect int32, nWork int32) (IAnswer, err
 		a.tcp0s[i] = base.NewTcp0()
 	}
 
+	if err = a.SetFraming(base.NewTcp0Framing()); err != nil {
+		return nil, errors.Wrapf(err, "Failed to new Tcp0Anser.")
+	}
+
 	//////////////////////////////////////////////////
 	// 自定義函式
 	//////////////////////////////////////////////////
@@ -48,9 +61,43 @@ func NewTcp0Anser(laddr *net.TCPAddr, nConnect int32, nWork int32) (IAnswer, err
 	a.readFunc = a.read
 	a.writeFunc = a.write
 	a.shouldCloseFunc = a.shouldClose
+	a.disconnectFunc = a.onDisconnect
 	return a, nil
 }
 
+// 設置長度前綴的格式(須於 Listen 之前呼叫)，MaxFrameSize 為 0 時，以讀取緩存可容納的長度作為上限
+// 工作的 SendTransData 以及 WriteFrame 將以相同格式加上長度前綴
+func (a *Tcp0Anser) SetFraming(framing *base.Tcp0Framing) error {
+	if framing == nil {
+		return errors.New("Tcp0 framing is nil.")
+	}
+
+	// 一包數據須可完整放入連線的讀取緩存，數據部分須可放入 readBuffer
+	limit := a.conns.BufferLength - define.MTU
+
+	if size := int32(len(a.readBuffer)) + framing.HeaderSize; size < limit {
+		limit = size
+	}
+
+	f, err := framing.Limit(limit)
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to set framing at %s.", a.laddr)
+	}
+
+	a.framing = f
+
+	for _, tcp0 := range a.tcp0s {
+		tcp0.SetFraming(f)
+	}
+
+	for work := a.works; work != nil; work = work.Next {
+		work.Framing = f
+	}
+
+	return nil
+}
+
 // 監聽連線並註冊
 func (a *Tcp0Anser) Listen() {
 	a.Anser.Listen()
@@ -64,11 +111,14 @@ func (a *Tcp0Anser) read() bool {
 			// 從 readBuffer 當中讀取封包長度
 			a.currConn.Read(&a.readBuffer, a.currTcp0.HeaderSize)
 
-			// 下次欲讀取長度為封包長度
-			a.currTcp0.ReadLength = base.BytesToInt32(a.readBuffer[:a.currTcp0.HeaderSize], a.order)
-
-			// 更新 currTcp0 狀態值
-			a.currTcp0.State = 1
+			// 下次欲讀取長度為封包長度，並更新 currTcp0 狀態值；長度不合法或超過上限時，立即斷線
+			if err := a.currTcp0.ReadHeader(a.readBuffer[:a.currTcp0.HeaderSize]); err != nil {
+				utils.Error("Conn(%d) %+v", a.currConn.GetId(), err)
+				a.currTcp0.ResetReadLength()
+				a.currConn.State = define.Disconnect
+				a.currConn.SetDisconnectTime(0)
+				return false
+			}
 
 		} else {
 			// 將傳入的數據，加入工作緩存中
@@ -95,11 +145,48 @@ func (a *Tcp0Anser) write(cid int32, data *[]byte, length int32) error {
 	return a.Write(cid, data, length)
 }
 
+// 以設置的格式加上長度前綴後寫出
+func (a *Tcp0Anser) WriteFrame(cid int32, data []byte) error {
+	frame, err := a.framing.Encode(data)
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write to conn(%d).", cid)
+	}
+
+	return a.Write(cid, &frame, int32(len(frame)))
+}
+
 // 由外部定義 workHandler，定義如何處理工作
 func (a *Tcp0Anser) SetWorkHandler(handler func(*base.Work)) {
 	a.Anser.workHandler = handler
 }
 
+// 以 protobuf 訊息取代工作處理函式，收到的訊息將交由 messages 中註冊的處理函式處理
+func (a *Tcp0Anser) SetMessages(messages *gproto.Messages) {
+	a.messages = messages
+	a.Anser.workHandler = messages.Handle
+}
+
+// 送出 protobuf 訊息(須先呼叫 SetMessages，且訊息型別已註冊)
+func (a *Tcp0Anser) SendMessage(cid int32, msg proto.Message) error {
+	if a.messages == nil {
+		return errors.New("Messages is not set.")
+	}
+
+	data, err := a.messages.Marshal(msg)
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to send message to conn(%d).", cid)
+	}
+
+	return a.WriteFrame(cid, data)
+}
+
+// 釋放連線物件前，重置讀取狀態
+func (a *Tcp0Anser) onDisconnect(cid int32) {
+	a.tcp0s[cid].ResetReadLength()
+}
+
 // 當前連線是否應斷線
 func (a *Tcp0Anser) shouldClose(err error) bool {
 	return a.Anser.shouldClose(err)
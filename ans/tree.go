@@ -0,0 +1,208 @@
+package ans
+
+import (
+	"strings"
+
+	"github.com/j32u4ukh/gos/utils"
+)
+
+// ====================================================================================================
+// routeTree
+// 以路徑片段為單位的前綴樹，每個節點對應一個 node 定義，匹配時的優先順序為:
+// 靜態片段 > 具型別的參數(int/uint/float) > 字串參數 > 萬用片段(*name)
+// ====================================================================================================
+type routeTree struct {
+	// 對應最開始的 '/' 所形成的空字串 node
+	root *treeNode
+}
+
+func newRouteTree() *routeTree {
+	t := &routeTree{
+		root: newTreeNode(newNode("")),
+	}
+	return t
+}
+
+type treeNode struct {
+	// 此節點的定義
+	node *node
+	// 靜態子節點(key: 路徑片段)
+	statics map[string]*treeNode
+	// 參數子節點(具型別的參數排在字串參數之前)
+	params []*treeNode
+	// 萬用子節點(匹配剩餘的所有路徑片段)
+	catchAll *treeNode
+	// 路徑於此節點結束時，所對應的 EndPoint
+	endpoint *EndPoint
+}
+
+func newTreeNode(n *node) *treeNode {
+	tn := &treeNode{
+		node:     n,
+		statics:  map[string]*treeNode{},
+		params:   []*treeNode{},
+		catchAll: nil,
+		endpoint: nil,
+	}
+	return tn
+}
+
+// 根據 nodes 建立路徑(nodes[0] 為根節點)，並返回路徑終點的樹節點
+func (t *routeTree) insert(nodes []*node) *treeNode {
+	curr := t.root
+	var n *node
+	var child *treeNode
+
+	for i := 1; i < len(nodes); i++ {
+		n = nodes[i]
+
+		switch {
+		case n.isCatchAll:
+			if i != len(nodes)-1 {
+				utils.Error("Catch-all segment *%s must be the last segment.", n.route)
+				return nil
+			}
+			if curr.catchAll == nil {
+				curr.catchAll = newTreeNode(n)
+			} else if curr.catchAll.node.route != n.route {
+				utils.Warn("Catch-all segment *%s conflicts with *%s.", n.route, curr.catchAll.node.route)
+			}
+			child = curr.catchAll
+
+		case n.isParam:
+			child = nil
+			for _, p := range curr.params {
+				if p.node.route == n.route && p.node.routeType == n.routeType {
+					child = p
+					break
+				}
+			}
+			if child == nil {
+				child = newTreeNode(n)
+				curr.addParam(child)
+			}
+
+		default:
+			var ok bool
+			if child, ok = curr.statics[n.route]; !ok {
+				child = newTreeNode(n)
+				curr.statics[n.route] = child
+			}
+		}
+
+		curr = child
+	}
+
+	return curr
+}
+
+// 加入參數子節點，並維持具型別的參數排在字串參數之前
+func (tn *treeNode) addParam(child *treeNode) {
+	if child.node.isTyped() {
+		idx := 0
+		for idx < len(tn.params) && tn.params[idx].node.isTyped() {
+			idx++
+		}
+		tn.params = append(tn.params, nil)
+		copy(tn.params[idx+1:], tn.params[idx:])
+		tn.params[idx] = child
+	} else {
+		tn.params = append(tn.params, child)
+	}
+}
+
+// 路由匹配時所取得的路徑參數，每次請求各自獨立
+type routeParam struct {
+	key string
+	// 原始字串
+	raw string
+	// 根據路由型別轉換後的數值
+	value any
+}
+
+// 根據路徑片段(splits[0] 為根節點所對應的空字串)，尋找支援 method 的 EndPoint，並將路徑參數依序附加到 params
+// 若沒有支援 method 的 EndPoint，則透過 allowed 返回所有路徑匹配的 EndPoint 所支援的 Method(用於 Allow 標頭)
+func (t *routeTree) lookup(method string, splits []string, params *[]routeParam) (endpoint *EndPoint, allowed []string) {
+	if len(splits) == 0 || splits[0] != "" {
+		return nil, nil
+	}
+	endpoint = t.root.lookup(method, splits[1:], params, &allowed)
+	return endpoint, allowed
+}
+
+func (tn *treeNode) lookup(method string, splits []string, params *[]routeParam, allowed *[]string) *EndPoint {
+	if len(splits) == 0 {
+		if tn.endpoint != nil {
+			if tn.endpoint.hasMethod(method) {
+				return tn.endpoint
+			}
+			addAllowed(allowed, tn.endpoint)
+		}
+
+		// 萬用片段也可以匹配空的剩餘路徑
+		if tn.catchAll != nil {
+			return tn.catchAll.matchCatchAll(method, splits, params, allowed)
+		}
+		return nil
+	}
+
+	route := splits[0]
+	var ep *EndPoint
+	nParam := len(*params)
+
+	// 靜態片段
+	if child, ok := tn.statics[route]; ok {
+		if ep = child.lookup(method, splits[1:], params, allowed); ep != nil {
+			return ep
+		}
+	}
+
+	// 參數片段(具型別的參數優先)
+	for _, child := range tn.params {
+		if value, ok := child.node.match(route); ok {
+			*params = append(*params, routeParam{key: child.node.route, raw: route, value: value})
+			if ep = child.lookup(method, splits[1:], params, allowed); ep != nil {
+				return ep
+			}
+			*params = (*params)[:nParam]
+		}
+	}
+
+	// 萬用片段
+	if tn.catchAll != nil {
+		return tn.catchAll.matchCatchAll(method, splits, params, allowed)
+	}
+
+	return nil
+}
+
+// 萬用片段匹配剩餘的所有路徑片段
+func (tn *treeNode) matchCatchAll(method string, splits []string, params *[]routeParam, allowed *[]string) *EndPoint {
+	if tn.endpoint == nil {
+		return nil
+	}
+	if !tn.endpoint.hasMethod(method) {
+		addAllowed(allowed, tn.endpoint)
+		return nil
+	}
+	route := strings.Join(splits, "/")
+	*params = append(*params, routeParam{key: tn.node.route, raw: route, value: route})
+	return tn.endpoint
+}
+
+// 將 EndPoint 所支援的 Method 加入 allowed(不重複，並維持首次出現的順序)
+func addAllowed(allowed *[]string, ep *EndPoint) {
+	var exists bool
+	for _, option := range ep.options {
+		exists = false
+		for _, a := range *allowed {
+			if a == option {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			*allowed = append(*allowed, option)
+		}
+	}
+}
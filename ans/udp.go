@@ -0,0 +1,195 @@
+package ans
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// UdpAnser
+// 根據對方的位置建立虛擬連線(擁有 cid，閒置超過 ReadTimeout 則斷線)，每個訊息作為一個 base.Work 交由 workHandler 處理
+// 訊息可選擇是否保證送達與順序(可靠訊息)，工作的輸出數據沿用該連線最後收到的訊息是否可靠
+// ====================================================================================================
+
+type UdpAnser struct {
+	*Anser
+	udps    []*base.Udp
+	currUdp *base.Udp
+}
+
+func NewUdpAnser(laddr net.Addr, nConnect int32, nWork int32) (IAnswer, error) {
+	a := &UdpAnser{
+		udps:    make([]*base.Udp, nConnect),
+		currUdp: nil,
+	}
+
+	// ===== Anser =====
+	uaddr, err := base.ToUdpAddr(laddr)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new UdpAnser.")
+	}
+
+	// 已建立的連線與等待中的連線各 nConnect 個
+	listener, err := base.ListenUdp(uaddr, int(nConnect), 2*int(nConnect))
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new UdpAnser.")
+	}
+
+	a.Anser = newAnserWithListener(laddr, listener, nConnect, nWork)
+	a.Anser.ReadTimeout = utils.GosConfig.UdpReadTimeout
+
+	// ===== Udp =====
+	var i int32
+
+	for i = 0; i < nConnect; i++ {
+		a.udps[i] = base.NewUdp()
+	}
+
+	//////////////////////////////////////////////////
+	// 自定義函式
+	//////////////////////////////////////////////////
+	a.readFunc = a.read
+	a.writeFunc = a.write
+	a.shouldCloseFunc = a.shouldClose
+	a.disconnectFunc = a.onDisconnect
+	return a, nil
+}
+
+// 監聽連線並註冊
+func (a *UdpAnser) Listen() {
+	a.Anser.Listen()
+}
+
+// UDP 不支援 TLS
+func (a *UdpAnser) UseTLS(config *tls.Config) error {
+	return errors.Errorf("TLS is not supported by Udp at %s.", a.laddr)
+}
+
+func (a *UdpAnser) read() bool {
+	cid := a.currConn.GetId()
+	a.currUdp = a.udps[cid]
+
+	for {
+		// 交付已收到的訊息(工作結構不足時，保留至下次迴圈)
+		for a.currWork != nil && a.currUdp.HasMessage() {
+			payload, _ := a.currUdp.PopMessage()
+			a.currWork.Index = cid
+			a.currWork.RequestTime = time.Now().UTC()
+			a.currWork.State = base.WORK_NEED_PROCESS
+			a.currWork.Body.AddRawData(payload)
+			a.currWork.Body.ResetIndex()
+
+			// 指向下一個工作結構
+			a.currWork = a.currWork.Next
+		}
+
+		if a.currWork == nil {
+			break
+		}
+
+		datagram, ok := a.currUdp.ReadDatagram(a.currConn, &a.readBuffer)
+
+		if !ok {
+			break
+		}
+
+		reply, err := a.currUdp.Handle(datagram)
+
+		if err != nil {
+			utils.Warn("Conn(%d) drop datagram: %+v", cid, err)
+			continue
+		}
+
+		if reply != nil {
+			a.currConn.SetWriteBuffer(&reply, int32(len(reply)))
+		}
+	}
+
+	// 重送逾時未確認的可靠訊息
+	frames, err := a.currUdp.Resend(time.Now())
+
+	if err != nil {
+		utils.Error("Conn(%d) %+v", cid, err)
+		a.currConn.State = define.Disconnect
+		a.currConn.SetDisconnectTime(0)
+		return false
+	}
+
+	for _, frame := range frames {
+		a.currConn.SetWriteBuffer(&frame, int32(len(frame)))
+	}
+
+	return true
+}
+
+// 工作的輸出數據，沿用該連線最後收到的訊息是否可靠
+func (a *UdpAnser) write(cid int32, data *[]byte, length int32) error {
+	return a.Write(cid, data, length)
+}
+
+// 供外部寫出數據，沿用該連線最後收到的訊息是否可靠
+func (a *UdpAnser) Write(cid int32, data *[]byte, length int32) error {
+	if cid < 0 || int(cid) >= len(a.udps) {
+		return errors.Errorf("There is no cid equals to %d.", cid)
+	}
+	return a.WriteMessage(cid, (*data)[:length], a.udps[cid].Reliable)
+}
+
+// 送出保證送達與順序的訊息
+func (a *UdpAnser) WriteReliable(cid int32, data []byte) error {
+	return a.WriteMessage(cid, data, true)
+}
+
+// 送出不保證送達與順序的訊息
+func (a *UdpAnser) WriteUnreliable(cid int32, data []byte) error {
+	return a.WriteMessage(cid, data, false)
+}
+
+func (a *UdpAnser) WriteMessage(cid int32, data []byte, reliable bool) error {
+	c := a.getConn(cid)
+
+	if c == nil || c.State != define.Connected {
+		return errors.Errorf("Conn(%d) is not connected.", cid)
+	}
+
+	frame, err := a.udps[cid].Encode(data, reliable)
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write to conn(%d).", cid)
+	}
+
+	c.SetWriteBuffer(&frame, int32(len(frame)))
+	return nil
+}
+
+// 取得連線 cid 的對方位置
+func (a *UdpAnser) RemoteAddr(cid int32) net.Addr {
+	if c := a.getConn(cid); c != nil && c.NetConn != nil {
+		return c.NetConn.RemoteAddr()
+	}
+	return nil
+}
+
+// 由外部定義 workHandler，定義如何處理工作
+func (a *UdpAnser) SetWorkHandler(handler func(*base.Work)) {
+	a.Anser.workHandler = handler
+}
+
+// 釋放連線物件前，重置協定狀態
+func (a *UdpAnser) onDisconnect(cid int32) {
+	a.udps[cid].Reset()
+}
+
+// 當前連線是否應斷線
+func (a *UdpAnser) shouldClose(err error) bool {
+	return a.Anser.shouldClose(err)
+}
@@ -0,0 +1,439 @@
+package ans
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// WebSocketAnser
+// 以 HTTP 升級交握建立連線後，每則完整的訊息(重組分段後)作為一個 base.Work 交由 workHandler 處理
+// 回應的訊息類型沿用該連線最後收到的訊息類型(WsText 或 WsBinary)
+// ====================================================================================================
+
+type WebSocketAnser struct {
+	*Anser
+	// 允許升級的路徑(為空時允許所有路徑)
+	paths map[string]bool
+	// 檢查升級請求(如 Origin、驗證資訊)，返回 false 時回應 403(nil 表示不檢查)
+	CheckOrigin func(r *ghttp.Request) bool
+	// 伺服器支援的子協定(依優先順序)
+	Subprotocols []string
+	// 完成交握時呼叫
+	OnOpen func(cid int32, r *ghttp.Request)
+	// 連線關閉時呼叫(未收到關閉幀而中斷時，code 為 base.CloseAbnormalClosure)
+	OnClose func(cid int32, code uint16, reason string)
+	// 閒置超過此時間時送出 Ping(0 表示不送出)
+	PingInterval time.Duration
+
+	sockets    []*base.WebSocket
+	currSocket *base.WebSocket
+	// 升級請求(交握完成後保留，供 GetRequest 取得)
+	contexts    []*ghttp.Context
+	currContext *ghttp.Context
+	// 各連線最後收到數據的時間與送出 Ping 的時間
+	lastReads []time.Time
+	lastPings []time.Time
+	// 已通知 OnClose 的連線
+	closed []bool
+}
+
+func NewWebSocketAnser(laddr net.Addr, nConnect int32, nWork int32) (IAnswer, error) {
+	var err error
+	a := &WebSocketAnser{
+		paths:        map[string]bool{},
+		Subprotocols: []string{},
+		PingInterval: utils.GosConfig.WebSocketReadTimeout / 2,
+		sockets:      make([]*base.WebSocket, nConnect),
+		contexts:     make([]*ghttp.Context, nConnect),
+		lastReads:    make([]time.Time, nConnect),
+		lastPings:    make([]time.Time, nConnect),
+		closed:       make([]bool, nConnect),
+	}
+
+	// ===== Anser =====
+	a.Anser, err = newAnser(laddr, nConnect, nWork)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new WebSocketAnser.")
+	}
+
+	a.Anser.ReadTimeout = utils.GosConfig.WebSocketReadTimeout
+
+	// ===== WebSocket =====
+	var i int32
+
+	for i = 0; i < nConnect; i++ {
+		a.sockets[i] = base.NewWebSocket(false, utils.GosConfig.WebSocketMaxMessageSize)
+		a.contexts[i] = ghttp.NewContext(i)
+	}
+
+	//////////////////////////////////////////////////
+	// 自定義函式
+	//////////////////////////////////////////////////
+	a.readFunc = a.read
+	a.writeFunc = a.write
+	a.shouldCloseFunc = a.shouldClose
+	a.disconnectFunc = a.onDisconnect
+	return a, nil
+}
+
+// 監聽連線並註冊
+func (a *WebSocketAnser) Listen() {
+	a.Anser.Listen()
+}
+
+// 允許在 path 上進行升級交握
+func (a *WebSocketAnser) HandlePath(path string) {
+	a.paths[path] = true
+}
+
+// 由外部定義 workHandler，定義如何處理工作
+func (a *WebSocketAnser) SetWorkHandler(handler func(*base.Work)) {
+	a.Anser.workHandler = handler
+}
+
+// 取得連線 cid 的升級請求(包含路徑、GET 參數與標頭)
+func (a *WebSocketAnser) GetRequest(cid int32) *ghttp.Request {
+	if cid < 0 || int(cid) >= len(a.contexts) {
+		return nil
+	}
+	return a.contexts[cid].Request
+}
+
+func (a *WebSocketAnser) read() bool {
+	cid := a.currConn.GetId()
+	a.currSocket = a.sockets[cid]
+	a.currContext = a.contexts[cid]
+
+	if a.currSocket.State == base.WS_HANDSHAKE {
+		a.closed[cid] = false
+		return a.readHandshake()
+	}
+
+	if a.currSocket.State == base.WS_CLOSED {
+		return false
+	}
+
+	now := time.Now()
+
+	if a.currConn.ReadableLength > 0 {
+		a.lastReads[cid] = now
+	} else if a.PingInterval > 0 && a.currSocket.IsOpen() &&
+		now.Sub(a.lastReads[cid]) >= a.PingInterval && now.Sub(a.lastPings[cid]) >= a.PingInterval {
+		// 閒置時送出 Ping，由客戶端的 Pong 維持連線
+		a.lastPings[cid] = now
+		a.writeFrame(cid, base.WsPing, []byte{})
+	}
+
+	for a.currWork != nil {
+		opcode, payload, ok, err := a.currSocket.ReadMessage(a.currConn, &a.readBuffer)
+
+		if err != nil {
+			utils.Warn("Conn(%d) %+v", cid, err)
+			code := base.CloseProtocolError
+
+			if wsErr, ok := err.(*base.WebSocketError); ok {
+				code = wsErr.Code
+			}
+
+			a.closeConn(cid, code, err.Error())
+			return false
+		}
+
+		if !ok {
+			return true
+		}
+
+		switch opcode {
+		case base.WsText, base.WsBinary:
+			// 收到完整的訊息，加入工作緩存中
+			a.currWork.Index = cid
+			a.currWork.RequestTime = time.Now().UTC()
+			a.currWork.State = base.WORK_NEED_PROCESS
+			a.currWork.Body.AddRawData(payload)
+			a.currWork.Body.ResetIndex()
+
+			// 指向下一個工作結構
+			a.currWork = a.currWork.Next
+
+		case base.WsPing:
+			a.writeFrame(cid, base.WsPong, payload)
+
+		case base.WsPong:
+
+		case base.WsClose:
+			code, reason, err := base.ParseClosePayload(payload)
+
+			if err != nil {
+				utils.Warn("Conn(%d) %+v", cid, err)
+				a.closeConn(cid, err.(*base.WebSocketError).Code, err.Error())
+				return false
+			}
+
+			// 回應關閉幀後斷線(若是由伺服器端發起的關閉，則此為客戶端的回應)
+			if !a.currSocket.CloseSent {
+				a.writeFrame(cid, base.WsClose, base.EncodeClosePayload(code, ""))
+				a.currSocket.CloseSent = true
+			}
+
+			a.currSocket.State = base.WS_CLOSED
+			a.notifyClose(cid, code, reason)
+			a.disconnect(a.currConn)
+			return false
+		}
+	}
+
+	return true
+}
+
+// 讀取升級請求的第一行與標頭，標頭讀取完畢後進行交握
+// 第一行與標頭的總長度上限同 HttpMaxHeaderSize，避免不含換行的數據填滿讀取緩衝而使連線停止讀取
+func (a *WebSocketAnser) readHandshake() bool {
+	for a.currConn.CheckReadable(a.currContext.Request.HasLineData) {
+		a.currConn.Read(&a.readBuffer, a.currContext.Request.ReadLength)
+		a.currContext.AddHeaderLength(a.currContext.Request.ReadLength)
+
+		if a.currContext.GetHeaderLength() > utils.GosConfig.HttpMaxHeaderSize {
+			a.rejectHandshake(ghttp.StatusRequestHeaderFieldsTooLarge, "Request Header Fields Too Large")
+			return false
+		}
+
+		line := strings.TrimRight(string(a.readBuffer[:a.currContext.Request.ReadLength]), "\r\n")
+
+		if a.currContext.Request.Method == "" {
+			if !a.currContext.ParseFirstReqLine(line) {
+				a.rejectHandshake(ghttp.StatusBadRequest, "Invalid request line.")
+				return false
+			}
+
+			if _, err := a.currContext.ParseQuery(); err != nil {
+				utils.Warn("Failed to parse query: %+v", err)
+			}
+			continue
+		}
+
+		// 空行，標頭讀取完畢
+		if line == "" {
+			a.handshake()
+			return false
+		}
+
+		key, value, ok := strings.Cut(line, ghttp.COLON)
+
+		if !ok {
+			a.rejectHandshake(ghttp.StatusBadRequest, "Invalid header line.")
+			return false
+		}
+
+		key = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key))
+		a.currContext.Request.Header.Add(key, strings.TrimSpace(value))
+	}
+
+	// 尚未讀到換行，但已超過長度上限
+	if a.currContext.GetHeaderLength()+a.currConn.ReadableLength > utils.GosConfig.HttpMaxHeaderSize {
+		a.rejectHandshake(ghttp.StatusRequestHeaderFieldsTooLarge, "Request Header Fields Too Large")
+		return false
+	}
+
+	return true
+}
+
+func (a *WebSocketAnser) handshake() {
+	r := a.currContext.Request
+	cid := a.currConn.GetId()
+
+	if len(a.paths) > 0 && !a.paths[r.Query] {
+		a.rejectHandshake(ghttp.StatusNotFound, fmt.Sprintf("Path %s is not found.", r.Query))
+		return
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+
+	if r.Method != ghttp.MethodGet || r.Proto != "HTTP/1.1" ||
+		!r.Header.HasToken("Connection", "upgrade") || !r.Header.HasToken("Upgrade", "websocket") ||
+		!base.IsValidWebSocketKey(key) {
+		a.rejectHandshake(ghttp.StatusBadRequest, "Invalid WebSocket handshake.")
+		return
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		a.rejectHandshake(ghttp.StatusUpgradeRequired, "Unsupported WebSocket version.")
+		return
+	}
+
+	if a.CheckOrigin != nil && !a.CheckOrigin(r) {
+		a.rejectHandshake(ghttp.StatusForbidden, "Origin is not allowed.")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString(fmt.Sprintf("Sec-WebSocket-Accept: %s\r\n", base.WebSocketAccept(key)))
+
+	if protocol := a.selectSubprotocol(r); protocol != "" {
+		b.WriteString(fmt.Sprintf("Sec-WebSocket-Protocol: %s\r\n", protocol))
+	}
+
+	b.WriteString("\r\n")
+	data := []byte(b.String())
+	a.Anser.Write(cid, &data, int32(len(data)))
+	a.currSocket.Open()
+	a.lastReads[cid] = time.Now()
+	a.lastPings[cid] = time.Now()
+	utils.Info("Conn(%d) upgraded to WebSocket, path: %s", cid, r.Query)
+
+	if a.OnOpen != nil {
+		a.OnOpen(cid, r)
+	}
+}
+
+// 選擇客戶端與伺服器皆支援的子協定(以伺服器的優先順序)
+func (a *WebSocketAnser) selectSubprotocol(r *ghttp.Request) string {
+	requested := map[string]bool{}
+
+	for _, line := range r.Header["Sec-Websocket-Protocol"] {
+		for _, protocol := range strings.Split(line, ",") {
+			requested[strings.TrimSpace(protocol)] = true
+		}
+	}
+
+	for _, protocol := range a.Subprotocols {
+		if requested[protocol] {
+			return protocol
+		}
+	}
+
+	return ""
+}
+
+// 以 HTTP 回應拒絕升級，並斷線
+func (a *WebSocketAnser) rejectHandshake(code int32, msg string) {
+	utils.Warn("Conn(%d) handshake is rejected: %s", a.currConn.GetId(), msg)
+	data := []byte(fmt.Sprintf("HTTP/1.1 %d %s\r\nConnection: close\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s",
+		code, ghttp.StatusText(code), ghttp.MIMEPlain, len(msg), msg))
+	a.Anser.Write(a.currConn.GetId(), &data, int32(len(data)))
+	a.currSocket.State = base.WS_CLOSED
+	a.disconnect(a.currConn)
+}
+
+// 送出關閉幀並斷線
+func (a *WebSocketAnser) closeConn(cid int32, code uint16, reason string) {
+	socket := a.sockets[cid]
+
+	if !socket.CloseSent {
+		a.writeFrame(cid, base.WsClose, base.EncodeClosePayload(code, reason))
+		socket.CloseSent = true
+	}
+
+	socket.State = base.WS_CLOSED
+	a.notifyClose(cid, code, reason)
+
+	if c := a.getConn(cid); c != nil {
+		a.disconnect(c)
+	}
+}
+
+// 標註為斷線，數秒後才切斷連線，預留時間給對方讀取數據
+func (a *WebSocketAnser) disconnect(c *base.Conn) {
+	if c.State == define.Connected {
+		c.State = define.Disconnect
+		c.SetDisconnectTime(utils.GosConfig.DisconnectTime)
+	}
+}
+
+func (a *WebSocketAnser) notifyClose(cid int32, code uint16, reason string) {
+	if !a.closed[cid] {
+		a.closed[cid] = true
+
+		if a.OnClose != nil {
+			a.OnClose(cid, code, reason)
+		}
+	}
+}
+
+// 釋放連線物件前，通知未正常關閉的連線，並重置協定狀態
+func (a *WebSocketAnser) onDisconnect(cid int32) {
+	if a.sockets[cid].State != base.WS_HANDSHAKE {
+		a.notifyClose(cid, base.CloseAbnormalClosure, "")
+	}
+
+	a.sockets[cid].Reset()
+	a.contexts[cid].Release()
+}
+
+// 工作的輸出數據，以該連線最後收到的訊息類型送出
+func (a *WebSocketAnser) write(cid int32, data *[]byte, length int32) error {
+	return a.Write(cid, data, length)
+}
+
+// 供外部寫出數據，以該連線最後收到的訊息類型送出
+func (a *WebSocketAnser) Write(cid int32, data *[]byte, length int32) error {
+	if cid < 0 || int(cid) >= len(a.sockets) {
+		return errors.Errorf("There is no cid equals to %d.", cid)
+	}
+	return a.WriteMessage(cid, a.sockets[cid].MessageType, (*data)[:length])
+}
+
+// 送出文字訊息
+func (a *WebSocketAnser) WriteText(cid int32, text string) error {
+	return a.WriteMessage(cid, base.WsText, []byte(text))
+}
+
+// 送出二進位訊息
+func (a *WebSocketAnser) WriteBinary(cid int32, data []byte) error {
+	return a.WriteMessage(cid, base.WsBinary, data)
+}
+
+// 以 messageType(base.WsText 或 base.WsBinary)送出訊息
+func (a *WebSocketAnser) WriteMessage(cid int32, messageType byte, data []byte) error {
+	if cid < 0 || int(cid) >= len(a.sockets) || !a.sockets[cid].IsOpen() {
+		return errors.Errorf("WebSocket %d is not open.", cid)
+	}
+
+	if messageType != base.WsText && messageType != base.WsBinary {
+		return errors.Errorf("Invalid message type %d.", messageType)
+	}
+
+	return a.writeFrame(cid, messageType, data)
+}
+
+// 對所有已完成交握的連線送出訊息
+func (a *WebSocketAnser) Broadcast(messageType byte, data []byte) {
+	for c := a.conns; c != nil && c.State != define.Unused; c = c.Next {
+		if c.State == define.Connected && a.sockets[c.GetId()].IsOpen() {
+			if err := a.WriteMessage(c.GetId(), messageType, data); err != nil {
+				utils.Error("Failed to broadcast to conn(%d): %+v", c.GetId(), err)
+			}
+		}
+	}
+}
+
+// 以 code 與 reason 關閉連線(送出關閉幀後，等待客戶端回應或斷線時間到達)
+func (a *WebSocketAnser) Close(cid int32, code uint16, reason string) error {
+	if cid < 0 || int(cid) >= len(a.sockets) || !a.sockets[cid].IsOpen() {
+		return errors.Errorf("WebSocket %d is not open.", cid)
+	}
+
+	a.closeConn(cid, code, reason)
+	return nil
+}
+
+func (a *WebSocketAnser) writeFrame(cid int32, opcode byte, payload []byte) error {
+	frame := base.EncodeWebSocketFrame(opcode, payload, false)
+	return a.Anser.Write(cid, &frame, int32(len(frame)))
+}
+
+// 當前連線是否應斷線
+func (a *WebSocketAnser) shouldClose(err error) bool {
+	return a.Anser.shouldClose(err)
+}
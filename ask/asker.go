@@ -1,7 +1,7 @@
 package ask
 
 import (
-	"encoding/binary"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"time"
@@ -22,23 +22,40 @@ type IAsker interface {
 	GetAddress() (string, int32)
 	// 供外部寫出數據(寫到寫出緩存中)
 	Write(*[]byte, int32) error
+	// 以 TLS 加密連線(須於 Connect 之前呼叫)
+	UseTLS(config *tls.Config) error
 }
 
-func NewAsker(socketType define.SocketType, site int32, laddr *net.TCPAddr, nWork int32, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (IAsker, error) {
+// TLS 交握的時間上限
+const tlsHandshakeTimeout time.Duration = 5 * time.Second
+
+func NewAsker(socketType define.SocketType, site int32, laddr net.Addr, nWork int32, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (IAsker, error) {
 	switch socketType {
 	case define.Tcp0:
 		return NewTcp0Asker(site, laddr, 1, nWork, onEvents, introduction, heartbeat)
 	// Chrome 一次最多可同時送出 6 個請求, HttpAsker nConnect = 6
 	case define.Http:
 		return NewHttpAsker(site, laddr, 6, nWork)
+	case define.WebSocket:
+		return NewWebSocketAsker(site, laddr, nWork, "/", onEvents)
+	case define.Udp:
+		return NewUdpAsker(site, laddr, nWork, onEvents, introduction)
+	case define.Line:
+		return NewLineAsker(site, laddr, nWork, onEvents, introduction, heartbeat)
 	default:
+		// 由 base.RegisterCodec 註冊的自定義協定
+		if factory, ok := base.GetCodec(socketType); ok {
+			return NewCodecAsker(site, laddr, nWork, factory, onEvents, introduction, heartbeat)
+		}
 		return nil, fmt.Errorf("invalid socket type: %v", socketType)
 	}
 }
 
 type Asker struct {
-	// 連線位置
-	addr *net.TCPAddr
+	// 連線位置(*net.TCPAddr 或 Unix domain socket 的 *net.UnixAddr)
+	addr net.Addr
+	// TLS 設置(非 TLS 連線時為 nil)
+	tlsConfig *tls.Config
 	// 心跳包數據
 	heartbeatData []byte
 	// 心跳包數據長度
@@ -68,8 +85,6 @@ type Asker struct {
 	preConn *base.Conn
 	// 數據讀取緩存
 	readBuffer []byte
-	// 位元組順序 (Byte Order)，即 位元組 的排列順序
-	order binary.ByteOrder
 
 	// ==================================================
 	// 連線緩存
@@ -90,18 +105,21 @@ type Asker struct {
 	workHandler func(*base.Work)
 	readFunc    func()
 	writeFunc   func(int32, *[]byte, int32) error
+	// 建立連線(為 nil 時根據連線位置，以 TCP 或 Unix domain socket 連線)
+	dialFunc func() (net.Conn, error)
+	// 連線建立後(TLS 交握之後)，開始讀取數據前的協定交握(如 WebSocket 升級)
+	handshakeFunc func(conn net.Conn) error
 	// 管理各種連線事件觸發函式(例如: 連線、斷線、、、)
 	onEvents base.OnEventsFunc
 }
 
-func newAsker(site int32, laddr *net.TCPAddr, nConnect int32, nWork int32, introduction *[]byte, heartbeat *[]byte) (*Asker, error) {
+func newAsker(site int32, laddr net.Addr, nConnect int32, nWork int32, introduction *[]byte, heartbeat *[]byte) (*Asker, error) {
 	a := &Asker{
 		addr:              laddr,
 		heartbeatData:     nil,
 		introductionData:  nil,
 		heartbeatLifetime: 0,
 		readLifetime:      3000 * time.Millisecond,
-		order:             binary.LittleEndian,
 		index:             site,
 		maxConn:           nConnect,
 		conns:             base.NewConn(0, utils.GosConfig.ConnBufferSize),
@@ -149,24 +167,109 @@ func newAsker(site int32, laddr *net.TCPAddr, nConnect int32, nWork int32, intro
 	return a, nil
 }
 
+// 取得連線位置(Unix domain socket 返回 socket 檔案路徑，port 為 0)
 func (a *Asker) GetAddress() (string, int32) {
-	return a.addr.IP.String(), int32(a.addr.Port)
+	switch addr := a.addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP.String(), int32(addr.Port)
+	case *net.UnixAddr:
+		return addr.Name, 0
+	default:
+		return a.addr.String(), 0
+	}
 }
 
 func (a *Asker) Connect(index int32) error {
 	// 註冊連線通道
-	netConn, err := net.DialTCP("tcp", nil, a.addr)
+	var netConn net.Conn
+	var err error
+
+	if a.dialFunc != nil {
+		netConn, err = a.dialFunc()
+	} else {
+		netConn, err = a.dial()
+	}
+
 	if err != nil {
 		utils.Error("Failed to connect, err: %+v", err)
-		return errors.Wrapf(err, "Failed to connect to %s:%d.", a.addr.IP, a.addr.Port)
+		return errors.Wrapf(err, "Failed to connect to %s.", a.addr)
 	}
 	utils.Info("Conn(%d) connect to %+v", index, a.addr)
+	var conn net.Conn = netConn
+
+	if a.tlsConfig != nil {
+		conn, err = a.handshake(netConn)
+
+		if err != nil {
+			netConn.Close()
+			utils.Error("Failed to handshake, err: %+v", err)
+			return errors.Wrapf(err, "Failed to handshake with %s.", a.addr)
+		}
+	}
+
+	if a.handshakeFunc != nil {
+		err = a.handshakeFunc(conn)
+
+		if err != nil {
+			conn.Close()
+			utils.Error("Failed to handshake, err: %+v", err)
+			return errors.Wrapf(err, "Failed to handshake with %s.", a.addr)
+		}
+	}
 
 	// 註冊連線通道
-	a.connBuffer <- base.ConnBuffer{Conn: netConn, Index: index}
+	a.connBuffer <- base.ConnBuffer{Conn: conn, Index: index}
 	return nil
 }
 
+// 根據連線位置，以 TCP 或 Unix domain socket 連線
+func (a *Asker) dial() (net.Conn, error) {
+	switch addr := a.addr.(type) {
+	case *net.TCPAddr:
+		return net.DialTCP("tcp", nil, addr)
+	case *net.UnixAddr:
+		return base.DialUnix(addr)
+	default:
+		return nil, errors.Errorf("Unsupported address type %T.", a.addr)
+	}
+}
+
+// 以 TLS 加密連線(須於 Connect 之前呼叫)，未設置 config.ServerName 時，以連線位置的 IP 驗證伺服器憑證(Unix domain socket 須設置 config.ServerName)
+// 提供客戶端憑證(mTLS)時，設置 config.Certificates 或 config.GetClientCertificate(如 base.CertReloader)
+func (a *Asker) UseTLS(config *tls.Config) error {
+	if config == nil {
+		return errors.New("TLS config is nil.")
+	}
+
+	if config.ServerName == "" && !config.InsecureSkipVerify {
+		addr, ok := a.addr.(*net.TCPAddr)
+
+		if !ok {
+			return errors.Errorf("TLS config has no ServerName for %s.", a.addr)
+		}
+
+		config = config.Clone()
+		config.ServerName = addr.IP.String()
+	}
+
+	a.tlsConfig = config
+	return nil
+}
+
+// 完成 TLS 交握，使連線建立後即可取得對方的憑證
+func (a *Asker) handshake(netConn net.Conn) (*tls.Conn, error) {
+	tlsConn := tls.Client(netConn, a.tlsConfig)
+	tlsConn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	// 清除交握所使用的時間上限，讀取時間上限由 Handler 管理
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
 // TODO: 區分 1. 使用心跳機制維持連線的版本() 2.
 // 根據 RFC 2616 (page 46) 的標準定義，單個客戶端不允許開啟 2 個以上的長連接，這個標準的目的是減少 HTTP 響應的時候，減少網絡堵塞。
 func (a *Asker) Handler() {
@@ -224,7 +327,7 @@ func (a *Asker) checkConnection() {
 			a.emptyConn.NetConn = connBuffer.Conn
 			a.emptyConn.State = define.Connected
 			a.emptyConn.NetConn.SetReadDeadline(a.heartbeatTime.Add(a.readLifetime))
-			go a.emptyConn.Handler()
+			a.emptyConn.Start()
 
 			// 檢查是否有自我介紹用數據
 			if a.introductionData != nil {
@@ -252,7 +355,7 @@ func (a *Asker) connectedHandler() {
 	// TODO: 處理主動斷線
 	select {
 	// 封包事件
-	case packet = <-a.currConn.ReadCh:
+	case packet = <-a.currConn.ReadChannel():
 
 		// 封包讀取發生異常
 		if packet.Error != nil {
@@ -404,6 +507,13 @@ func (a *Asker) dealWork() {
 		case base.WORK_DONE:
 			finished = a.relinkWork(finished, true)
 		case base.WORK_NEED_PROCESS:
+			// 提供 TLS 連線狀態，使工作處理函式可取得對方的憑證
+			if a.tlsConfig != nil && a.currWork.TLS == nil {
+				if c := a.getConn(a.currWork.Index); c != nil {
+					a.currWork.TLS = c.TLSState()
+				}
+			}
+
 			// 對工作進行處理
 			a.workHandler(a.currWork)
 
@@ -0,0 +1,139 @@
+package ask
+
+import (
+	"net"
+	"time"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// CodecAsker
+// 以 base.Codec 切分與封裝訊息的自定義協定，每個訊息作為一個 base.Work 交由 workHandler 處理
+// 自我介紹、心跳包、工作以 Send 寫出的數據，以及 Write 寫出的數據，皆由 Codec 封裝
+// ====================================================================================================
+
+type CodecAsker struct {
+	*Asker
+	codec base.Codec
+}
+
+func NewCodecAsker(site int32, laddr net.Addr, nWork int32, factory base.CodecFactory, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (IAsker, error) {
+	if factory == nil {
+		return nil, errors.New("Failed to new CodecAsker: codec factory is nil.")
+	}
+
+	var err error
+	a := &CodecAsker{
+		codec: factory(),
+	}
+
+	if introduction, err = a.encode(introduction); err != nil {
+		return nil, errors.Wrapf(err, "Failed to encode introduction.")
+	}
+
+	if heartbeat, err = a.encode(heartbeat); err != nil {
+		return nil, errors.Wrapf(err, "Failed to encode heartbeat.")
+	}
+
+	a.Asker, err = newAsker(site, laddr, 1, nWork, introduction, heartbeat)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new CodecAsker.")
+	}
+
+	// 設置成功連線時的 callback
+	a.Asker.onEvents = onEvents
+
+	// 設置連線的模式
+	a.conns.Mode = base.KEEPALIVE
+
+	//////////////////////////////////////////////////
+	// CodecAsker 自定義函式
+	//////////////////////////////////////////////////
+	a.readFunc = a.read
+	a.writeFunc = a.write
+	a.handshakeFunc = a.handshake
+	return a, nil
+}
+
+func (a *CodecAsker) Connect() error {
+	return a.Asker.Connect(-1)
+}
+
+// 以 Codec 封裝自我介紹與心跳包數據
+func (a *CodecAsker) encode(data *[]byte) (*[]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	frame, err := a.codec.Encode(*data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &frame, nil
+}
+
+// 每次連線(包含重新連線)時，重置讀取狀態
+func (a *CodecAsker) handshake(conn net.Conn) error {
+	a.codec.Reset()
+	return nil
+}
+
+func (a *CodecAsker) read() {
+	// 工作結構不足時，保留至下次迴圈
+	for a.currWork != nil {
+		message, ok, err := a.codec.Decode(a.currConn, &a.readBuffer)
+
+		// 數據不合法，重新連線
+		if err != nil {
+			utils.Error("Conn(%d) %+v", a.currConn.GetId(), err)
+			a.codec.Reset()
+			a.currConn.State = define.Reconnect
+			return
+		}
+
+		if !ok {
+			return
+		}
+
+		a.currWork.Index = a.currConn.GetId()
+		a.currWork.RequestTime = time.Now().UTC()
+		a.currWork.State = base.WORK_NEED_PROCESS
+		a.currWork.Body.AddRawData(message)
+		a.currWork.Body.ResetIndex()
+
+		// 指向下一個工作結構
+		a.currWork = a.currWork.Next
+	}
+}
+
+// 內部寫出數據
+func (a *CodecAsker) write(id int32, data *[]byte, length int32) error {
+	err := a.Write(data, length)
+	a.currWork.State = base.WORK_DONE
+	return err
+}
+
+// 供外部寫出數據，由 Codec 封裝
+func (a *CodecAsker) Write(data *[]byte, length int32) error {
+	frame, err := a.codec.Encode((*data)[:length])
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to write.")
+	}
+
+	a.conns.SetWriteBuffer(&frame, int32(len(frame)))
+	return nil
+}
+
+// 由外部定義 workHandler，定義如何處理工作
+func (a *CodecAsker) SetWorkHandler(handler func(*base.Work)) {
+	a.Asker.workHandler = handler
+}
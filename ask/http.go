@@ -2,6 +2,7 @@ package ask
 
 import (
 	"net"
+	"net/textproto"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,7 +25,7 @@ type HttpAsker struct {
 	// 可以不用從第一個開始使用，結束使用後也不需要對順序進行調整
 	// 和工作結構不同，一個 Conn 和一個 httpConn 一一對應，但可以有 0 到多個工作結構
 	// ==================================================
-	contextPool sync.Pool
+	requestPool sync.Pool
 	contexts    []*ghttp.Context
 	context     *ghttp.Context
 
@@ -32,12 +33,12 @@ type HttpAsker struct {
 	Handlers map[int32]ghttp.HandlerFunc
 }
 
-func NewHttpAsker(site int32, laddr *net.TCPAddr, nConnect int32, nWork int32) (IAsker, error) {
+func NewHttpAsker(site int32, laddr net.Addr, nConnect int32, nWork int32) (IAsker, error) {
 	var err error
 	a := &HttpAsker{
 		contexts:    make([]*ghttp.Context, nConnect),
 		context:     nil,
-		contextPool: sync.Pool{New: func() any { return &ghttp.Context{} }},
+		requestPool: sync.Pool{New: func() any { return ghttp.NewContext(-1).Request }},
 		Handlers:    map[int32]ghttp.HandlerFunc{},
 	}
 
@@ -82,13 +83,15 @@ func (a *HttpAsker) read() {
 
 	// 讀取 第一行
 	if a.context.State == ghttp.READ_FIRST_LINE {
-		if a.currConn.CheckReadable(a.context.HasLineData) {
+		if a.currConn.CheckReadable(a.context.Response.HasLineData) {
 			a.currConn.Read(&a.readBuffer, a.context.Response.ReadLength)
 
 			// 拆分第一行數據 HTTP/1.1 200 OK\r\n
 			firstLine := strings.TrimRight(string(a.readBuffer[:a.context.Response.ReadLength]), "\r\n")
 			utils.Debug("firstLine: %s", firstLine)
 
+			// 清除前一個 Response 的 Header 與 Body
+			a.context.Response.Release()
 			a.context.ParseFirstResLine(firstLine)
 			a.context.State = ghttp.READ_HEADER
 			utils.Debug("State: READ_FIRST_LINE -> READ_HEADER")
@@ -100,7 +103,7 @@ func (a *HttpAsker) read() {
 		var headerLine, key, value string
 		var ok bool
 
-		for a.currConn.CheckReadable(a.context.HasLineData) && a.context.State == ghttp.READ_HEADER {
+		for a.currConn.CheckReadable(a.context.Response.HasLineData) && a.context.State == ghttp.READ_HEADER {
 			// 讀取一行數據
 			a.currConn.Read(&a.readBuffer, a.context.Response.ReadLength)
 
@@ -111,6 +114,9 @@ func (a *HttpAsker) read() {
 			key, value, ok = strings.Cut(headerLine, ghttp.COLON)
 
 			if ok {
+				// 標頭名稱不區分大小寫，統一轉換為標準格式(ex: content-length -> Content-Length)
+				key = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key))
+
 				// 持續讀取 Header
 				if _, ok := a.context.Response.Header[key]; !ok {
 					a.context.Response.Header[key] = []string{}
@@ -123,8 +129,14 @@ func (a *HttpAsker) read() {
 				// 當前這行數據不包含":"，結束 Header 的讀取
 				utils.Debug("Empty line")
 
-				// Header 中包含 Content-Length，狀態值設為 2，等待讀取後續數據
-				if contentLength, ok := a.context.Response.Header["Content-Length"]; ok {
+				// Header 中宣告了分塊傳輸，等待讀取分塊數據(優先於 Content-Length)
+				if ghttp.IsChunked(a.context.Response.Header) {
+					a.context.State = ghttp.READ_CHUNK_SIZE
+					utils.Debug("State: READ_HEADER -> READ_CHUNK_SIZE")
+					break
+
+				} else if contentLength, ok := a.context.Response.Header["Content-Length"]; ok {
+					// Header 中包含 Content-Length，狀態值設為 2，等待讀取後續數據
 					length, err := strconv.Atoi(contentLength[0])
 					utils.Debug("Content-Length: %d", length)
 
@@ -147,23 +159,48 @@ func (a *HttpAsker) read() {
 					a.currWork.Index = a.currConn.GetId()
 					a.currWork.RequestTime = time.Now().UTC()
 					a.currWork.State = base.WORK_NEED_PROCESS
+
+					// 指向下一個工作結構
+					a.currWork = a.currWork.Next
 				}
 				return
 			}
 		}
 	}
 
+	// 讀取分塊傳輸的 Body 數據
+	if a.context.State.IsReadingChunk() {
+		done, err := a.context.ReadChunkedResponse(a.currConn, &a.readBuffer)
+
+		if err != nil {
+			utils.Error("Conn(%d) chunk err: %+v", a.currConn.GetId(), err)
+
+			// 無法再正確解析後續數據，直接斷線
+			a.currConn.State = define.Disconnect
+			a.context.State = ghttp.READ_FIRST_LINE
+			return
+		}
+
+		if done {
+			// 重置狀態值
+			a.context.State = ghttp.READ_FIRST_LINE
+
+			// 數據已讀入 httpConn 當中，此處工作結構僅負責觸發 WorkHandler，進一步觸發 Callback 函式
+			a.currWork.Index = a.currConn.GetId()
+			a.currWork.RequestTime = time.Now().UTC()
+			a.currWork.State = base.WORK_NEED_PROCESS
+
+			// 指向下一個工作結構
+			a.currWork = a.currWork.Next
+		}
+		return
+	}
+
 	// 讀取 Body 數據
 	if a.context.State == ghttp.READ_BODY {
 		utils.Debug("State READ_BODY, a.httpConn.ReadLength: %d", a.context.Response.ReadLength)
 
-		if a.currConn.CheckReadable(a.context.HasEnoughData) {
-			// 將傳入的數據，加入工作緩存中
-			a.currConn.Read(&a.readBuffer, a.context.Response.ReadLength)
-			utils.Debug("State READ_BODY, data: %s", string(a.readBuffer[:a.context.Response.ReadLength]))
-
-			a.context.Response.SetBody(a.readBuffer, a.context.Response.ReadLength)
-
+		if a.readBody() {
 			// 重置狀態值
 			a.context.State = ghttp.READ_FIRST_LINE
 
@@ -178,6 +215,33 @@ func (a *HttpAsker) read() {
 	}
 }
 
+// 讀取長度為 Content-Length 的 Body 數據，Body 可能大於讀取緩衝，因此有多少數據就先讀取多少，全部讀取完畢時返回 true
+func (a *HttpAsker) readBody() bool {
+	var size int32
+
+	for a.context.Response.ReadLength > 0 {
+		size = a.context.Response.ReadLength
+
+		if size > a.currConn.ReadableLength {
+			size = a.currConn.ReadableLength
+		}
+
+		if size > int32(len(a.readBuffer)) {
+			size = int32(len(a.readBuffer))
+		}
+
+		if size == 0 {
+			return false
+		}
+
+		a.currConn.Read(&a.readBuffer, size)
+		a.context.Response.AppendBody(a.readBuffer[:size])
+		a.context.Response.ReadLength -= size
+	}
+
+	return true
+}
+
 func (a *HttpAsker) write(id int32, data *[]byte, length int32) error {
 	utils.Debug("work id: %d", id)
 
@@ -250,9 +314,9 @@ func (a *HttpAsker) SetWorkHandler() {
 }
 
 func (a *HttpAsker) NewRequest(method string, uri string, params map[string]string) *ghttp.Request {
-	a.context = a.contextPool.Get().(*ghttp.Context)
-	a.context.Request.FormRequest(method, uri, params)
-	return a.context.Request
+	req := a.requestPool.Get().(*ghttp.Request)
+	req.FormRequest(method, uri, params)
+	return req
 }
 
 // 供外部傳送 Http 請求
@@ -274,6 +338,6 @@ func (a *HttpAsker) Send(req *ghttp.Request, callback func(*ghttp.Context)) erro
 	// 釋放 req *ghttp.Request
 	req.Release()
 	// 將 Request 放回物件池
-	a.contextPool.Put(req)
+	a.requestPool.Put(req)
 	return nil
 }
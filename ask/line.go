@@ -0,0 +1,98 @@
+package ask
+
+import (
+	"net"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/define"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// LineAsker
+// 以分隔字元切分的文字協定，每一行(不含分隔字元)作為一個 base.Work 交由 workHandler 處理
+// 自我介紹、心跳包、工作以 Send 寫出的數據，以及 Write、WriteLine 寫出的數據，將自動加上分隔字元
+// ====================================================================================================
+
+type LineAsker struct {
+	*CodecAsker
+	// 行格式
+	format *base.LineFormat
+	// 自我介紹數據(未加上分隔字元)
+	introduction []byte
+	// 心跳包數據(未加上分隔字元)
+	heartbeat []byte
+}
+
+func NewLineAsker(site int32, laddr net.Addr, nWork int32, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (IAsker, error) {
+	a := &LineAsker{
+		format: base.NewLineFormat(),
+	}
+
+	if introduction != nil {
+		a.introduction = make([]byte, len(*introduction))
+		copy(a.introduction, *introduction)
+	}
+
+	if heartbeat != nil {
+		a.heartbeat = make([]byte, len(*heartbeat))
+		copy(a.heartbeat, *heartbeat)
+	}
+
+	asker, err := NewCodecAsker(site, laddr, nWork, func() base.Codec {
+		return base.NewLineCodec(a.format)
+	}, onEvents, introduction, heartbeat)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new LineAsker.")
+	}
+
+	a.CodecAsker = asker.(*CodecAsker)
+	return a, nil
+}
+
+// 設置行的格式(須於 Connect 之前呼叫)，並以新的格式重新封裝自我介紹與心跳包數據
+func (a *LineAsker) SetLineFormat(format *base.LineFormat) error {
+	if format == nil {
+		return errors.New("Line format is nil.")
+	}
+
+	// 一行數據須可完整放入連線的讀取緩存以及 readBuffer
+	limit := a.conns.BufferLength - define.MTU
+
+	if size := int32(len(a.readBuffer)); size < limit {
+		limit = size
+	}
+
+	if err := format.Check(limit); err != nil {
+		return errors.Wrapf(err, "Failed to set line format for %s.", a.addr)
+	}
+
+	var introduction, heartbeat []byte
+	var err error
+
+	if a.introduction != nil {
+		if introduction, err = format.Encode(a.introduction); err != nil {
+			return errors.Wrapf(err, "Failed to encode introduction.")
+		}
+	}
+
+	if a.heartbeat != nil {
+		if heartbeat, err = format.Encode(a.heartbeat); err != nil {
+			return errors.Wrapf(err, "Failed to encode heartbeat.")
+		}
+	}
+
+	*a.format = *format
+	a.introductionData = introduction
+	a.heartbeatData = heartbeat
+	a.heartbeatLength = int32(len(heartbeat))
+	return nil
+}
+
+// 寫出一行文字
+func (a *LineAsker) WriteLine(line string) error {
+	data := []byte(line)
+	return a.Write(&data, int32(len(data)))
+}
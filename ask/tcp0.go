@@ -5,17 +5,25 @@ import (
 	"time"
 
 	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/base/gproto"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
 
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 )
 
 type Tcp0Asker struct {
 	*Asker
 	tcp0s    []*base.Tcp0
 	currTcp0 *base.Tcp0
+	// 長度前綴的格式
+	framing *base.Tcp0Framing
+	// protobuf 訊息(未設置時為 nil)
+	messages *gproto.Messages
 }
 
-func NewTcp0Asker(site int32, laddr *net.TCPAddr, nConnect int32, nWork int32, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (IAsker, error) {
+func NewTcp0Asker(site int32, laddr net.Addr, nConnect int32, nWork int32, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (IAsker, error) {
 	var err error
 	a := &Tcp0Asker{
 		tcp0s: make([]*base.Tcp0, nConnect),
@@ -44,6 +52,10 @@ func NewTcp0Asker(site int32, laddr *net.TCPAddr, nConnect int32, nWork int32, o
 		a.tcp0s[i] = base.NewTcp0()
 	}
 
+	if err = a.SetFraming(base.NewTcp0Framing()); err != nil {
+		return nil, errors.Wrapf(err, "Failed to new Tcp0Asker.")
+	}
+
 	//////////////////////////////////////////////////
 	// Tcp0Asker 自定義函式
 	//////////////////////////////////////////////////
@@ -56,6 +68,39 @@ func (a *Tcp0Asker) Connect() error {
 	return a.Asker.Connect(-1)
 }
 
+// 設置長度前綴的格式(須於 Connect 之前呼叫)，MaxFrameSize 為 0 時，以讀取緩存可容納的長度作為上限
+// 工作的 SendTransData 以及 WriteFrame 將以相同格式加上長度前綴(自我介紹與心跳包數據須自行以相同格式加上長度前綴)
+func (a *Tcp0Asker) SetFraming(framing *base.Tcp0Framing) error {
+	if framing == nil {
+		return errors.New("Tcp0 framing is nil.")
+	}
+
+	// 一包數據須可完整放入連線的讀取緩存，數據部分須可放入 readBuffer
+	limit := a.conns.BufferLength - define.MTU
+
+	if size := int32(len(a.readBuffer)) + framing.HeaderSize; size < limit {
+		limit = size
+	}
+
+	f, err := framing.Limit(limit)
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to set framing for %s.", a.addr)
+	}
+
+	a.framing = f
+
+	for _, tcp0 := range a.tcp0s {
+		tcp0.SetFraming(f)
+	}
+
+	for work := a.works; work != nil; work = work.Next {
+		work.Framing = f
+	}
+
+	return nil
+}
+
 func (a *Tcp0Asker) read() {
 	a.currTcp0 = a.tcp0s[a.currConn.GetId()]
 
@@ -65,11 +110,13 @@ func (a *Tcp0Asker) read() {
 			// 從 readBuffer 當中讀取數據
 			a.currConn.Read(&a.readBuffer, a.currTcp0.HeaderSize)
 
-			// 下次欲讀取長度為封包長度
-			a.currTcp0.ReadLength = base.BytesToInt32(a.readBuffer[:a.currTcp0.HeaderSize], a.order)
-
-			// 更新 currTcp0 狀態值
-			a.currTcp0.State = 1
+			// 下次欲讀取長度為封包長度，並更新 currTcp0 狀態值；長度不合法或超過上限時，重新連線
+			if err := a.currTcp0.ReadHeader(a.readBuffer[:a.currTcp0.HeaderSize]); err != nil {
+				utils.Error("Conn(%d) %+v", a.currConn.GetId(), err)
+				a.currTcp0.ResetReadLength()
+				a.currConn.State = define.Reconnect
+				return
+			}
 
 		} else {
 			// 將傳入的數據，加入工作緩存中
@@ -106,7 +153,39 @@ func (a *Tcp0Asker) Write(data *[]byte, length int32) error {
 	return nil
 }
 
+// 以設置的格式加上長度前綴後寫出
+func (a *Tcp0Asker) WriteFrame(data []byte) error {
+	frame, err := a.framing.Encode(data)
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to write frame.")
+	}
+
+	return a.Write(&frame, int32(len(frame)))
+}
+
 // 由外部定義 workHandler，定義如何處理工作
 func (a *Tcp0Asker) SetWorkHandler(handler func(*base.Work)) {
 	a.Asker.workHandler = handler
 }
+
+// 以 protobuf 訊息取代工作處理函式，收到的訊息將交由 messages 中註冊的處理函式處理
+func (a *Tcp0Asker) SetMessages(messages *gproto.Messages) {
+	a.messages = messages
+	a.Asker.workHandler = messages.Handle
+}
+
+// 送出 protobuf 訊息(須先呼叫 SetMessages，且訊息型別已註冊)
+func (a *Tcp0Asker) SendMessage(msg proto.Message) error {
+	if a.messages == nil {
+		return errors.New("Messages is not set.")
+	}
+
+	data, err := a.messages.Marshal(msg)
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to send message.")
+	}
+
+	return a.WriteFrame(data)
+}
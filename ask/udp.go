@@ -0,0 +1,194 @@
+package ask
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// UdpAsker
+// 以 UDP 連線，定期送出 Ping 維持虛擬連線，每個訊息作為一個 base.Work 交由 workHandler 處理
+// ====================================================================================================
+
+type UdpAsker struct {
+	*Asker
+	// Write 與工作的輸出數據是否以可靠訊息(保證送達與順序)送出
+	Reliable bool
+	// 自我介紹數據(每次連線後以可靠訊息送出)
+	introduction []byte
+	// 對方的 UDP 位置
+	raddr *net.UDPAddr
+	udp   *base.Udp
+}
+
+func NewUdpAsker(site int32, laddr net.Addr, nWork int32, onEvents base.OnEventsFunc, introduction *[]byte) (IAsker, error) {
+	raddr, err := base.ToUdpAddr(laddr)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new UdpAsker.")
+	}
+
+	a := &UdpAsker{
+		Reliable: false,
+		raddr:    raddr,
+		udp:      base.NewUdp(),
+	}
+
+	if introduction != nil {
+		a.introduction = make([]byte, len(*introduction))
+		copy(a.introduction, *introduction)
+	}
+
+	// 以 Ping 作為心跳包，維持虛擬連線
+	heartbeat := base.EncodeUdpDatagram(base.UdpPing, 0, nil)
+	a.Asker, err = newAsker(site, laddr, 1, nWork, nil, &heartbeat)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new UdpAsker.")
+	}
+
+	// 設置成功連線時的 callback
+	a.Asker.onEvents = onEvents
+
+	// 設置連線的模式
+	a.conns.Mode = base.KEEPALIVE
+
+	//////////////////////////////////////////////////
+	// UdpAsker 自定義函式
+	//////////////////////////////////////////////////
+	a.readFunc = a.read
+	a.writeFunc = a.write
+	a.dialFunc = a.dial
+	return a, nil
+}
+
+func (a *UdpAsker) Connect() error {
+	return a.Asker.Connect(-1)
+}
+
+// UDP 不支援 TLS
+func (a *UdpAsker) UseTLS(config *tls.Config) error {
+	return errors.New("TLS is not supported by Udp.")
+}
+
+// 建立 UDP 連線，並重置協定狀態(重新連線時序號重新計算)
+func (a *UdpAsker) dial() (net.Conn, error) {
+	conn, err := base.DialUdp(a.raddr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.udp.Reset()
+
+	if a.introduction != nil {
+		// 自我介紹數據將於連線註冊後寫出，以可靠訊息確保送達
+		if a.introductionData, err = a.udp.Encode(a.introduction, true); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "Failed to encode introduction.")
+		}
+	}
+
+	return conn, nil
+}
+
+func (a *UdpAsker) read() {
+	for {
+		// 交付已收到的訊息(工作結構不足時，保留至下次迴圈)
+		for a.currWork != nil && a.udp.HasMessage() {
+			payload, _ := a.udp.PopMessage()
+			a.currWork.Index = a.currConn.GetId()
+			a.currWork.RequestTime = time.Now().UTC()
+			a.currWork.State = base.WORK_NEED_PROCESS
+			a.currWork.Body.AddRawData(payload)
+			a.currWork.Body.ResetIndex()
+
+			// 指向下一個工作結構
+			a.currWork = a.currWork.Next
+		}
+
+		if a.currWork == nil {
+			break
+		}
+
+		datagram, ok := a.udp.ReadDatagram(a.currConn, &a.readBuffer)
+
+		if !ok {
+			break
+		}
+
+		reply, err := a.udp.Handle(datagram)
+
+		if err != nil {
+			utils.Warn("Conn(%d) drop datagram: %+v", a.currConn.GetId(), err)
+			continue
+		}
+
+		if reply != nil {
+			a.currConn.SetWriteBuffer(&reply, int32(len(reply)))
+		}
+	}
+
+	// 重送逾時未確認的可靠訊息，超過重送次數上限時重新連線
+	frames, err := a.udp.Resend(time.Now())
+
+	if err != nil {
+		utils.Error("Conn(%d) %+v", a.currConn.GetId(), err)
+		a.currConn.State = define.Reconnect
+		return
+	}
+
+	for _, frame := range frames {
+		a.currConn.SetWriteBuffer(&frame, int32(len(frame)))
+	}
+}
+
+// 內部寫出數據
+func (a *UdpAsker) write(id int32, data *[]byte, length int32) error {
+	err := a.Write(data, length)
+	a.currWork.State = base.WORK_DONE
+	return err
+}
+
+// 供外部寫出數據，根據 Reliable 決定是否為可靠訊息
+func (a *UdpAsker) Write(data *[]byte, length int32) error {
+	return a.WriteMessage((*data)[:length], a.Reliable)
+}
+
+// 送出保證送達與順序的訊息
+func (a *UdpAsker) WriteReliable(data []byte) error {
+	return a.WriteMessage(data, true)
+}
+
+// 送出不保證送達與順序的訊息
+func (a *UdpAsker) WriteUnreliable(data []byte) error {
+	return a.WriteMessage(data, false)
+}
+
+func (a *UdpAsker) WriteMessage(data []byte, reliable bool) error {
+	frame, err := a.udp.Encode(data, reliable)
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to write message.")
+	}
+
+	a.conns.SetWriteBuffer(&frame, int32(len(frame)))
+	return nil
+}
+
+// 尚未收到確認的可靠訊息數量
+func (a *UdpAsker) Unacked() int {
+	return a.udp.Unacked()
+}
+
+// 由外部定義 workHandler，定義如何處理工作
+func (a *UdpAsker) SetWorkHandler(handler func(*base.Work)) {
+	a.Asker.workHandler = handler
+}
@@ -0,0 +1,299 @@
+package ask
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// WebSocket 升級交握的時間上限
+const webSocketHandshakeTimeout time.Duration = 5 * time.Second
+
+// ====================================================================================================
+// WebSocketAsker
+// 連線後以 HTTP 升級交握建立 WebSocket，每則完整的訊息(重組分段後)作為一個 base.Work 交由 workHandler 處理
+// ====================================================================================================
+
+type WebSocketAsker struct {
+	*Asker
+	// 升級請求的路徑(可包含 GET 參數)
+	Path string
+	// 升級請求額外的標頭(如 Origin、Authorization)
+	Header ghttp.Header
+	// 客戶端要求的子協定(依優先順序)
+	Subprotocols []string
+	// 伺服器所選擇的子協定
+	Subprotocol string
+	// 連線關閉時呼叫(未收到關閉幀而中斷時不會呼叫)
+	OnClose func(code uint16, reason string)
+	// 工作的輸出數據所使用的訊息類型(base.WsText 或 base.WsBinary)
+	MessageType byte
+
+	socket *base.WebSocket
+}
+
+func NewWebSocketAsker(site int32, laddr net.Addr, nWork int32, path string, onEvents base.OnEventsFunc) (IAsker, error) {
+	var err error
+	a := &WebSocketAsker{
+		Path:         path,
+		Header:       ghttp.Header{},
+		Subprotocols: []string{},
+		MessageType:  base.WsBinary,
+		socket:       base.NewWebSocket(true, utils.GosConfig.WebSocketMaxMessageSize),
+	}
+
+	// 以空的 Ping 幀作為心跳包，維持連線
+	heartbeat := base.EncodeWebSocketFrame(base.WsPing, []byte{}, true)
+	a.Asker, err = newAsker(site, laddr, 1, nWork, nil, &heartbeat)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to new WebSocketAsker.")
+	}
+
+	// 設置成功連線時的 callback
+	a.Asker.onEvents = onEvents
+
+	// 設置連線的模式
+	a.conns.Mode = base.KEEPALIVE
+
+	//////////////////////////////////////////////////
+	// WebSocketAsker 自定義函式
+	//////////////////////////////////////////////////
+	a.readFunc = a.read
+	a.writeFunc = a.write
+	a.handshakeFunc = a.handshake
+	return a, nil
+}
+
+func (a *WebSocketAsker) Connect() error {
+	return a.Asker.Connect(-1)
+}
+
+// 送出升級請求，並驗證伺服器的回應
+func (a *WebSocketAsker) handshake(conn net.Conn) error {
+	a.socket.Reset()
+	key := base.NewWebSocketKey()
+	conn.SetDeadline(time.Now().Add(webSocketHandshakeTimeout))
+
+	// Unix domain socket 的路徑不是合法的 Host
+	host := a.addr.String()
+
+	if _, ok := a.addr.(*net.UnixAddr); ok {
+		host = "localhost"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\n", a.Path, host))
+	b.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13\r\n")
+	b.WriteString(fmt.Sprintf("Sec-WebSocket-Key: %s\r\n", key))
+
+	if len(a.Subprotocols) > 0 {
+		b.WriteString(fmt.Sprintf("Sec-WebSocket-Protocol: %s\r\n", strings.Join(a.Subprotocols, ", ")))
+	}
+
+	for k, values := range a.Header {
+		for _, value := range values {
+			b.WriteString(fmt.Sprintf("%s: %s\r\n", k, value))
+		}
+	}
+
+	b.WriteString("\r\n")
+
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return errors.Wrap(err, "Failed to write upgrade request.")
+	}
+
+	// 逐 byte 讀取回應標頭，避免讀取到交握後伺服器送出的幀
+	reader := textproto.NewReader(bufio.NewReaderSize(&byteReader{conn: conn}, 16))
+	line, err := reader.ReadLine()
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to read upgrade response.")
+	}
+
+	if !strings.HasPrefix(line, "HTTP/1.1 101") {
+		return errors.Errorf("Upgrade is rejected: %s", line)
+	}
+
+	header, err := reader.ReadMIMEHeader()
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to read upgrade response header.")
+	}
+
+	h := ghttp.Header(header)
+
+	if !h.HasToken("Upgrade", "websocket") || !h.HasToken("Connection", "upgrade") {
+		return errors.New("Invalid upgrade response.")
+	}
+
+	if h.Get("Sec-WebSocket-Accept") != base.WebSocketAccept(key) {
+		return errors.New("Invalid Sec-WebSocket-Accept.")
+	}
+
+	a.Subprotocol = h.Get("Sec-WebSocket-Protocol")
+
+	// 清除交握所使用的時間上限，讀取時間上限由 Handler 管理
+	conn.SetDeadline(time.Time{})
+	a.socket.Open()
+	return nil
+}
+
+// 每次只讀取 1 byte，使 bufio.Reader 不會預先讀取交握之後的數據
+type byteReader struct {
+	conn net.Conn
+}
+
+func (r *byteReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return r.conn.Read(p[:1])
+}
+
+func (a *WebSocketAsker) read() {
+	if a.socket.State == base.WS_CLOSED {
+		return
+	}
+
+	for a.currWork != nil {
+		opcode, payload, ok, err := a.socket.ReadMessage(a.currConn, &a.readBuffer)
+
+		if err != nil {
+			utils.Warn("Conn(%d) %+v", a.currConn.GetId(), err)
+			code := base.CloseProtocolError
+
+			if wsErr, ok := err.(*base.WebSocketError); ok {
+				code = wsErr.Code
+			}
+
+			a.closeConn(code, err.Error())
+			return
+		}
+
+		if !ok {
+			return
+		}
+
+		switch opcode {
+		case base.WsText, base.WsBinary:
+			// 收到完整的訊息，加入工作緩存中
+			a.currWork.Index = a.currConn.GetId()
+			a.currWork.RequestTime = time.Now().UTC()
+			a.currWork.State = base.WORK_NEED_PROCESS
+			a.currWork.Body.AddRawData(payload)
+			a.currWork.Body.ResetIndex()
+
+			// 指向下一個工作結構
+			a.currWork = a.currWork.Next
+
+		case base.WsPing:
+			a.writeFrame(base.WsPong, payload)
+
+		case base.WsPong:
+
+		case base.WsClose:
+			code, reason, err := base.ParseClosePayload(payload)
+
+			if err != nil {
+				utils.Warn("Conn(%d) %+v", a.currConn.GetId(), err)
+				a.closeConn(err.(*base.WebSocketError).Code, err.Error())
+				return
+			}
+
+			if !a.socket.CloseSent {
+				a.writeFrame(base.WsClose, base.EncodeClosePayload(code, ""))
+				a.socket.CloseSent = true
+			}
+
+			a.socket.State = base.WS_CLOSED
+			a.currConn.State = define.Disconnect
+
+			if a.OnClose != nil {
+				a.OnClose(code, reason)
+			}
+			return
+		}
+	}
+}
+
+// 送出關閉幀並斷線
+func (a *WebSocketAsker) closeConn(code uint16, reason string) {
+	if !a.socket.CloseSent {
+		a.writeFrame(base.WsClose, base.EncodeClosePayload(code, reason))
+		a.socket.CloseSent = true
+	}
+
+	a.socket.State = base.WS_CLOSED
+
+	// 寫出關閉幀後再斷線
+	if err := a.conns.Write(); err != nil {
+		utils.Error("Failed to write close frame, err: %+v", err)
+	}
+
+	a.conns.State = define.Disconnect
+
+	if a.OnClose != nil {
+		a.OnClose(code, reason)
+	}
+}
+
+// 內部寫出數據
+func (a *WebSocketAsker) write(id int32, data *[]byte, length int32) error {
+	err := a.WriteMessage(a.MessageType, (*data)[:length])
+	a.currWork.State = base.WORK_DONE
+	return err
+}
+
+// 供外部寫出數據，以 MessageType 送出
+func (a *WebSocketAsker) Write(data *[]byte, length int32) error {
+	return a.WriteMessage(a.MessageType, (*data)[:length])
+}
+
+// 送出文字訊息
+func (a *WebSocketAsker) WriteText(text string) error {
+	return a.WriteMessage(base.WsText, []byte(text))
+}
+
+// 以 messageType(base.WsText 或 base.WsBinary)送出訊息
+func (a *WebSocketAsker) WriteMessage(messageType byte, data []byte) error {
+	if !a.socket.IsOpen() {
+		return errors.New("WebSocket is not open.")
+	}
+
+	if messageType != base.WsText && messageType != base.WsBinary {
+		return errors.Errorf("Invalid message type %d.", messageType)
+	}
+
+	a.writeFrame(messageType, data)
+	return nil
+}
+
+// 以 code 與 reason 關閉連線
+func (a *WebSocketAsker) Close(code uint16, reason string) error {
+	if !a.socket.IsOpen() {
+		return errors.New("WebSocket is not open.")
+	}
+
+	a.closeConn(code, reason)
+	return nil
+}
+
+func (a *WebSocketAsker) writeFrame(opcode byte, payload []byte) {
+	frame := base.EncodeWebSocketFrame(opcode, payload, true)
+	a.conns.SetWriteBuffer(&frame, int32(len(frame)))
+}
+
+// 由外部定義 workHandler，定義如何處理工作
+func (a *WebSocketAsker) SetWorkHandler(handler func(*base.Work)) {
+	a.Asker.workHandler = handler
+}
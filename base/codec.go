@@ -0,0 +1,71 @@
+package base
+
+import (
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Codec
+// 自定義協定的訊息切分與封裝方式，透過 RegisterCodec 註冊後，即可如內建協定般以 gos.Listen 與 gos.Bind 使用
+// 每個連線各自持有一個 Codec 實例，因此可於其中保存讀取狀態
+// ====================================================================================================
+
+type Codec interface {
+	// 從連線的讀取緩存中取出一個完整的訊息(返回的數據於下次讀取前有效)，數據不足時返回 false，數據不合法時返回錯誤(將斷線)
+	// 以 c.CheckReadable 檢查數據是否足夠，再以 c.Read 將數據讀取至 buffer
+	Decode(c *Conn, buffer *[]byte) ([]byte, bool, error)
+	// 將訊息封裝為寫出的數據
+	Encode(message []byte) ([]byte, error)
+	// 重置讀取狀態(連線釋放或重新連線時呼叫)
+	Reset()
+}
+
+// 生成 Codec 實例
+type CodecFactory func() Codec
+
+type codecEntry struct {
+	name    string
+	factory CodecFactory
+}
+
+var codecs = map[define.SocketType]codecEntry{}
+
+// 註冊自定義協定(須於 Listen 與 Bind 之前呼叫，如於 init 中)，socketType 須大於等於 define.Custom
+// 未設置連線數與工作數的協定，將以 Tcp0 的設置作為預設值
+func RegisterCodec(socketType define.SocketType, name string, factory CodecFactory) error {
+	if socketType < define.Custom {
+		return errors.Errorf("SocketType %d is reserved, should be greater than or equal to %d.", socketType, define.Custom)
+	}
+
+	if factory == nil {
+		return errors.Errorf("Codec factory of %s is nil.", name)
+	}
+
+	if entry, ok := codecs[socketType]; ok {
+		return errors.Errorf("SocketType %d is already registered by %s.", socketType, entry.name)
+	}
+
+	codecs[socketType] = codecEntry{name: name, factory: factory}
+	define.SetCustomName(socketType, name)
+
+	for _, numbers := range []map[define.SocketType]int32{
+		utils.GosConfig.AnswerConnectNumbers,
+		utils.GosConfig.AnswerWorkNumbers,
+		utils.GosConfig.AskerWorkNumbers,
+	} {
+		if _, ok := numbers[socketType]; !ok {
+			numbers[socketType] = numbers[define.Tcp0]
+		}
+	}
+
+	return nil
+}
+
+// 取得已註冊的自定義協定
+func GetCodec(socketType define.SocketType) (CodecFactory, bool) {
+	entry, ok := codecs[socketType]
+	return entry.factory, ok
+}
@@ -2,6 +2,7 @@ package base
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
 	"net"
@@ -43,6 +44,8 @@ type Conn struct {
 	Next *Conn
 	// Handler 中斷用 chan
 	stopCh chan bool
+	// Handler 的協程結束時關閉(未開始讀取時為 nil)
+	readDone chan struct{}
 
 	// ==================================================
 	// 讀寫結構
@@ -122,8 +125,9 @@ func NewConn(id int32, size int32) *Conn {
 	c.readBuffer = make([]byte, c.BufferLength)
 	c.writeBuffer = make([]byte, c.BufferLength)
 
+	// 除了通道中的 size 個封包外，另外保留讀取中與處理中的封包各一個，避免讀取中的封包覆寫尚未處理的封包
 	var i int32
-	for i = 0; i < size; i++ {
+	for i = 0; i < size+2; i++ {
 		c.readPackets = append(c.readPackets, NewPacket())
 	}
 
@@ -143,6 +147,35 @@ func (c *Conn) Add(conn *Conn) {
 	curr.Next = conn
 }
 
+// 於新的協程中執行 Handler，開始讀取連線數據
+func (c *Conn) Start() {
+	done := make(chan struct{})
+	c.readDone = done
+
+	go func() {
+		defer close(done)
+		c.Handler()
+	}()
+}
+
+// 等待 Handler 的協程結束，期間持續清空讀取封包通道，避免其阻塞於寫入通道
+// 須先關閉 NetConn，使阻塞中的讀取返回
+func (c *Conn) waitHandler() {
+	if c.readDone == nil {
+		return
+	}
+
+	for {
+		select {
+		case packet := <-c.ReadCh:
+			packet.Release()
+		case <-c.readDone:
+			c.readDone = nil
+			return
+		}
+	}
+}
+
 func (c *Conn) Handler() {
 	utils.Debug("Start, c.readErr: %+v", c.readErr)
 	// 確保 stopCh 為空
@@ -150,6 +183,19 @@ func (c *Conn) Handler() {
 	case <-c.stopCh:
 	default:
 	}
+
+	// TLS 連線於開始讀取前完成交握，使工作處理函式可取得對方的憑證
+	if tlsConn, ok := c.NetConn.(*tls.Conn); ok {
+		if c.readErr = tlsConn.Handshake(); c.readErr != nil {
+			utils.Error("TLS handshake error: %+v", c.readErr)
+			c.readPackets[c.readIdx].Error = c.readErr
+			c.readPackets[c.readIdx].Length = 0
+			c.ReadCh <- c.readPackets[c.readIdx]
+			c.readIdx += 1
+			return
+		}
+	}
+
 	for c.readErr == nil {
 		select {
 		case <-c.stopCh:
@@ -178,7 +224,7 @@ func (c *Conn) Handler() {
 			c.ReadCh <- c.readPackets[c.readIdx]
 			c.readIdx += 1
 
-			if c.readIdx >= c.nPacket {
+			if c.readIdx >= int32(len(c.readPackets)) {
 				c.readIdx = 0
 			}
 		}
@@ -186,6 +232,26 @@ func (c *Conn) Handler() {
 	utils.Info("Stop, c.readErr: %+v", c.readErr)
 }
 
+// 取得 TLS 連線狀態(包含對方的憑證)，非 TLS 連線或尚未完成交握時返回 nil
+func (c *Conn) TLSState() *tls.ConnectionState {
+	if tlsConn, ok := c.NetConn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+
+		if state.HandshakeComplete {
+			return &state
+		}
+	}
+	return nil
+}
+
+// 取得讀取封包通道，readBuffer 剩餘空間不足一個 MTU 時返回 nil(select 將不會選取)，使數據暫時保留在 socket 中
+func (c *Conn) ReadChannel() <-chan *Packet {
+	if c.ReadableLength+define.MTU >= c.BufferLength {
+		return nil
+	}
+	return c.ReadCh
+}
+
 // 讀取封包數據，並寫入 readBuffer
 func (c *Conn) SetReadBuffer(packet *Packet) {
 	// 更新可讀數據長度
@@ -239,16 +305,21 @@ func (c *Conn) CheckReadable(checker func(buffer *[]byte, i int32, o int32, leng
 }
 
 // 將寫出數據加入緩存
-// TODO: 檢查 c.writeInput 是否反超 c.writeOutput，若反超，表示緩衝大小不足
 func (c *Conn) SetWriteBuffer(data *[]byte, length int32) {
+	// 緩衝大小不足時擴充，避免 c.writeInput 反超 c.writeOutput
+	if c.WritableLength+length >= int32(len(c.writeBuffer)) {
+		c.growWriteBuffer(c.WritableLength + length)
+	}
+
 	c.WritableLength += length
+	size := int32(len(c.writeBuffer))
 
-	if c.writeInput+length < c.BufferLength {
+	if c.writeInput+length < size {
 		copy(c.writeBuffer[c.writeInput:c.writeInput+length], (*data)[:length])
 		c.writeInput += length
 
 	} else {
-		c.writeIdx = c.BufferLength - c.writeInput
+		c.writeIdx = size - c.writeInput
 		copy(c.writeBuffer[c.writeInput:], (*data)[:c.writeIdx])
 
 		c.writeInput = length - c.writeIdx
@@ -256,6 +327,28 @@ func (c *Conn) SetWriteBuffer(data *[]byte, length int32) {
 	}
 }
 
+// 擴充寫出緩衝，使其足以容納 length 長度的數據，並將尚未寫出的數據移到緩衝最前面
+func (c *Conn) growWriteBuffer(length int32) {
+	size := 2 * int32(len(c.writeBuffer))
+
+	if size <= length {
+		size = length + 1
+	}
+
+	buffer := make([]byte, size)
+
+	if c.writeOutput <= c.writeInput {
+		copy(buffer, c.writeBuffer[c.writeOutput:c.writeInput])
+	} else {
+		c.writeIdx = int32(copy(buffer, c.writeBuffer[c.writeOutput:]))
+		copy(buffer[c.writeIdx:], c.writeBuffer[:c.writeInput])
+	}
+
+	c.writeBuffer = buffer
+	c.writeOutput = 0
+	c.writeInput = c.WritableLength
+}
+
 func (c *Conn) Write() error {
 	for c.NetConn != nil && c.writeInput != c.writeOutput {
 
@@ -282,7 +375,7 @@ func (c *Conn) Write() error {
 		c.writeOutput += int32(c.nWrite)
 		c.WritableLength -= int32(c.nWrite)
 
-		if c.writeOutput == c.BufferLength {
+		if c.writeOutput == int32(len(c.writeBuffer)) {
 			c.writeOutput = 0
 		}
 	}
@@ -302,6 +395,9 @@ func (c *Conn) Reconnect() {
 		c.NetConn = nil
 	}
 
+	// 確保 Handler 的協程已結束，才重置讀取相關的變數
+	c.waitHandler()
+
 	c.nRead = 0
 	c.readErr = nil
 	c.writeErr = nil
@@ -318,6 +414,9 @@ func (c *Conn) Release() {
 	// 關閉當前連線
 	c.NetConn.Close()
 
+	// 確保 Handler 的協程已結束，才重置讀取相關的變數
+	c.waitHandler()
+
 	// 清空連線物件
 	c.NetConn = nil
 
@@ -349,6 +448,11 @@ func (c *Conn) Release() {
 	c.WritableLength = 0
 	c.writeIdx = 0
 
+	// 寫出緩衝曾因大量數據而擴充，則恢復為原始大小
+	if int32(len(c.writeBuffer)) > c.BufferLength {
+		c.writeBuffer = make([]byte, c.BufferLength)
+	}
+
 	// 確保 stopCh 為空
 	notEmpty := true
 	var packet *Packet
@@ -0,0 +1,215 @@
+package base
+
+import (
+	"sync"
+
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+// ====================================================================================================
+// Dispatcher
+// 根據數據開頭的 kind(byte) 與 service(uint16)，將 Work 分派給對應的處理函式，作用相當於 ans.Router
+// 使用方式: anser.SetWorkHandler(dispatcher.Dispatch)
+// ====================================================================================================
+
+// 指令標頭的長度(kind: 1 byte, service: 2 bytes)
+const CommandHeaderSize int32 = 3
+
+type CommandFunc func(*Command)
+type CommandChain []CommandFunc
+
+// 一次指令處理的上下文，嵌入 Work 以便處理函式直接讀取 Body 或回應
+// 處理函式鏈返回後，Command 將被回收並重複使用，因此不可在返回後保留 Command(如交由其他協程處理)，
+// 需要時應先複製所需的數據
+type Command struct {
+	*Work
+	Kind    byte
+	Service uint16
+	// 指令是否已註冊(未註冊或數據不足以讀取標頭時為 false)
+	Found bool
+	// 處理函式之間共享的數據(如驗證後取得的使用者)
+	Keys     map[string]any
+	handlers CommandChain
+	index    int
+}
+
+// 執行處理函式鏈中，尚未執行的處理函式
+// 於中介函式中呼叫時，會先執行後續的處理函式，結束後再返回中介函式繼續執行
+func (c *Command) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// 停止執行後續的處理函式(不影響當前處理函式的執行)
+func (c *Command) Abort() {
+	c.index = define.AbortIndex
+}
+
+// 是否已停止執行後續的處理函式
+func (c *Command) IsAborted() bool {
+	return c.index >= define.AbortIndex
+}
+
+// 設置處理函式之間共享的數據
+func (c *Command) Set(key string, value any) {
+	c.Keys[key] = value
+}
+
+// 取得處理函式之間共享的數據
+func (c *Command) Get(key string) (value any, exists bool) {
+	value, exists = c.Keys[key]
+	return value, exists
+}
+
+func (c *Command) reset() {
+	c.Work = nil
+	c.Kind = 0
+	c.Service = 0
+	c.Found = false
+	c.handlers = nil
+	c.index = -1
+
+	for key := range c.Keys {
+		delete(c.Keys, key)
+	}
+}
+
+type Dispatcher struct {
+	// 全域中介函式，在所有指令(包含未註冊的指令)的處理函式之前執行
+	middlewares CommandChain
+	// key: kind << 16 | service, value: 指令專用的中介函式與處理函式
+	commands map[uint32]CommandChain
+	// key 同 commands，value: 全域中介函式與 commands 組合而成的處理函式鏈(註冊時組合，分派時不需再複製)
+	chains map[uint32]CommandChain
+	// 全域中介函式與 NoCommand 組合而成的處理函式鏈
+	noCommandChain CommandChain
+	// 未註冊的指令(或數據不足以讀取標頭)的處理函式，預設輸出警告並結束工作
+	NoCommand CommandFunc
+	// 回收處理完成的 Command，以便重複使用
+	commandPool sync.Pool
+}
+
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{
+		middlewares: CommandChain{},
+		commands:    map[uint32]CommandChain{},
+		chains:      map[uint32]CommandChain{},
+		commandPool: sync.Pool{New: func() any { return &Command{Keys: map[string]any{}, index: -1} }},
+	}
+	d.NoCommand = d.noCommand
+	d.noCommandChain = d.combine(CommandChain{d.callNoCommand})
+	return d
+}
+
+// 註冊全域中介函式，將在所有指令(包含未註冊的指令)的處理函式之前，依註冊順序執行
+func (d *Dispatcher) Use(handlers ...CommandFunc) {
+	d.middlewares = append(d.middlewares, handlers...)
+
+	// 已註冊的指令也須加上新的中介函式
+	for key, chain := range d.commands {
+		d.chains[key] = d.combine(chain)
+	}
+
+	d.noCommandChain = d.combine(CommandChain{d.callNoCommand})
+}
+
+// 註冊指令的處理函式，handlers 中除了最後一個以外，可作為該指令專用的中介函式(重複註冊時覆蓋)
+func (d *Dispatcher) Handle(kind byte, service uint16, handlers ...CommandFunc) {
+	if len(handlers) == 0 {
+		utils.Error("No handler for command(%d, %d).", kind, service)
+		return
+	}
+
+	chain := make(CommandChain, len(handlers))
+	copy(chain, handlers)
+	d.register(commandKey(kind, service), chain)
+}
+
+// 保存指令的處理函式，並與全域中介函式組合成完整的處理函式鏈
+func (d *Dispatcher) register(key uint32, chain CommandChain) {
+	d.commands[key] = chain
+	d.chains[key] = d.combine(chain)
+}
+
+// 在 chain 之前加上全域中介函式
+func (d *Dispatcher) combine(chain CommandChain) CommandChain {
+	combined := make(CommandChain, 0, len(d.middlewares)+len(chain))
+	combined = append(combined, d.middlewares...)
+	return append(combined, chain...)
+}
+
+// 建立同一個 kind 的指令群組，handlers 將作為群組內所有指令的中介函式
+func (d *Dispatcher) Group(kind byte, handlers ...CommandFunc) *CommandGroup {
+	g := &CommandGroup{
+		dispatcher: d,
+		kind:       kind,
+		handlers:   append(CommandChain{}, handlers...),
+	}
+	return g
+}
+
+// 作為 Anser 或 Asker 的工作處理函式，讀取指令標頭後，依序執行對應的處理函式
+// 和一般的工作處理函式相同，處理函式須呼叫 Send 或 Finish 等設置工作狀態，
+// 若保持 WORK_NEED_PROCESS 則下次將重新從標頭開始分派
+func (d *Dispatcher) Dispatch(w *Work) {
+	c := d.commandPool.Get().(*Command)
+	c.Work = w
+	w.Body.ResetIndex()
+
+	if w.Body.GetLength() >= CommandHeaderSize {
+		c.Kind = w.Body.PopByte()
+		c.Service = w.Body.PopUInt16()
+		c.handlers, c.Found = d.chains[commandKey(c.Kind, c.Service)]
+	}
+
+	if !c.Found {
+		c.handlers = d.noCommandChain
+	}
+
+	c.Next()
+	c.reset()
+	d.commandPool.Put(c)
+}
+
+// NoCommand 可於建立 Dispatcher 後替換，因此於執行時才取得
+func (d *Dispatcher) callNoCommand(c *Command) {
+	d.NoCommand(c)
+}
+
+func (d *Dispatcher) noCommand(c *Command) {
+	if c.Body.GetLength() < CommandHeaderSize {
+		utils.Warn("Work from %d is too short(%d) to read the command header.", c.Index, c.Body.GetLength())
+	} else {
+		utils.Warn("Unknown command(%d, %d) from %d.", c.Kind, c.Service, c.Index)
+	}
+
+	c.Finish()
+}
+
+func commandKey(kind byte, service uint16) uint32 {
+	return uint32(kind)<<16 | uint32(service)
+}
+
+// 同一個 kind 的指令群組
+type CommandGroup struct {
+	dispatcher *Dispatcher
+	kind       byte
+	handlers   CommandChain
+}
+
+// 註冊群組內指令的處理函式，將在群組的中介函式之後執行
+func (g *CommandGroup) Handle(service uint16, handlers ...CommandFunc) {
+	if len(handlers) == 0 {
+		utils.Error("No handler for command(%d, %d).", g.kind, service)
+		return
+	}
+
+	chain := make(CommandChain, 0, len(g.handlers)+len(handlers))
+	chain = append(chain, g.handlers...)
+	chain = append(chain, handlers...)
+	g.dispatcher.register(commandKey(g.kind, service), chain)
+}
@@ -0,0 +1,578 @@
+package ghttp
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/textproto"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Binding
+// 根據結構標籤，將請求數據綁定到結構中，並根據 binding 標籤進行驗證
+// - json:   JSON Body
+// - form:   GET 參數與表單參數
+// - uri:    路徑參數
+// - header: 標頭
+// - binding: 驗證規則，以逗號分隔(ex: binding:"required,min=1,max=10")
+//   - required: 不可為零值
+//   - omitempty: 欄位為零值時略過其他規則
+//   - min=n, max=n: 數值的範圍，或字串(字元數)、切片、映射的長度範圍
+//   - len=n: 字串(字元數)、切片、映射的長度
+//   - oneof=a b c: 必須是以空白分隔的其中一個值
+//   - regex=pattern: 字串須符合正規表示式(須為最後一個規則，pattern 中可包含逗號)
+//   零值同樣需通過其他規則，欄位為 nil 指標或設置了 omitempty 且為零值時才會略過
+//   無法解析的規則(未知規則、參數錯誤、型別不支援)將使 Bind 返回 ErrInvalidRule
+// ====================================================================================================
+
+// 單一欄位的綁定或驗證錯誤
+type FieldError struct {
+	// 欄位名稱(根據標籤名稱，巢狀結構以 . 串接)
+	Field string `json:"field"`
+	// 未通過的規則(轉換型別失敗時為 type)
+	Rule string `json:"rule"`
+	// 規則參數
+	Param string `json:"param,omitempty"`
+	// 錯誤說明
+	Message string `json:"message"`
+}
+
+// 綁定或驗證失敗的欄位列表
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	messages := make([]string, len(ve))
+	for i, fe := range ve {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// binding 標籤中的規則無法解析或不適用於欄位型別
+var ErrInvalidRule = errors.New("Invalid binding rule.")
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	// 已編譯的正規表示式(key: pattern)
+	regexps sync.Map
+)
+
+// 根據 Content-Type 選擇解碼方式，將 Body(GET 等沒有 Body 的請求則為 GET 參數)綁定到 obj 並進行驗證
+// 失敗時自動回應 400 與錯誤內容，並停止執行後續的處理函式
+func (c *Context) Bind(obj any) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	var err error
+
+	switch {
+	case mediaType == "application/json":
+		if c.Request.BodyLength > 0 {
+			if err = json.NewDecoder(c.Request.BodyReader()).Decode(obj); err != nil {
+				return c.bindFailed(errors.Wrap(err, "Failed to decode json body."))
+			}
+		}
+
+	case mediaType == MIMEPostForm || mediaType == MIMEMultipartForm:
+		if err = c.Request.ParseForm(); err != nil {
+			return c.bindFailed(err)
+		}
+
+		// 表單參數優先於 GET 參數
+		err = bindValues(obj, "form", func(key string) ([]string, bool) {
+			if values, ok := c.Request.form[key]; ok {
+				return values, true
+			}
+			values, ok := c.Request.QueryValues[key]
+			return values, ok
+		})
+
+	case c.Request.BodyLength == 0:
+		err = bindValues(obj, "form", valuesSource(c.Request.QueryValues))
+
+	default:
+		return c.bindFailed(errors.Errorf("Unsupported Content-Type: %s", mediaType))
+	}
+
+	return c.validateBinding(obj, err)
+}
+
+// 將 GET 參數綁定到 obj(form 標籤)並進行驗證，失敗時自動回應 400
+func (c *Context) BindQuery(obj any) error {
+	err := bindValues(obj, "form", valuesSource(c.Request.QueryValues))
+	return c.validateBinding(obj, err)
+}
+
+// 將路徑參數綁定到 obj(uri 標籤)並進行驗證，失敗時自動回應 400
+func (c *Context) BindUri(obj any) error {
+	err := bindValues(obj, "uri", func(key string) ([]string, bool) {
+		value, ok := c.Request.PathParams[key]
+		return []string{value}, ok
+	})
+	return c.validateBinding(obj, err)
+}
+
+// 將標頭綁定到 obj(header 標籤)並進行驗證，失敗時自動回應 400
+func (c *Context) BindHeader(obj any) error {
+	err := bindValues(obj, "header", func(key string) ([]string, bool) {
+		values, ok := c.Request.Header[textproto.CanonicalMIMEHeaderKey(key)]
+		return values, ok
+	})
+	return c.validateBinding(obj, err)
+}
+
+// 綁定成功時進行驗證，綁定或驗證失敗時回應 400
+func (c *Context) validateBinding(obj any, err error) error {
+	if err == nil {
+		err = Validate(obj)
+	}
+	if err != nil {
+		return c.bindFailed(err)
+	}
+	return nil
+}
+
+// 回應 400 與結構化的錯誤內容，並停止執行後續的處理函式；binding 標籤有誤時回應 500
+func (c *Context) bindFailed(err error) error {
+	if errors.Is(err, ErrInvalidRule) {
+		utils.Error("method: %s, query: %s, bind err: %+v", c.Method, c.Query, err)
+		c.AbortWithStatusJSON(StatusInternalServerError, H{
+			"error": "Internal Server Error",
+		})
+		return err
+	}
+
+	utils.Warn("method: %s, query: %s, bind err: %+v", c.Method, c.Query, err)
+	body := H{
+		"error": "Bad Request",
+	}
+
+	if ve, ok := err.(ValidationErrors); ok {
+		body["fields"] = ve
+	} else {
+		body["message"] = err.Error()
+	}
+
+	c.AbortWithStatusJSON(StatusBadRequest, body)
+	return err
+}
+
+func valuesSource(values map[string][]string) func(string) ([]string, bool) {
+	return func(key string) ([]string, bool) {
+		vs, ok := values[key]
+		return vs, ok
+	}
+}
+
+// 根據 tag 標籤的名稱，從 source 取得數據並轉換為欄位的型別
+func bindValues(obj any, tag string, source func(string) ([]string, bool)) error {
+	rv := reflect.ValueOf(obj)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("Binding target must be a non-nil pointer to struct, got %T.", obj)
+	}
+
+	errs := ValidationErrors{}
+	bindStruct(rv.Elem(), tag, source, &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func bindStruct(rv reflect.Value, tag string, source func(string) ([]string, bool), errs *ValidationErrors) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Tag.Get(tag)
+
+		if name == "-" {
+			continue
+		}
+
+		fv := rv.Field(i)
+
+		if name == "" {
+			// 未設置標籤的結構(如嵌入的結構)，綁定其欄位
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				bindStruct(fv, tag, source, errs)
+				continue
+			}
+			name = field.Name
+		}
+
+		values, ok := source(name)
+
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if err := setField(fv, values); err != nil {
+			*errs = append(*errs, FieldError{
+				Field:   name,
+				Rule:    "type",
+				Param:   fv.Type().String(),
+				Message: fmt.Sprintf("%s: %s", name, err.Error()),
+			})
+		}
+	}
+}
+
+// 將 values 轉換為欄位的型別(切片將使用所有的值，其他型別使用第一個值)
+func setField(fv reflect.Value, values []string) error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setField(fv.Elem(), values)
+
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+
+			for i, value := range values {
+				if err := setValue(slice.Index(i), value); err != nil {
+					return err
+				}
+			}
+
+			fv.Set(slice)
+			return nil
+		}
+	}
+
+	return setValue(fv, values[0])
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return tu.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return errors.Errorf("invalid duration %q", s)
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("invalid float %q", s)
+		}
+		v.SetFloat(f)
+
+	case reflect.Slice:
+		// []byte
+		v.SetBytes([]byte(s))
+
+	default:
+		return errors.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// ====================================================================================================
+// Validate
+// ====================================================================================================
+
+// 根據 binding 標籤驗證 obj(結構或結構指標)，未通過時返回 ValidationErrors，規則有誤時返回 ErrInvalidRule
+func Validate(obj any) error {
+	rv := reflect.ValueOf(obj)
+
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	errs := ValidationErrors{}
+
+	if err := validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, errs *ValidationErrors) error {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+		name := prefix + fieldName(field)
+		rules := field.Tag.Get("binding")
+
+		if rules != "" && rules != "-" {
+			if err := validateField(fv, name, rules, errs); err != nil {
+				return err
+			}
+		}
+
+		// 巢狀結構(嵌入的結構不增加前綴)
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			if field.Anonymous {
+				name = prefix
+			} else {
+				name += "."
+			}
+
+			if err := validateStruct(fv, name, errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// 欄位對外的名稱，依序參考 json, form, uri, header 標籤，皆未設置時使用欄位名稱
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri", "header"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// 將規則字串拆分為 [規則, 參數] 的列表，regex 之後的內容皆視為正規表示式
+func parseRules(rules string) [][2]string {
+	result := [][2]string{}
+	var rule, name, param string
+
+	for rules != "" {
+		if strings.HasPrefix(rules, "regex=") {
+			result = append(result, [2]string{"regex", strings.TrimPrefix(rules, "regex=")})
+			break
+		}
+
+		rule, rules, _ = strings.Cut(rules, ",")
+		name, param, _ = strings.Cut(strings.TrimSpace(rule), "=")
+
+		if name != "" {
+			result = append(result, [2]string{name, param})
+		}
+	}
+
+	return result
+}
+
+func validateField(fv reflect.Value, name string, rules string, errs *ValidationErrors) error {
+	var rule, param string
+	parsed := parseRules(rules)
+	required, omitEmpty := false, false
+
+	for _, r := range parsed {
+		switch r[0] {
+		case "required":
+			required = true
+		case "omitempty":
+			omitEmpty = true
+		}
+	}
+
+	if fv.IsZero() {
+		if required {
+			*errs = append(*errs, FieldError{Field: name, Rule: "required", Message: fmt.Sprintf("%s is required", name)})
+			return nil
+		}
+
+		// nil 指標沒有可供檢查的值
+		if omitEmpty || fv.Kind() == reflect.Ptr {
+			return nil
+		}
+	}
+
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+
+	for _, r := range parsed {
+		rule, param = r[0], r[1]
+
+		if rule == "required" || rule == "omitempty" {
+			continue
+		}
+
+		msg, ok, err := checkRule(fv, rule, param)
+
+		if err != nil {
+			return errors.Wrapf(err, "Field %s", name)
+		}
+
+		if !ok {
+			*errs = append(*errs, FieldError{Field: name, Rule: rule, Param: param, Message: fmt.Sprintf("%s %s", name, msg)})
+		}
+	}
+
+	return nil
+}
+
+// 檢查 fv 是否符合規則，不符合時返回錯誤說明；規則本身有誤時返回 ErrInvalidRule
+func checkRule(fv reflect.Value, rule string, param string) (string, bool, error) {
+	switch rule {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+
+		if err != nil {
+			return "", false, errors.Wrapf(ErrInvalidRule, "Invalid param of rule %s: %q", rule, param)
+		}
+
+		value, isLength, ok := measure(fv)
+
+		if !ok {
+			return "", false, errors.Wrapf(ErrInvalidRule, "Rule %s is not supported by type %s", rule, fv.Type())
+		}
+
+		subject := "must be"
+
+		if isLength {
+			subject = "length must be"
+		}
+
+		switch {
+		case rule == "min" && value < limit:
+			return fmt.Sprintf("%s at least %s", subject, param), false, nil
+		case rule == "max" && value > limit:
+			return fmt.Sprintf("%s at most %s", subject, param), false, nil
+		case rule == "len" && value != limit:
+			return fmt.Sprintf("%s %s", subject, param), false, nil
+		}
+
+	case "oneof":
+		value := fmt.Sprint(fv.Interface())
+
+		for _, option := range strings.Fields(param) {
+			if value == option {
+				return "", true, nil
+			}
+		}
+
+		return fmt.Sprintf("must be one of [%s]", param), false, nil
+
+	case "regex":
+		if fv.Kind() != reflect.String {
+			return "", false, errors.Wrapf(ErrInvalidRule, "Rule regex is not supported by type %s", fv.Type())
+		}
+
+		re, err := compileRegexp(param)
+
+		if err != nil {
+			return "", false, errors.Wrapf(ErrInvalidRule, "Invalid regex %s: %v", param, err)
+		}
+
+		if !re.MatchString(fv.String()) {
+			return fmt.Sprintf("must match %s", param), false, nil
+		}
+
+	default:
+		return "", false, errors.Wrapf(ErrInvalidRule, "Unknown binding rule: %s", rule)
+	}
+
+	return "", true, nil
+}
+
+// 取得用於比較的數值，數值型別返回其值，字串(字元數)、切片、映射返回其長度(isLength 為 true)
+func measure(fv reflect.Value) (value float64, isLength bool, ok bool) {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(fv.String())), true, true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(fv.Len()), true, true
+	}
+	return 0, false, false
+}
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+
+	if err != nil {
+		return nil, err
+	}
+
+	regexps.Store(pattern, re)
+	return re, nil
+}
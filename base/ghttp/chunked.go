@@ -0,0 +1,290 @@
+package ghttp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+)
+
+// 分塊傳輸(Transfer-Encoding: chunked)格式
+//
+// chunk-size(16 進位)[; chunk-ext]\r\n
+// chunk-data\r\n
+// ...
+// 0\r\n
+// [trailer-field: value\r\n]
+// \r\n
+
+// 單一分塊的最大長度
+const MaxChunkSize int64 = 1<<31 - 1
+
+// 不得出現於 Trailer 的欄位(訊息框架、路由、驗證與逐跳欄位)，讀取時直接忽略
+var forbiddenTrailers = map[string]bool{
+	"Authorization":       true,
+	"Cache-Control":       true,
+	"Connection":          true,
+	"Content-Encoding":    true,
+	"Content-Length":      true,
+	"Content-Range":       true,
+	"Content-Type":        true,
+	"Cookie":              true,
+	"Expect":              true,
+	"Host":                true,
+	"Keep-Alive":          true,
+	"Max-Forwards":        true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Set-Cookie":          true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Www-Authenticate":    true,
+}
+
+var (
+	// 分塊數據的總長度超過上限
+	ErrChunkedBodyTooLarge = errors.New("Chunked body too large.")
+	// 分塊數據無法寫入 Body(ex: 暫存檔案寫入失敗)
+	ErrChunkedBodyStorage = errors.New("Failed to store chunked body.")
+)
+
+// 分塊數據的來源(ex: base.Conn)
+type ChunkSource interface {
+	CheckReadable(checker func(buffer *[]byte, i int32, o int32, length int32) bool) bool
+	Read(data *[]byte, length int32)
+}
+
+// 分塊數據的接收端(Request 或 Response)
+type chunkSink interface {
+	// 已接收的 Body 長度
+	bodyLength() int32
+	writeChunk(data []byte) error
+	addTrailer(key string, value string)
+}
+
+func (r *Request) bodyLength() int32 {
+	return r.BodyLength
+}
+
+func (r *Request) writeChunk(data []byte) error {
+	return r.WriteBody(data)
+}
+
+// Trailer 與 Header 分開保存，避免於 Body 之後覆寫已通過檢查的 Header
+func (r *Request) addTrailer(key string, value string) {
+	r.Trailer.Add(key, value)
+}
+
+func (r *Response) bodyLength() int32 {
+	return r.BodyLength
+}
+
+func (r *Response) writeChunk(data []byte) error {
+	r.AppendBody(data)
+	return nil
+}
+
+func (r *Response) addTrailer(key string, value string) {
+	r.Trailer.Add(key, value)
+}
+
+// 讀取分塊傳輸的 Request Body，所有分塊(包含 Trailer)皆讀取完畢時返回 true
+// Body 總長度超過 maxBodySize 時返回 ErrChunkedBodyTooLarge
+func (c *Context) ReadChunkedRequest(src ChunkSource, buffer *[]byte, maxBodySize int32) (bool, error) {
+	return c.readChunked(src, buffer, c.Request, maxBodySize)
+}
+
+// 讀取分塊傳輸的 Response Body，所有分塊(包含 Trailer)皆讀取完畢時返回 true
+func (c *Context) ReadChunkedResponse(src ChunkSource, buffer *[]byte) (bool, error) {
+	return c.readChunked(src, buffer, c.Response, math.MaxInt32)
+}
+
+// 根據 c.State 讀取分塊傳輸的數據，數據不足時返回 false，待下次收到數據後從中斷處繼續讀取
+// READ_CHUNK_SIZE -> READ_CHUNK_DATA -> READ_CHUNK_DATA_END -> READ_CHUNK_SIZE -> ... -> READ_CHUNK_TRAILER
+func (c *Context) readChunked(src ChunkSource, buffer *[]byte, sink chunkSink, maxBodySize int32) (bool, error) {
+	var line, key, value string
+	var size, readable int32
+	var ok bool
+	var err error
+
+	for {
+		switch c.State {
+		case READ_CHUNK_SIZE:
+			if line, ok = readLine(src, buffer); !ok {
+				return false, nil
+			}
+
+			size, err = ParseChunkSize(strings.TrimRight(line, "\r\n"))
+
+			if err != nil {
+				return false, errors.Wrap(err, "Failed to parse chunk size.")
+			}
+
+			if size == 0 {
+				c.State = READ_CHUNK_TRAILER
+			} else if size > maxBodySize-sink.bodyLength() {
+				// 以減法比較，避免兩個 int32 相加溢位
+				return false, ErrChunkedBodyTooLarge
+			} else {
+				c.chunkRemaining = size
+				c.State = READ_CHUNK_DATA
+			}
+
+		case READ_CHUNK_DATA:
+			// 分塊可能大於讀取緩衝，因此有多少數據就先讀取多少
+			src.CheckReadable(func(_ *[]byte, _ int32, _ int32, length int32) bool {
+				readable = length
+				return true
+			})
+			size = c.chunkRemaining
+
+			if size > readable {
+				size = readable
+			}
+
+			if size > int32(len(*buffer)) {
+				size = int32(len(*buffer))
+			}
+
+			if size == 0 {
+				return false, nil
+			}
+
+			// 隨著數據寫入持續檢查 Body 長度
+			if size > maxBodySize-sink.bodyLength() {
+				return false, ErrChunkedBodyTooLarge
+			}
+
+			src.Read(buffer, size)
+
+			if err = sink.writeChunk((*buffer)[:size]); err != nil {
+				utils.Error("Failed to write chunk: %+v", err)
+				return false, ErrChunkedBodyStorage
+			}
+
+			c.chunkRemaining -= size
+
+			if c.chunkRemaining == 0 {
+				c.State = READ_CHUNK_DATA_END
+			}
+
+		case READ_CHUNK_DATA_END:
+			if line, ok = readLine(src, buffer); !ok {
+				return false, nil
+			}
+
+			// 分塊數據之後必須緊接著一個 CRLF
+			if line != "\r\n" {
+				return false, errors.Errorf("Expected CRLF after chunk data, got %q.", line)
+			}
+
+			c.State = READ_CHUNK_SIZE
+
+		case READ_CHUNK_TRAILER:
+			if line, ok = readLine(src, buffer); !ok {
+				return false, nil
+			}
+
+			line = strings.TrimRight(line, "\r\n")
+
+			// 空行代表分塊傳輸結束
+			if line == "" {
+				return true, nil
+			}
+
+			key, value, ok = strings.Cut(line, COLON)
+
+			if !ok {
+				continue
+			}
+
+			key = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key))
+
+			if forbiddenTrailers[key] {
+				utils.Warn("Ignore forbidden trailer: %s", key)
+				continue
+			}
+
+			sink.addTrailer(key, strings.TrimLeft(value, " \t"))
+
+		default:
+			return false, nil
+		}
+	}
+}
+
+// 從 src 讀取一行數據(包含結尾的換行)，尚未收到完整的一行時返回 false
+func readLine(src ChunkSource, buffer *[]byte) (string, bool) {
+	var length int32
+	var ok bool
+
+	if !src.CheckReadable(func(b *[]byte, i int32, o int32, n int32) bool {
+		length, ok = findLine(b, i, o, n)
+		return ok
+	}) {
+		return "", false
+	}
+
+	src.Read(buffer, length)
+	return string((*buffer)[:length]), true
+}
+
+// 檢查標頭是否宣告了分塊傳輸
+func IsChunked(header Header) bool {
+	return header.HasToken("Transfer-Encoding", "chunked")
+}
+
+// 解析分塊長度行(ex: "1a;name=value")，返回分塊數據長度
+func ParseChunkSize(line string) (int32, error) {
+	// 忽略分塊擴展(chunk-ext)
+	line, _, _ = strings.Cut(line, ";")
+	line = strings.TrimSpace(line)
+
+	if line == "" {
+		return 0, errors.New("Empty chunk size.")
+	}
+
+	size, err := strconv.ParseInt(line, 16, 64)
+
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid chunk size: %s", line)
+	}
+
+	if size < 0 || size > MaxChunkSize {
+		return 0, errors.Errorf("Chunk size out of range: %d", size)
+	}
+
+	return int32(size), nil
+}
+
+// 生成分塊傳輸的結尾(長度為 0 的分塊 + Trailer + 空行)
+func EncodeLastChunk(trailer Header) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("0\r\n")
+
+	for k, v := range trailer {
+		buffer.WriteString(fmt.Sprintf("%s: %s\r\n", k, strings.Join(v, ", ")))
+	}
+
+	buffer.WriteString("\r\n")
+	return buffer.Bytes()
+}
+
+// 將數據編碼為一個分塊
+func EncodeChunk(data []byte) []byte {
+	head := fmt.Sprintf("%x\r\n", len(data))
+	chunk := make([]byte, 0, len(head)+len(data)+2)
+	chunk = append(chunk, head...)
+	chunk = append(chunk, data...)
+	chunk = append(chunk, '\r', '\n')
+	return chunk
+}
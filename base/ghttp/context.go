@@ -1,505 +1,1040 @@
-package ghttp
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"strconv"
-	"strings"
-
-	"github.com/j32u4ukh/gos/utils"
-	"github.com/pkg/errors"
-)
-
-// 工作完成時的 Callback 函式
-type HandlerFunc func(*Context)
-type ContextState int8
-
-// HTTP 工作流程按照下方順序執行
-const (
-	// 讀取第一行
-	READ_FIRST_LINE ContextState = iota
-	// 讀取 Header
-	READ_HEADER
-	// 讀取 Data
-	READ_BODY
-	// 等待數據寫出(Response)
-	WRITE_RESPONSE
-	// 完成數據複製到寫出緩存
-	FINISH_RESPONSE
-)
-
-func (cs ContextState) String() string {
-	switch cs {
-	case READ_FIRST_LINE:
-		return "READ_FIRST_LINE"
-	case READ_HEADER:
-		return "READ_HEADER"
-	case READ_BODY:
-		return "READ_BODY"
-	case WRITE_RESPONSE:
-		return "WRITE_RESPONSE"
-	case FINISH_RESPONSE:
-		return "FINISH_RESPONSE"
-	default:
-		return "Unknown ContextState"
-	}
-}
-
-/*
-Request 和 Response 的 Header 應該分開來，因為端點函式中既會讀取 Request 的 Header，也會寫出 Response 的 Header。
-透過不同函式來讀寫即可，記得保留 Context 送出 Request 的能力。
-*/
-type Context struct {
-	// Context 唯一碼
-	id int32
-	// 對應 連線結構 的 id
-	Cid int32
-	// 對應 工作結構 的 id
-	Wid int32
-	// 工作流程當前階段
-	State ContextState
-	*Request
-	*Response
-}
-
-func NewContext(id int32) *Context {
-	c := &Context{
-		id:       id,
-		Cid:      -1,
-		Wid:      -1,
-		State:    READ_FIRST_LINE,
-		Request:  newRequest(),
-		Response: newResponse(),
-	}
-	return c
-}
-
-func (c *Context) GetId() int32 {
-	return c.id
-}
-
-func (c *Context) Json(code int32, obj any) {
-	c.Response.Json(code, obj)
-}
-
-func (c Context) ReadJson(obj any) error {
-	if c.Request.BodyLength > 0 {
-		data := c.Request.Body[:c.Request.BodyLength]
-		err := json.Unmarshal(data, obj)
-		if err != nil {
-			return errors.Wrap(err, "Failed to unmarshal body to json.")
-		}
-	}
-	return nil
-}
-
-func (c Context) ReadBytes() []byte {
-	if c.Request.BodyLength > 0 {
-		result := make([]byte, c.Request.BodyLength)
-		copy(result, c.Request.Body[:c.Request.BodyLength])
-		return result
-	}
-	return nil
-}
-
-func (c *Context) Release() {
-	c.Cid = -1
-	c.Wid = -1
-	c.State = READ_FIRST_LINE
-	c.Request.Release()
-	c.Response.Release()
-}
-
-// ====================================================================================================
-// Request
-// ====================================================================================================
-type Request struct {
-	// ex: GET
-	Method string
-	// ex: /user/get
-	Query string
-	// ex: HTTP/1.1
-	Proto  string
-	Params map[string]string
-	Values map[string]any
-
-	Header
-
-	// 讀取長度
-	ReadLength int32
-
-	// Body 數據
-	// NOTE: Body 和 BodyLength 之後將改為私有變數，要存取的話需透過函式來操作。
-	Body       []byte
-	BodyLength int32
-}
-
-func NewRequest(method string, uri string, params map[string]string) (*Request, error) {
-	c := NewContext(-1)
-	c.Request.FormRequest(method, uri, params)
-	return c.Request, nil
-}
-
-func newRequest() *Request {
-	r := &Request{
-		Proto:      "HTTP/1.1",
-		Params:     map[string]string{},
-		Values:     make(map[string]any),
-		Header:     make(Header),
-		ReadLength: 0,
-		Body:       make([]byte, 64*1024),
-		BodyLength: 0,
-	}
-	return r
-}
-
-func (r *Request) FormRequest(method string, uri string, params map[string]string) {
-	r.Method = method
-	r.Proto = "HTTP/1.1"
-	r.Params = params
-	for key, value := range params {
-		r.Values[key] = value
-	}
-	host, query, ok := strings.Cut(uri, "/")
-	if ok {
-		r.Query = fmt.Sprintf("/%s", query)
-		utils.Debug("Query: %s", r.Query)
-	}
-	r.Header["Host"] = []string{host}
-}
-
-// 檢查是否有一行數據(以換行符 '\n' 來區分)
-func (r *Request) HasLineData(buffer *[]byte, i int32, o int32, length int32) bool {
-	// fmt.Printf("(c *Context) HasLineData | i: %d, o: %d, length: %d\n", i, o, length)
-
-	if length == 0 {
-		return false
-	}
-
-	r.ReadLength = 0
-	value := -1
-
-	if o < i {
-		// fmt.Printf("(c *Context) HasLineData | buffer0: %+v\n", (*buffer)[o:i])
-		value = bytes.IndexByte((*buffer)[o:i], '\n')
-		// fmt.Printf("(c *Context) HasLineData | value(o < i): %d\n", value)
-
-	} else {
-		value = bytes.IndexByte((*buffer)[o:], '\n')
-		// fmt.Printf("(c *Context) HasLineData | buffer1: %+v\n", (*buffer)[o:])
-
-		if value != -1 {
-			r.ReadLength = int32(value) + 1
-			// fmt.Printf("(c *Context) HasLineData | value([o:]): %d\n", value)
-			return true
-		}
-
-		r.ReadLength = int32(len((*buffer)[o:]))
-		// fmt.Printf("(c *Context) HasLineData | temp ReadLength: %d\n", c.ReadLength)
-		value = bytes.IndexByte((*buffer)[:i], '\n')
-		// fmt.Printf("(c *Context) HasLineData | buffer2: %+v\n", (*buffer)[:i])
-		// fmt.Printf("(c *Context) HasLineData | value([:i]): %d\n", value)
-	}
-
-	if value != -1 {
-		r.ReadLength += int32(value) + 1
-		// fmt.Printf("(c *Context) HasLineData | value: %d\n", value)
-		return true
-	}
-
-	r.ReadLength = 0
-	return false
-}
-
-func (r *Request) HasEnoughData(buffer *[]byte, i int32, o int32, length int32) bool {
-	utils.Debug("length: %d, ReadLength: %d", length, r.ReadLength)
-	return length >= r.ReadLength
-}
-
-// 解析第一行數據
-// parseRequestLine parses "GET /foo HTTP/1.1" into its three parts.
-func (r *Request) ParseFirstReqLine(line string) bool {
-	var ok bool
-	r.Method, r.Query, ok = strings.Cut(line, " ")
-	if !ok {
-		return false
-	}
-	r.Query, r.Proto, ok = strings.Cut(r.Query, " ")
-	if !ok {
-		return false
-	}
-	r.Query = strings.TrimPrefix(r.Query, "?")
-	utils.Debug("Method: %s, Query: %s, Proto: %s", r.Method, r.Query, r.Proto)
-	return true
-}
-
-// 解析第一行數據中的請求路徑
-func (r *Request) ParseQuery() (bool, error) {
-	var ok bool
-	var params string
-	r.Query, params, ok = strings.Cut(r.Query, "?")
-	if !ok {
-		return false, nil
-	}
-	utils.Debug("Query: %s, params: %s", r.Query, params)
-	err := r.ParseParams(params)
-
-	if err != nil {
-		return true, errors.Wrapf(err, "Failed to parse params: %s", params)
-	}
-
-	utils.Debug("params: %+v", r.Params)
-	utils.Debug("values: %+v", r.Values)
-	return true, nil
-}
-
-// 解析第一行數據中的請求路徑中的 GET 參數
-func (r *Request) ParseParams(params string) error {
-	var key, value string
-	var ok bool
-	var err error
-
-	for params != "" {
-		key, params, _ = strings.Cut(params, "&")
-
-		if strings.Contains(key, ";") {
-			utils.Warn("invalid semicolon separator in query(%s)", key)
-			continue
-		}
-
-		if key == "" {
-			utils.Warn("Empty query is found.")
-			continue
-		}
-
-		key, value, ok = strings.Cut(key, "=")
-
-		if !ok {
-			continue
-		}
-
-		// 將 url 上的參數加入 params 管理
-		r.Params[key] = value
-	}
-
-	return err
-}
-
-func (r Request) GetParam(key string) (bool, string) {
-	if param, ok := r.Params[key]; ok {
-		return true, param
-	}
-	return false, ""
-}
-
-func (r Request) GetValue(key string) any {
-	if value, ok := r.Values[key]; ok {
-		return value
-	}
-	return nil
-}
-
-func (r *Request) Json(obj any) {
-	r.Header["Content-Type"] = jsonContentType
-	data, _ := json.Marshal(obj)
-	r.SetBody(data, int32(len(data)))
-	r.SetContentLength()
-}
-
-// 供 Request 設置 Body 數據
-func (r *Request) SetBody(data []byte, length int32) {
-	r.BodyLength = length
-	copy(r.Body[:length], data[:length])
-}
-
-// 協助設置標頭檔的 Content-Length
-func (r *Request) SetContentLength() {
-	r.Header["Content-Length"] = []string{strconv.Itoa(int(r.BodyLength))}
-}
-
-func (r Request) ToRequestData() []byte {
-	// Accept: */*
-
-	var buffer bytes.Buffer
-	// GET /end HTTP/1.1
-	buffer.WriteString(fmt.Sprintf("%s %s %s\r\n", r.Method, r.Query, r.Proto))
-
-	/*
-		Content-Type: application/json
-		User-Agent: Go-http-client/1.1
-		Host: 192.168.0.198:3333
-		Accept-Encoding: gzip
-		Connection: keep-alive
-		Content-Length: 35
-
-		{
-			"id":0,
-			"msg":"test"
-		}
-	*/
-
-	r.Header["User-Agent"] = []string{"Go-http-client/1.1"}
-	r.Header["Accept-Encoding"] = []string{"gzip"}
-
-	for k, v := range r.Header {
-		buffer.WriteString(fmt.Sprintf("%s: %s\r\n", k, strings.Join(v, ", ")))
-	}
-
-	buffer.WriteString("\r\n")
-
-	if _, ok := r.Header["Content-Length"]; ok {
-		buffer.WriteString("\r\n")
-		buffer.Write(r.Body[:r.BodyLength])
-	}
-	result := buffer.Bytes()
-	utils.Debug("result: %s", string(result))
-	return result
-}
-
-func (r *Request) Release() {
-	r.Method = ""
-	r.Query = ""
-	r.Proto = ""
-	r.BodyLength = 0
-	r.ReadLength = 0
-	var key string
-	for key = range r.Params {
-		delete(r.Params, key)
-	}
-	for key = range r.Values {
-		delete(r.Values, key)
-	}
-	for key = range r.Header {
-		delete(r.Header, key)
-	}
-}
-
-// ====================================================================================================
-// Response
-// ====================================================================================================
-type Response struct {
-	Code    int32
-	Message string
-	Proto   string
-
-	Header
-
-	// 讀取長度
-	ReadLength int32
-
-	// Body 數據
-	// NOTE: Body 和 BodyLength 之後將改為私有變數，要存取的話需透過函式來操作。
-	Body       []byte
-	BodyLength int32
-}
-
-func newResponse() *Response {
-	r := &Response{
-		Code:       -1,
-		Message:    "",
-		Proto:      "HTTP/1.1",
-		Header:     make(Header),
-		ReadLength: 0,
-		Body:       make([]byte, 64*1024),
-		BodyLength: 0,
-	}
-	return r
-}
-
-// 解析第一行數據
-// parseRequestLine parses "HTTP/1.1 200 OK" into its three parts.
-func (r *Response) ParseFirstResLine(line string) bool {
-	var ok bool
-	r.Proto, r.Message, ok = strings.Cut(line, " ")
-
-	if !ok {
-		return false
-	}
-
-	var codeString string
-	codeString, r.Message, ok = strings.Cut(r.Message, " ")
-
-	if !ok {
-		r.Message = codeString
-		return false
-	}
-
-	code, err := strconv.Atoi(codeString)
-
-	if err != nil {
-		return false
-	}
-
-	r.Code = int32(code)
-	utils.Debug("Proto: %s, Code: %d, Message: %s", r.Proto, r.Code, r.Message)
-	return true
-}
-
-func (r *Response) SetHeader(key string, value string) {
-	if _, ok := r.Header[key]; !ok {
-		r.Header[key] = []string{value}
-	} else {
-		r.Header[key] = append(r.Header[key], value)
-	}
-}
-
-// Status sets the HTTP response code.
-func (r *Response) Status(code int32) {
-	r.Code = code
-	r.Message = StatusText(code)
-}
-
-func (r *Response) Json(code int32, obj any) {
-	r.Status(code)
-
-	for k := range r.Header {
-		delete(r.Header, k)
-	}
-
-	r.Header["Content-Type"] = jsonContentType
-	data, _ := json.Marshal(obj)
-	r.SetBody(data, int32(len(data)))
-	r.SetContentLength()
-}
-
-// 供 Request 設置 Body 數據
-func (r *Response) SetBody(data []byte, length int32) {
-	r.BodyLength = length
-	copy(r.Body[:length], data[:length])
-}
-
-func (r *Response) SetContentLength() {
-	r.Header["Content-Length"] = []string{strconv.Itoa(int(r.BodyLength))}
-}
-
-// 生成 Response message
-func (r Response) ToResponseData() []byte {
-	var buffer bytes.Buffer
-	// HTTP/1.1 200 OK\r\n
-	buffer.WriteString(fmt.Sprintf("%s %d %s\r\n", r.Proto, r.Code, r.Message))
-
-	// Header
-	for k, v := range r.Header {
-		buffer.WriteString(fmt.Sprintf("%s: %s\r\n", k, strings.Join(v, ", ")))
-	}
-
-	// Body
-	if _, ok := r.Header["Content-Length"]; ok {
-		buffer.WriteString("\r\n")
-		buffer.Write(r.Body[:r.BodyLength])
-	}
-
-	return buffer.Bytes()
-}
-
-func (r *Response) Release() {
-	r.Code = -1
-	r.Message = ""
-	r.ReadLength = 0
-	r.BodyLength = 0
-
-	for k := range r.Header {
-		delete(r.Header, k)
-	}
-}
+package ghttp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// 工作完成時的 Callback 函式，也作為 HttpAnser 的端點處理函式與中介函式
+type HandlerFunc func(*Context)
+type ContextState int8
+
+// StreamContent 每次迴圈讀取並寫出的數據長度
+const streamContentSize int = 32 * 1024
+
+// Request 與 Response 的 Body 預設容量，因大型 Body 而擴充後，於 Release 時縮回此容量
+const defaultBodySize int = 64 * 1024
+
+// HTTP 工作流程按照下方順序執行
+const (
+	// 讀取第一行
+	READ_FIRST_LINE ContextState = iota
+	// 讀取 Header
+	READ_HEADER
+	// 讀取 Data
+	READ_BODY
+	// 讀取分塊長度(Transfer-Encoding: chunked)
+	READ_CHUNK_SIZE
+	// 讀取分塊數據
+	READ_CHUNK_DATA
+	// 讀取分塊數據結尾的 CRLF
+	READ_CHUNK_DATA_END
+	// 讀取分塊傳輸結尾的 Trailer
+	READ_CHUNK_TRAILER
+	// 等待數據寫出(Response)
+	WRITE_RESPONSE
+	// 以串流的方式持續寫出 Body
+	WRITE_STREAM
+	// 完成數據複製到寫出緩存
+	FINISH_RESPONSE
+)
+
+func (cs ContextState) String() string {
+	switch cs {
+	case READ_FIRST_LINE:
+		return "READ_FIRST_LINE"
+	case READ_HEADER:
+		return "READ_HEADER"
+	case READ_BODY:
+		return "READ_BODY"
+	case READ_CHUNK_SIZE:
+		return "READ_CHUNK_SIZE"
+	case READ_CHUNK_DATA:
+		return "READ_CHUNK_DATA"
+	case READ_CHUNK_DATA_END:
+		return "READ_CHUNK_DATA_END"
+	case READ_CHUNK_TRAILER:
+		return "READ_CHUNK_TRAILER"
+	case WRITE_RESPONSE:
+		return "WRITE_RESPONSE"
+	case WRITE_STREAM:
+		return "WRITE_STREAM"
+	case FINISH_RESPONSE:
+		return "FINISH_RESPONSE"
+	default:
+		return "Unknown ContextState"
+	}
+}
+
+// 是否處於讀取分塊傳輸數據的階段
+func (cs ContextState) IsReadingChunk() bool {
+	return cs >= READ_CHUNK_SIZE && cs <= READ_CHUNK_TRAILER
+}
+
+/*
+Request 和 Response 的 Header 應該分開來，因為端點函式中既會讀取 Request 的 Header，也會寫出 Response 的 Header。
+透過不同函式來讀寫即可，記得保留 Context 送出 Request 的能力。
+*/
+type Context struct {
+	// Context 唯一碼
+	id int32
+	// 對應 連線結構 的 id
+	Cid int32
+	// 對應 工作結構 的 id
+	Wid int32
+	// 工作流程當前階段
+	State ContextState
+	// 完成本次請求後，是否維持連線以等待下一個請求
+	KeepAlive bool
+	// 當前連線已接收的請求數
+	nRequest int32
+	// 當前分塊尚未讀取的數據長度
+	chunkRemaining int32
+	// 當前請求已讀取的第一行與 Header 的長度
+	headerLength int32
+	// 當前請求的處理函式鏈(中介函式 + 端點處理函式)
+	handlers []HandlerFunc
+	// 當前執行中的處理函式索引值
+	index int
+	// 處理函式之間共享的數據，只屬於當前請求
+	Keys map[string]any
+	// 串流寫出 Body 的函式，返回 false 表示傳輸結束
+	stream func(w io.Writer) bool
+	// 串流結束或中斷時關閉(由 StreamContent 設置)
+	streamCloser io.Closer
+	// Server-Sent Events 串流(由 SSE 設置)
+	sse *SSEStream
+	// HTML 模板(由 HttpAnser 設置)
+	template *template.Template
+	// TLS 連線狀態(由 HttpAnser 設置，非 TLS 連線時為 nil)
+	TLS *tls.ConnectionState
+	*Request
+	*Response
+}
+
+func NewContext(id int32) *Context {
+	c := &Context{
+		id:        id,
+		Cid:       -1,
+		Wid:       -1,
+		State:     READ_FIRST_LINE,
+		KeepAlive: false,
+		nRequest:  0,
+		handlers:  []HandlerFunc{},
+		index:     -1,
+		Keys:      map[string]any{},
+		Request:   newRequest(),
+		Response:  newResponse(),
+	}
+	return c
+}
+
+// 取得客戶端的憑證，非 TLS 連線或客戶端未提供憑證時返回 nil
+func (c *Context) PeerCertificate() *x509.Certificate {
+	if c.TLS != nil && len(c.TLS.PeerCertificates) > 0 {
+		return c.TLS.PeerCertificates[0]
+	}
+	return nil
+}
+
+func (c *Context) GetId() int32 {
+	return c.id
+}
+
+func (c *Context) Json(code int32, obj any) {
+	c.Response.Json(code, obj)
+}
+
+func (c Context) ReadJson(obj any) error {
+	if c.Request.BodyLength > 0 {
+		err := json.NewDecoder(c.Request.BodyReader()).Decode(obj)
+		if err != nil {
+			return errors.Wrap(err, "Failed to unmarshal body to json.")
+		}
+	}
+	return nil
+}
+
+func (c Context) ReadBytes() []byte {
+	if c.Request.BodyLength > 0 {
+		result := make([]byte, c.Request.BodyLength)
+		io.ReadFull(c.Request.BodyReader(), result)
+		return result
+	}
+	return nil
+}
+
+// 以串流的方式寫出 Body(分塊傳輸)，適用於無法一次放入記憶體的大型回應
+// 處理函式返回並寫出 Header 後，每次迴圈於寫出緩存有空間時呼叫 step，由 step 將下一段數據寫入 w，返回 false 表示傳輸結束
+// NOTE: 僅適用於在處理函式中同步回應的情況
+func (c *Context) Stream(step func(w io.Writer) bool) {
+	c.Response.startChunked()
+	c.Response.streaming = true
+	c.stream = step
+}
+
+// 以串流的方式寫出 content 中長度為 length 的數據，保留 Content-Length(不使用分塊傳輸)，適用於檔案等已知長度的大型回應
+// content 實作 io.Closer 時，將於傳輸結束或連線中斷時關閉；HEAD 請求只寫出 Header
+// NOTE: 僅適用於在處理函式中同步回應的情況
+func (c *Context) StreamContent(code int32, contentType string, content io.Reader, length int64) {
+	c.Response.Status(code)
+	c.Response.chunked = false
+	c.Response.BodyLength = 0
+	delete(c.Response.Header, "Transfer-Encoding")
+	c.Response.Header["Content-Type"] = []string{contentType}
+	c.Response.Header["Content-Length"] = []string{strconv.FormatInt(length, 10)}
+
+	if closer, ok := content.(io.Closer); ok {
+		c.streamCloser = closer
+	}
+
+	if c.Method == MethodHead || length <= 0 {
+		c.closeStream()
+		return
+	}
+
+	c.Response.streaming = true
+	buffer := make([]byte, streamContentSize)
+
+	c.stream = func(w io.Writer) bool {
+		size := int64(len(buffer))
+
+		if size > length {
+			size = length
+		}
+
+		n, err := io.ReadFull(content, buffer[:size])
+		w.Write(buffer[:n])
+		length -= int64(n)
+
+		if err != nil {
+			// Header 已寫出，無法再回應錯誤，結束後關閉連線，使客戶端得知 Body 不完整
+			utils.Error("Failed to read stream content: %+v", err)
+			c.KeepAlive = false
+			length = 0
+		}
+
+		if length == 0 {
+			c.closeStream()
+			return false
+		}
+
+		return true
+	}
+}
+
+// 關閉 StreamContent 的數據來源
+func (c *Context) closeStream() {
+	if c.streamCloser != nil {
+		c.streamCloser.Close()
+		c.streamCloser = nil
+	}
+}
+
+// 是否以串流的方式寫出 Body
+func (c *Context) IsStreaming() bool {
+	return c.stream != nil
+}
+
+// 是否以 Server-Sent Events 回應
+func (c *Context) IsEventStream() bool {
+	return c.sse != nil
+}
+
+// 執行一次串流寫出，返回 false 表示傳輸結束
+func (c *Context) StreamStep(w io.Writer) bool {
+	if c.stream == nil {
+		return false
+	}
+	return c.stream(w)
+}
+
+// 完整讀取 Header 後呼叫，累計請求數，並根據請求內容與請求數上限 maxRequest 決定是否維持連線
+func (c *Context) UpdateKeepAlive(maxRequest int32) {
+	c.nRequest += 1
+	c.KeepAlive = c.Request.IsKeepAlive() && c.nRequest < maxRequest
+}
+
+// 累計當前請求已讀取的第一行與 Header 的長度
+func (c *Context) AddHeaderLength(length int32) {
+	c.headerLength += length
+}
+
+// 取得當前請求已讀取的第一行與 Header 的長度
+func (c *Context) GetHeaderLength() int32 {
+	return c.headerLength
+}
+
+// 取得當前連線已接收的請求數
+func (c *Context) GetRequestNumber() int32 {
+	return c.nRequest
+}
+
+// 設置當前請求的處理函式鏈，依序串接 chains 中的處理函式
+func (c *Context) SetHandlers(chains ...[]HandlerFunc) {
+	c.handlers = c.handlers[:0]
+	for _, chain := range chains {
+		c.handlers = append(c.handlers, chain...)
+	}
+	c.index = -1
+}
+
+// 執行處理函式鏈中，尚未執行的處理函式
+// 於中介函式中呼叫時，會先執行後續的處理函式，結束後再返回中介函式繼續執行
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// 停止執行後續的處理函式(不影響當前處理函式的執行)
+func (c *Context) Abort() {
+	c.index = define.AbortIndex
+}
+
+// 是否已停止執行後續的處理函式
+func (c *Context) IsAborted() bool {
+	return c.index >= define.AbortIndex
+}
+
+// 設置狀態碼，並停止執行後續的處理函式
+func (c *Context) AbortWithStatus(code int32) {
+	c.Status(code)
+	c.Response.BodyLength = 0
+	c.Response.SetContentLength()
+	c.Abort()
+}
+
+// 以 JSON 格式回應，並停止執行後續的處理函式
+func (c *Context) AbortWithStatusJSON(code int32, obj any) {
+	c.Abort()
+	c.Json(code, obj)
+}
+
+// 設置處理函式之間共享的數據
+func (c *Context) Set(key string, value any) {
+	c.Keys[key] = value
+}
+
+// 取得處理函式之間共享的數據
+func (c *Context) Get(key string) (value any, exists bool) {
+	value, exists = c.Keys[key]
+	return value, exists
+}
+
+// 完成一次請求後重置 Request 與 Response，以便在同一連線上處理下一個請求
+func (c *Context) Reset() {
+	c.State = READ_FIRST_LINE
+	c.chunkRemaining = 0
+	c.headerLength = 0
+	c.handlers = c.handlers[:0]
+	c.index = -1
+	c.stream = nil
+	c.closeStream()
+
+	// 串流尚未結束即重置，表示連線已中斷
+	if c.sse != nil {
+		c.sse.disconnect()
+		c.sse = nil
+	}
+
+	for k := range c.Keys {
+		delete(c.Keys, k)
+	}
+	c.Request.Release()
+	c.Response.Release()
+}
+
+func (c *Context) Release() {
+	c.TLS = nil
+	c.Cid = -1
+	c.Wid = -1
+	c.KeepAlive = false
+	c.nRequest = 0
+	c.Reset()
+}
+
+// ====================================================================================================
+// Request
+// ====================================================================================================
+type Request struct {
+	// ex: GET
+	Method string
+	// ex: /user/get
+	Query string
+	// ex: HTTP/1.1
+	Proto  string
+	Params map[string]string
+	Values map[string]any
+	// 路由匹配所取得的路徑參數(原始字串)，只屬於當前請求
+	PathParams map[string]string
+	// URL 上的 GET 參數(已解碼，包含重複的 key)
+	QueryValues url.Values
+
+	Header
+
+	// 讀取長度
+	ReadLength int32
+
+	// Body 數據
+	// NOTE: Body 和 BodyLength 之後將改為私有變數，要存取的話需透過函式來操作。
+	Body       []byte
+	BodyLength int32
+
+	// 分塊傳輸結尾的 Trailer(與 Header 分開保存)
+	Trailer Header
+
+	// 超過記憶體上限的 Body 所暫存的檔案(此時 Body 中的數據不完整，需透過 BodyReader 讀取)
+	bodyFile *os.File
+
+	// 表單數據(於首次存取時才解析 Body)
+	form          url.Values
+	multipartForm *multipart.Form
+	formParsed    bool
+	formErr       error
+}
+
+func NewRequest(method string, uri string, params map[string]string) (*Request, error) {
+	c := NewContext(-1)
+	c.Request.FormRequest(method, uri, params)
+	return c.Request, nil
+}
+
+func newRequest() *Request {
+	r := &Request{
+		Proto:       "HTTP/1.1",
+		Params:      map[string]string{},
+		Values:      make(map[string]any),
+		PathParams:  map[string]string{},
+		QueryValues: url.Values{},
+		form:        url.Values{},
+		Header:      make(Header),
+		Trailer:     make(Header),
+		ReadLength:  0,
+		Body:        make([]byte, defaultBodySize),
+		BodyLength:  0,
+	}
+	return r
+}
+
+func (r *Request) FormRequest(method string, uri string, params map[string]string) {
+	r.Method = method
+	r.Proto = "HTTP/1.1"
+	r.Params = params
+	for key, value := range params {
+		r.Values[key] = value
+	}
+	host, query, ok := strings.Cut(uri, "/")
+	if ok {
+		r.Query = fmt.Sprintf("/%s", query)
+		utils.Debug("Query: %s", r.Query)
+	}
+	r.Header["Host"] = []string{host}
+}
+
+// 檢查是否有一行數據(以換行符 '\n' 來區分)
+func findLine(buffer *[]byte, i int32, o int32, length int32) (int32, bool) {
+	// fmt.Printf("(c *Context) HasLineData | i: %d, o: %d, length: %d\n", i, o, length)
+
+	if length == 0 {
+		return 0, false
+	}
+
+	var readLength int32 = 0
+	value := -1
+
+	if o < i {
+		// fmt.Printf("(c *Context) HasLineData | buffer0: %+v\n", (*buffer)[o:i])
+		value = bytes.IndexByte((*buffer)[o:i], '\n')
+		// fmt.Printf("(c *Context) HasLineData | value(o < i): %d\n", value)
+
+	} else {
+		value = bytes.IndexByte((*buffer)[o:], '\n')
+		// fmt.Printf("(c *Context) HasLineData | buffer1: %+v\n", (*buffer)[o:])
+
+		if value != -1 {
+			readLength = int32(value) + 1
+			// fmt.Printf("(c *Context) HasLineData | value([o:]): %d\n", value)
+			return readLength, true
+		}
+
+		readLength = int32(len((*buffer)[o:]))
+		// fmt.Printf("(c *Context) HasLineData | temp ReadLength: %d\n", c.ReadLength)
+		value = bytes.IndexByte((*buffer)[:i], '\n')
+		// fmt.Printf("(c *Context) HasLineData | buffer2: %+v\n", (*buffer)[:i])
+		// fmt.Printf("(c *Context) HasLineData | value([:i]): %d\n", value)
+	}
+
+	if value != -1 {
+		readLength += int32(value) + 1
+		// fmt.Printf("(c *Context) HasLineData | value: %d\n", value)
+		return readLength, true
+	}
+
+	return 0, false
+}
+
+// 檢查是否有一行數據，並將該行長度記錄於 ReadLength
+func (r *Request) HasLineData(buffer *[]byte, i int32, o int32, length int32) bool {
+	var ok bool
+	r.ReadLength, ok = findLine(buffer, i, o, length)
+	return ok
+}
+
+func (r *Request) HasEnoughData(buffer *[]byte, i int32, o int32, length int32) bool {
+	utils.Debug("length: %d, ReadLength: %d", length, r.ReadLength)
+	return length >= r.ReadLength
+}
+
+// 解析第一行數據
+// parseRequestLine parses "GET /foo HTTP/1.1" into its three parts.
+func (r *Request) ParseFirstReqLine(line string) bool {
+	var ok bool
+	r.Method, r.Query, ok = strings.Cut(line, " ")
+	if !ok {
+		return false
+	}
+	r.Query, r.Proto, ok = strings.Cut(r.Query, " ")
+	if !ok {
+		return false
+	}
+	r.Query = strings.TrimPrefix(r.Query, "?")
+	utils.Debug("Method: %s, Query: %s, Proto: %s", r.Method, r.Query, r.Proto)
+	return true
+}
+
+// 解析第一行數據中的請求路徑
+func (r *Request) ParseQuery() (bool, error) {
+	var ok bool
+	var params string
+	r.Query, params, ok = strings.Cut(r.Query, "?")
+	if !ok {
+		return false, nil
+	}
+	utils.Debug("Query: %s, params: %s", r.Query, params)
+	err := r.ParseParams(params)
+
+	if err != nil {
+		return true, errors.Wrapf(err, "Failed to parse params: %s", params)
+	}
+
+	utils.Debug("params: %+v", r.Params)
+	utils.Debug("values: %+v", r.Values)
+	return true, nil
+}
+
+// 解析第一行數據中的請求路徑中的 GET 參數
+// 參數將進行 URL 解碼，重複的 key 全數保存於 QueryValues，Params 則保存第一個值
+func (r *Request) ParseParams(params string) error {
+	err := parseValues(params, r.QueryValues)
+
+	// 將 url 上的參數加入 params 管理
+	for key, values := range r.QueryValues {
+		if _, ok := r.Params[key]; !ok {
+			r.Params[key] = values[0]
+		}
+	}
+
+	return err
+}
+
+// 根據 HTTP 版本與 Connection 標頭，判斷客戶端是否希望維持連線
+// HTTP/1.1 預設維持連線，除非明確要求 close；HTTP/1.0 則須明確要求 keep-alive
+func (r *Request) IsKeepAlive() bool {
+	if r.Header.HasToken("Connection", "close") {
+		return false
+	}
+	if r.Proto == "HTTP/1.0" {
+		return r.Header.HasToken("Connection", "keep-alive")
+	}
+	return strings.HasPrefix(r.Proto, "HTTP/1.")
+}
+
+// 設置路徑參數，raw 為原始字串，value 為根據路由型別(<id int> 等)轉換後的數值
+// 路徑參數會覆蓋同名的 GET 參數
+func (r *Request) SetPathParam(key string, raw string, value any) {
+	r.PathParams[key] = raw
+	r.Params[key] = raw
+	r.Values[key] = value
+}
+
+// 以 int64 取得參數，路由宣告為 <key int> 時直接返回匹配時轉換的數值，否則嘗試解析原始字串
+func (r Request) ParamInt(key string) (int64, error) {
+	switch value := r.Values[key].(type) {
+	case int64:
+		return value, nil
+	case uint64:
+		if value > math.MaxInt64 {
+			return 0, errors.Errorf("Param %s(%d) overflows int64.", key, value)
+		}
+		return int64(value), nil
+	}
+	if param, ok := r.Params[key]; ok {
+		i, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "Param %s(%s) is not an int.", key, param)
+		}
+		return i, nil
+	}
+	return 0, errors.Errorf("Param %s is not found.", key)
+}
+
+// 以 uint64 取得參數，路由宣告為 <key uint> 時直接返回匹配時轉換的數值，否則嘗試解析原始字串
+func (r Request) ParamUint(key string) (uint64, error) {
+	switch value := r.Values[key].(type) {
+	case uint64:
+		return value, nil
+	case int64:
+		if value < 0 {
+			return 0, errors.Errorf("Param %s(%d) is negative.", key, value)
+		}
+		return uint64(value), nil
+	}
+	if param, ok := r.Params[key]; ok {
+		u, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "Param %s(%s) is not an uint.", key, param)
+		}
+		return u, nil
+	}
+	return 0, errors.Errorf("Param %s is not found.", key)
+}
+
+// 以 float64 取得參數，路由宣告為 <key float> 時直接返回匹配時轉換的數值，否則嘗試解析原始字串
+func (r Request) ParamFloat(key string) (float64, error) {
+	switch value := r.Values[key].(type) {
+	case float64:
+		return value, nil
+	case int64:
+		return float64(value), nil
+	case uint64:
+		return float64(value), nil
+	}
+	if param, ok := r.Params[key]; ok {
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "Param %s(%s) is not a float.", key, param)
+		}
+		return f, nil
+	}
+	return 0, errors.Errorf("Param %s is not found.", key)
+}
+
+func (r Request) GetParam(key string) (bool, string) {
+	if param, ok := r.Params[key]; ok {
+		return true, param
+	}
+	return false, ""
+}
+
+func (r Request) GetValue(key string) any {
+	if value, ok := r.Values[key]; ok {
+		return value
+	}
+	return nil
+}
+
+func (r *Request) Json(obj any) {
+	r.Header["Content-Type"] = jsonContentType
+	data, _ := json.Marshal(obj)
+	r.SetBody(data, int32(len(data)))
+	r.SetContentLength()
+}
+
+// 供 Request 設置 Body 數據
+func (r *Request) SetBody(data []byte, length int32) {
+	r.Body = appendBody(r.Body, 0, data[:length])
+	r.BodyLength = length
+}
+
+// 將數據附加到 Body 之後(用於分段讀取的 Body，如分塊傳輸)
+func (r *Request) AppendBody(data []byte) {
+	r.Body = appendBody(r.Body, r.BodyLength, data)
+	r.BodyLength += int32(len(data))
+}
+
+// 寫入分段讀取的 Body 數據，總長度超過 utils.GosConfig.HttpBodyMemoryLimit 後，改為暫存於檔案中
+func (r *Request) WriteBody(data []byte) error {
+	if r.bodyFile == nil && r.BodyLength+int32(len(data)) > utils.GosConfig.HttpBodyMemoryLimit {
+		file, err := os.CreateTemp("", "gos-body-*")
+
+		if err != nil {
+			return errors.Wrap(err, "Failed to create temp file for body.")
+		}
+
+		r.bodyFile = file
+
+		if _, err = r.bodyFile.Write(r.Body[:r.BodyLength]); err != nil {
+			return errors.Wrapf(err, "Failed to write body to %s.", r.bodyFile.Name())
+		}
+	}
+
+	if r.bodyFile != nil {
+		if _, err := r.bodyFile.Write(data); err != nil {
+			return errors.Wrapf(err, "Failed to write body to %s.", r.bodyFile.Name())
+		}
+		r.BodyLength += int32(len(data))
+		return nil
+	}
+
+	r.AppendBody(data)
+	return nil
+}
+
+// 取得讀取完整 Body 的 io.Reader，可用於逐段處理暫存於檔案中的大型 Body
+func (r *Request) BodyReader() io.Reader {
+	if r.bodyFile != nil {
+		return io.NewSectionReader(r.bodyFile, 0, int64(r.BodyLength))
+	}
+	return bytes.NewReader(r.Body[:r.BodyLength])
+}
+
+// 關閉並刪除暫存 Body 的檔案
+func (r *Request) closeBodyFile() {
+	if r.bodyFile != nil {
+		r.bodyFile.Close()
+		os.Remove(r.bodyFile.Name())
+		r.bodyFile = nil
+	}
+}
+
+// 協助設置標頭檔的 Content-Length
+func (r *Request) SetContentLength() {
+	r.Header["Content-Length"] = []string{strconv.Itoa(int(r.BodyLength))}
+}
+
+func (r Request) ToRequestData() []byte {
+	// Accept: */*
+
+	var buffer bytes.Buffer
+	// GET /end HTTP/1.1
+	buffer.WriteString(fmt.Sprintf("%s %s %s\r\n", r.Method, r.Query, r.Proto))
+
+	/*
+		Content-Type: application/json
+		User-Agent: Go-http-client/1.1
+		Host: 192.168.0.198:3333
+		Accept-Encoding: gzip
+		Connection: keep-alive
+		Content-Length: 35
+
+		{
+			"id":0,
+			"msg":"test"
+		}
+	*/
+
+	r.Header["User-Agent"] = []string{"Go-http-client/1.1"}
+	r.Header["Accept-Encoding"] = []string{"gzip"}
+
+	for k, v := range r.Header {
+		buffer.WriteString(fmt.Sprintf("%s: %s\r\n", k, strings.Join(v, ", ")))
+	}
+
+	buffer.WriteString("\r\n")
+
+	if _, ok := r.Header["Content-Length"]; ok {
+		buffer.Write(r.Body[:r.BodyLength])
+	}
+	result := buffer.Bytes()
+	utils.Debug("result: %s", string(result))
+	return result
+}
+
+func (r *Request) Release() {
+	r.Method = ""
+	r.Query = ""
+	r.Proto = ""
+	r.BodyLength = 0
+	r.ReadLength = 0
+	r.Body = shrinkBody(r.Body)
+	r.closeBodyFile()
+	r.releaseForm()
+	var key string
+	for key = range r.Params {
+		delete(r.Params, key)
+	}
+	for key = range r.Values {
+		delete(r.Values, key)
+	}
+	for key = range r.PathParams {
+		delete(r.PathParams, key)
+	}
+	for key = range r.QueryValues {
+		delete(r.QueryValues, key)
+	}
+	for key = range r.Header {
+		delete(r.Header, key)
+	}
+	for key = range r.Trailer {
+		delete(r.Trailer, key)
+	}
+}
+
+// ====================================================================================================
+// Response
+// ====================================================================================================
+type Response struct {
+	Code    int32
+	Message string
+	Proto   string
+
+	Header
+
+	// 讀取長度
+	ReadLength int32
+
+	// Body 數據
+	// NOTE: Body 和 BodyLength 之後將改為私有變數，要存取的話需透過函式來操作。
+	Body       []byte
+	BodyLength int32
+
+	// 分塊傳輸結尾的 Trailer
+	Trailer Header
+	// 是否以分塊傳輸的方式寫出 Body
+	chunked bool
+	// 是否以串流的方式寫出 Body(分塊傳輸的結尾將由串流結束時寫出)
+	streaming bool
+}
+
+func newResponse() *Response {
+	r := &Response{
+		Code:       -1,
+		Message:    "",
+		Proto:      "HTTP/1.1",
+		Header:     make(Header),
+		ReadLength: 0,
+		Body:       make([]byte, defaultBodySize),
+		BodyLength: 0,
+		Trailer:    make(Header),
+		chunked:    false,
+	}
+	return r
+}
+
+// 檢查是否有一行數據，並將該行長度記錄於 ReadLength
+func (r *Response) HasLineData(buffer *[]byte, i int32, o int32, length int32) bool {
+	var ok bool
+	r.ReadLength, ok = findLine(buffer, i, o, length)
+	return ok
+}
+
+func (r *Response) HasEnoughData(buffer *[]byte, i int32, o int32, length int32) bool {
+	return length >= r.ReadLength
+}
+
+// 解析第一行數據
+// parseRequestLine parses "HTTP/1.1 200 OK" into its three parts.
+func (r *Response) ParseFirstResLine(line string) bool {
+	var ok bool
+	r.Proto, r.Message, ok = strings.Cut(line, " ")
+
+	if !ok {
+		return false
+	}
+
+	var codeString string
+	codeString, r.Message, ok = strings.Cut(r.Message, " ")
+
+	if !ok {
+		r.Message = codeString
+		return false
+	}
+
+	code, err := strconv.Atoi(codeString)
+
+	if err != nil {
+		return false
+	}
+
+	r.Code = int32(code)
+	utils.Debug("Proto: %s, Code: %d, Message: %s", r.Proto, r.Code, r.Message)
+	return true
+}
+
+func (r *Response) SetHeader(key string, value string) {
+	if _, ok := r.Header[key]; !ok {
+		r.Header[key] = []string{value}
+	} else {
+		r.Header[key] = append(r.Header[key], value)
+	}
+}
+
+// Status sets the HTTP response code.
+func (r *Response) Status(code int32) {
+	r.Code = code
+	r.Message = StatusText(code)
+}
+
+// 以 JSON 格式回應，保留先前設置的其他標頭(如 CORS、快取相關標頭)
+func (r *Response) Json(code int32, obj any) {
+	data, err := json.Marshal(obj)
+
+	if err != nil {
+		utils.Error("Failed to marshal %+v: %+v", obj, err)
+		r.Data(StatusInternalServerError, MIMEPlain, []byte(StatusText(StatusInternalServerError)))
+		return
+	}
+
+	r.Data(code, MIMEJson, data)
+}
+
+// 以 contentType 回應完整的 data，保留先前設置的其他標頭
+func (r *Response) Data(code int32, contentType string, data []byte) {
+	r.Status(code)
+
+	// 改為一次性回應完整的 Body
+	r.chunked = false
+	delete(r.Header, "Transfer-Encoding")
+
+	r.Header["Content-Type"] = []string{contentType}
+	r.SetBody(data, int32(len(data)))
+	r.SetContentLength()
+}
+
+// 供 Request 設置 Body 數據
+func (r *Response) SetBody(data []byte, length int32) {
+	r.Body = appendBody(r.Body, 0, data[:length])
+	r.BodyLength = length
+}
+
+func (r *Response) SetContentLength() {
+	r.Header["Content-Length"] = []string{strconv.Itoa(int(r.BodyLength))}
+}
+
+// 將數據附加到 Body 之後(用於分段讀取的 Body，如分塊傳輸)
+func (r *Response) AppendBody(data []byte) {
+	r.Body = appendBody(r.Body, r.BodyLength, data)
+	r.BodyLength += int32(len(data))
+}
+
+// 以分塊傳輸的方式寫入一段 Body 數據，適用於事先無法得知 Body 長度的情況
+// 結尾的空分塊與 Trailer 將在生成 Response message 時自動加上
+func (r *Response) WriteChunk(data []byte) {
+	if !r.chunked {
+		r.startChunked()
+	}
+
+	// 長度為 0 的分塊代表傳輸結束，因此略過空數據
+	if len(data) > 0 {
+		r.AppendBody(EncodeChunk(data))
+	}
+}
+
+// 切換為分塊傳輸
+func (r *Response) startChunked() {
+	r.chunked = true
+	r.BodyLength = 0
+
+	if r.Code == -1 {
+		r.Status(StatusOK)
+	}
+
+	delete(r.Header, "Content-Length")
+	r.Header["Transfer-Encoding"] = []string{"chunked"}
+}
+
+// 是否以分塊傳輸的方式寫出 Body
+func (r *Response) IsChunked() bool {
+	return r.chunked
+}
+
+// 生成 Response message
+func (r Response) ToResponseData() []byte {
+	var buffer bytes.Buffer
+	// HTTP/1.1 200 OK\r\n
+	buffer.WriteString(fmt.Sprintf("%s %d %s\r\n", r.Proto, r.Code, r.Message))
+
+	// Header
+	for k, v := range r.Header {
+		// Set-Cookie 的值可能包含逗號(ex: Expires)，因此每個值各自一行
+		if k == "Set-Cookie" {
+			for _, cookie := range v {
+				buffer.WriteString(fmt.Sprintf("%s: %s\r\n", k, cookie))
+			}
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf("%s: %s\r\n", k, strings.Join(v, ", ")))
+	}
+
+	// Header 與 Body 之間的空行
+	buffer.WriteString("\r\n")
+
+	// Body
+	if r.chunked {
+		buffer.Write(r.Body[:r.BodyLength])
+
+		// 結尾的空分塊(串流的結尾將於串流結束時寫出)
+		if !r.streaming {
+			buffer.Write(EncodeLastChunk(r.Trailer))
+		}
+
+	} else if _, ok := r.Header["Content-Length"]; ok {
+		buffer.Write(r.Body[:r.BodyLength])
+	}
+
+	return buffer.Bytes()
+}
+
+func (r *Response) Release() {
+	r.Code = -1
+	r.Message = ""
+	r.ReadLength = 0
+	r.BodyLength = 0
+	r.Body = shrinkBody(r.Body)
+	r.chunked = false
+	r.streaming = false
+
+	for k := range r.Header {
+		delete(r.Header, k)
+	}
+
+	for k := range r.Trailer {
+		delete(r.Trailer, k)
+	}
+}
+
+// body 因大型 Body 而擴充時，重新配置為預設容量，避免閒置的連線持續佔用大量記憶體
+func shrinkBody(body []byte) []byte {
+	if len(body) > defaultBodySize {
+		return make([]byte, defaultBodySize)
+	}
+	return body
+}
+
+// 將 data 附加到 body[:length] 之後，容量不足時擴充
+func appendBody(body []byte, length int32, data []byte) []byte {
+	size := int(length) + len(data)
+
+	if size > len(body) {
+		capacity := 2 * len(body)
+
+		if capacity < size {
+			capacity = size
+		}
+
+		extended := make([]byte, capacity)
+		copy(extended, body[:length])
+		body = extended
+	}
+
+	copy(body[length:size], data)
+	return body
+}
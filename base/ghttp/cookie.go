@@ -0,0 +1,284 @@
+package ghttp
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// 請求中不存在指定的 Cookie
+var ErrNoCookie = errors.New("Named cookie not present.")
+
+// ====================================================================================================
+// Cookie
+// ====================================================================================================
+
+// Cookie 的 SameSite 屬性
+type SameSite int8
+
+const (
+	// 不設置 SameSite 屬性
+	SameSiteDefaultMode SameSite = iota
+	SameSiteLaxMode
+	SameSiteStrictMode
+	SameSiteNoneMode
+)
+
+func (s SameSite) String() string {
+	switch s {
+	case SameSiteLaxMode:
+		return "Lax"
+	case SameSiteStrictMode:
+		return "Strict"
+	case SameSiteNoneMode:
+		return "None"
+	default:
+		return ""
+	}
+}
+
+type Cookie struct {
+	Name  string
+	Value string
+
+	Path    string
+	Domain  string
+	Expires time.Time
+	// MaxAge = 0 表示不設置 Max-Age；MaxAge < 0 表示立即刪除 Cookie(Max-Age=0)；MaxAge > 0 表示存活秒數
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite SameSite
+}
+
+// 生成 Set-Cookie 標頭的值，名稱不合法時返回空字串
+func (c *Cookie) String() string {
+	if !isCookieNameValid(c.Name) {
+		utils.Error("Invalid cookie name: %q", c.Name)
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(c.Name)
+	b.WriteString("=")
+	b.WriteString(sanitizeCookieValue(c.Value))
+
+	if c.Path != "" {
+		b.WriteString("; Path=")
+		b.WriteString(sanitizeCookieAttribute(c.Path))
+	}
+
+	if c.Domain != "" {
+		b.WriteString("; Domain=")
+		b.WriteString(strings.TrimPrefix(sanitizeCookieAttribute(c.Domain), "."))
+	}
+
+	if !c.Expires.IsZero() {
+		b.WriteString("; Expires=")
+		b.WriteString(c.Expires.UTC().Format(TimeFormat))
+	}
+
+	if c.MaxAge > 0 {
+		b.WriteString("; Max-Age=")
+		b.WriteString(strconv.Itoa(c.MaxAge))
+	} else if c.MaxAge < 0 {
+		b.WriteString("; Max-Age=0")
+	}
+
+	if c.HttpOnly {
+		b.WriteString("; HttpOnly")
+	}
+
+	if c.Secure {
+		b.WriteString("; Secure")
+	}
+
+	if c.SameSite != SameSiteDefaultMode {
+		b.WriteString("; SameSite=")
+		b.WriteString(c.SameSite.String())
+	}
+
+	return b.String()
+}
+
+// Cookie 名稱須為 RFC 7230 定義的 token
+func isCookieNameValid(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		if !isTokenChar(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isTokenChar(b byte) bool {
+	if b <= ' ' || b >= 0x7f {
+		return false
+	}
+	return !strings.ContainsRune("()<>@,;:\\\"/[]?={}", rune(b))
+}
+
+// 移除 Cookie 值中不合法的字元，包含空白或逗號時以雙引號包覆
+func sanitizeCookieValue(value string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		// RFC 6265 cookie-octet
+		if c := value[i]; 0x20 <= c && c < 0x7f && c != '"' && c != ';' && c != '\\' {
+			b.WriteByte(c)
+		}
+	}
+
+	result := b.String()
+
+	if strings.ContainsAny(result, " ,") {
+		return `"` + result + `"`
+	}
+
+	return result
+}
+
+// 移除屬性值中的分號與控制字元
+func sanitizeCookieAttribute(value string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; 0x20 <= c && c < 0x7f && c != ';' {
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// 解析請求標頭 Cookie 中的所有 Cookie(名稱不合法者將被略過)
+func (r *Request) Cookies() []*Cookie {
+	cookies := []*Cookie{}
+	var pair, name, value string
+	var ok bool
+
+	for _, line := range r.Header["Cookie"] {
+		for line != "" {
+			pair, line, _ = strings.Cut(line, ";")
+			name, value, ok = strings.Cut(strings.TrimSpace(pair), "=")
+
+			if !ok || !isCookieNameValid(name) {
+				continue
+			}
+
+			cookies = append(cookies, &Cookie{Name: name, Value: unquoteCookieValue(value)})
+		}
+	}
+
+	return cookies
+}
+
+// 取得名稱為 name 的 Cookie，不存在時返回 ErrNoCookie
+func (r *Request) Cookie(name string) (*Cookie, error) {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == name {
+			return cookie, nil
+		}
+	}
+	return nil, ErrNoCookie
+}
+
+// 解析回應中所有 Set-Cookie 標頭(用於 HttpAsker)，無法解析者將被略過
+func (r *Response) ResponseCookies() []*Cookie {
+	cookies := []*Cookie{}
+
+	for _, line := range r.Header["Set-Cookie"] {
+		cookie, err := ParseSetCookie(line)
+
+		if err != nil {
+			utils.Warn("Failed to parse Set-Cookie(%s): %+v", line, err)
+			continue
+		}
+
+		cookies = append(cookies, cookie)
+	}
+
+	return cookies
+}
+
+// 解析 Set-Cookie 標頭的值，包含 Path, Domain, Expires, Max-Age, Secure, HttpOnly, SameSite 等屬性
+func ParseSetCookie(line string) (*Cookie, error) {
+	parts := strings.Split(line, ";")
+	name, value, ok := strings.Cut(strings.TrimSpace(parts[0]), "=")
+
+	if !ok || !isCookieNameValid(name) {
+		return nil, errors.Errorf("Invalid cookie: %s", parts[0])
+	}
+
+	cookie := &Cookie{Name: name, Value: unquoteCookieValue(value)}
+	var key, attr string
+
+	for _, part := range parts[1:] {
+		key, attr, _ = strings.Cut(strings.TrimSpace(part), "=")
+
+		switch strings.ToLower(key) {
+		case "path":
+			cookie.Path = attr
+		case "domain":
+			cookie.Domain = strings.TrimPrefix(attr, ".")
+		case "expires":
+			expires, err := time.Parse(TimeFormat, attr)
+
+			if err != nil {
+				// 部分伺服器以 - 分隔日期
+				expires, err = time.Parse("Mon, 02-Jan-2006 15:04:05 MST", attr)
+			}
+
+			if err != nil {
+				utils.Warn("Invalid Expires of cookie %s: %s", name, attr)
+				continue
+			}
+
+			cookie.Expires = expires.UTC()
+		case "max-age":
+			maxAge, err := strconv.Atoi(attr)
+
+			if err != nil {
+				utils.Warn("Invalid Max-Age of cookie %s: %s", name, attr)
+				continue
+			}
+
+			if maxAge <= 0 {
+				maxAge = -1
+			}
+
+			cookie.MaxAge = maxAge
+		case "secure":
+			cookie.Secure = true
+		case "httponly":
+			cookie.HttpOnly = true
+		case "samesite":
+			switch strings.ToLower(attr) {
+			case "lax":
+				cookie.SameSite = SameSiteLaxMode
+			case "strict":
+				cookie.SameSite = SameSiteStrictMode
+			case "none":
+				cookie.SameSite = SameSiteNoneMode
+			}
+		}
+	}
+
+	return cookie, nil
+}
+
+// 移除 Cookie 值兩端的雙引號
+func unquoteCookieValue(value string) string {
+	if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,206 @@
+package ghttp
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// 表單所使用的 Content-Type
+const (
+	MIMEPostForm      = "application/x-www-form-urlencoded"
+	MIMEMultipartForm = "multipart/form-data"
+)
+
+// 表單中不存在指定的檔案
+var ErrMissingFile = errors.New("There is no such file in the form.")
+
+// 解析以 & 分隔的 key=value 字串(URL 解碼後)，並加入 values
+// 無法解碼的參數將被略過，並返回第一個遇到的錯誤
+func parseValues(params string, values url.Values) error {
+	var pair, key, value string
+	var ok bool
+	var err, firstErr error
+
+	for params != "" {
+		pair, params, _ = strings.Cut(params, "&")
+
+		if strings.Contains(pair, ";") {
+			utils.Warn("invalid semicolon separator in query(%s)", pair)
+			continue
+		}
+
+		if pair == "" {
+			utils.Warn("Empty query is found.")
+			continue
+		}
+
+		key, value, ok = strings.Cut(pair, "=")
+
+		if !ok {
+			continue
+		}
+
+		if key, err = url.QueryUnescape(key); err == nil {
+			value, err = url.QueryUnescape(value)
+		}
+
+		if err != nil {
+			utils.Warn("Failed to unescape query(%s): %+v", pair, err)
+
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "Failed to unescape query(%s)", pair)
+			}
+			continue
+		}
+
+		values[key] = append(values[key], value)
+	}
+
+	return firstErr
+}
+
+// 根據 Content-Type 解析 Body 中的表單數據(application/x-www-form-urlencoded 或 multipart/form-data)
+// 只會解析一次，之後返回第一次解析的結果
+func (r *Request) ParseForm() error {
+	if r.formParsed {
+		return r.formErr
+	}
+
+	r.formParsed = true
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if err != nil {
+		// 沒有 Body 或非表單的請求
+		return nil
+	}
+
+	switch mediaType {
+	case MIMEPostForm:
+		var data []byte
+		data, err = io.ReadAll(r.BodyReader())
+
+		if err != nil {
+			r.formErr = errors.Wrap(err, "Failed to read form body.")
+			return r.formErr
+		}
+
+		if err = parseValues(string(data), r.form); err != nil {
+			r.formErr = errors.Wrap(err, "Failed to parse form body.")
+		}
+
+	case MIMEMultipartForm:
+		boundary, ok := params["boundary"]
+
+		if !ok || boundary == "" {
+			r.formErr = errors.New("Missing boundary of multipart/form-data.")
+			return r.formErr
+		}
+
+		// 超過記憶體上限的檔案，將由 mime/multipart 暫存於檔案中
+		reader := multipart.NewReader(r.BodyReader(), boundary)
+		r.multipartForm, err = reader.ReadForm(int64(utils.GosConfig.HttpBodyMemoryLimit))
+
+		if err != nil {
+			r.formErr = errors.Wrap(err, "Failed to parse multipart form.")
+			return r.formErr
+		}
+
+		for key, values := range r.multipartForm.Value {
+			r.form[key] = append(r.form[key], values...)
+		}
+	}
+
+	return r.formErr
+}
+
+// 取得 GET 參數的第一個值
+func (r *Request) GetQuery(key string) (string, bool) {
+	if values, ok := r.QueryValues[key]; ok && len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// 取得 GET 參數的所有值(重複的 key)
+func (r *Request) QueryArray(key string) []string {
+	return r.QueryValues[key]
+}
+
+// 取得表單參數的第一個值，不存在時返回空字串
+func (r *Request) PostForm(key string) string {
+	value, _ := r.GetPostForm(key)
+	return value
+}
+
+// 取得表單參數的第一個值
+func (r *Request) GetPostForm(key string) (string, bool) {
+	if values := r.PostFormArray(key); len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// 取得表單參數的所有值(重複的 key)
+func (r *Request) PostFormArray(key string) []string {
+	if err := r.ParseForm(); err != nil {
+		utils.Error("Failed to parse form: %+v", err)
+	}
+	return r.form[key]
+}
+
+// 取得所有表單參數
+func (r *Request) PostFormValues() url.Values {
+	if err := r.ParseForm(); err != nil {
+		utils.Error("Failed to parse form: %+v", err)
+	}
+	return r.form
+}
+
+// 取得 multipart/form-data 表單中，名稱為 key 的第一個檔案
+func (r *Request) FormFile(key string) (*multipart.FileHeader, error) {
+	form, err := r.MultipartForm()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if files := form.File[key]; len(files) > 0 {
+		return files[0], nil
+	}
+
+	return nil, ErrMissingFile
+}
+
+// 取得解析後的 multipart/form-data 表單
+func (r *Request) MultipartForm() (*multipart.Form, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	if r.multipartForm == nil {
+		return nil, errors.New("Request Content-Type isn't multipart/form-data.")
+	}
+
+	return r.multipartForm, nil
+}
+
+// 釋放表單數據，並刪除 mime/multipart 所暫存的檔案
+func (r *Request) releaseForm() {
+	if r.multipartForm != nil {
+		r.multipartForm.RemoveAll()
+		r.multipartForm = nil
+	}
+
+	for key := range r.form {
+		delete(r.form, key)
+	}
+
+	r.formParsed = false
+	r.formErr = nil
+}
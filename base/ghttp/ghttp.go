@@ -1,5 +1,10 @@
 package ghttp
 
+import (
+	"net/textproto"
+	"strings"
+)
+
 // A Header represents the key-value pairs in an HTTP header.
 //
 // The keys should be in canonical form, as returned by
@@ -26,6 +31,42 @@ package ghttp
 
 type Header map[string][]string
 
+// 取得 key 所對應的第一個值，若不存在則返回空字串
+func (h Header) Get(key string) string {
+	if values, ok := h[textproto.CanonicalMIMEHeaderKey(key)]; ok && len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// 以 value 取代 key 原有的值
+func (h Header) Set(key string, value string) {
+	h[textproto.CanonicalMIMEHeaderKey(key)] = []string{value}
+}
+
+// 將 value 附加到 key 原有的值之後
+func (h Header) Add(key string, value string) {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	h[key] = append(h[key], value)
+}
+
+// 移除 key 所對應的值
+func (h Header) Del(key string) {
+	delete(h, textproto.CanonicalMIMEHeaderKey(key))
+}
+
+// 檢查 key 所對應的值(以逗號分隔)當中，是否包含 token(不區分大小寫)
+func (h Header) HasToken(key string, token string) bool {
+	for _, value := range h[textproto.CanonicalMIMEHeaderKey(key)] {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // A MIMEHeader represents a MIME-style header mapping keys to sets of values.
 type MIMEHeader map[string][]string
 
@@ -57,6 +98,17 @@ const (
 
 // const errorHeaders = "\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\n\r\n"
 
+// HTTP 標頭中的時間格式(ex: Last-Modified, Expires)
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// 常用的 Content-Type
+const (
+	MIMEJson        = "application/json"
+	MIMEHTML        = "text/html; charset=utf-8"
+	MIMEPlain       = "text/plain; charset=utf-8"
+	MIMEEventStream = "text/event-stream"
+)
+
 var (
-	jsonContentType = []string{"application/json"}
+	jsonContentType = []string{MIMEJson}
 )
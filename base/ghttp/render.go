@@ -0,0 +1,128 @@
+package ghttp
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/j32u4ukh/gos/utils"
+)
+
+// ====================================================================================================
+// Render
+// 各種格式的回應函式，皆會保留先前設置的其他標頭
+// ====================================================================================================
+
+// 以純文字回應，format 與 args 同 fmt.Sprintf
+func (c *Context) String(code int32, format string, args ...any) {
+	if len(args) > 0 {
+		format = fmt.Sprintf(format, args...)
+	}
+	c.Response.Data(code, MIMEPlain, []byte(format))
+}
+
+// 重新導向至 location(code 須為 3xx 或 201)
+func (c *Context) Redirect(code int32, location string) {
+	if (code < StatusMultipleChoices || code > StatusPermanentRedirect) && code != StatusCreated {
+		utils.Error("Cannot redirect with status code %d", code)
+		c.Response.Data(StatusInternalServerError, MIMEPlain, []byte(StatusText(StatusInternalServerError)))
+		return
+	}
+
+	c.Response.Header.Set("Location", location)
+	c.Response.Data(code, MIMEPlain, []byte{})
+	delete(c.Response.Header, "Content-Type")
+}
+
+// 以檔案內容回應，Content-Type 根據副檔名判斷，無法判斷時根據檔案內容判斷
+// 檔案內容以串流的方式逐段寫出，不會整個讀入記憶體
+func (c *Context) File(path string) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		utils.Warn("File not found: %s", path)
+		c.Json(StatusNotFound, H{
+			"error": "Not Found",
+		})
+		return
+	}
+
+	info, err := file.Stat()
+
+	if err != nil || info.IsDir() {
+		file.Close()
+		utils.Warn("File not found: %s", path)
+		c.Json(StatusNotFound, H{
+			"error": "Not Found",
+		})
+		return
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+
+	if contentType == "" {
+		// 只讀取檔案開頭的數據(最多 512 bytes)來判斷
+		head := make([]byte, 512)
+		n, err := file.ReadAt(head, 0)
+
+		if err != nil && err != io.EOF {
+			file.Close()
+			utils.Error("Failed to read file %s: %+v", path, err)
+			c.Json(StatusInternalServerError, H{
+				"error": "Internal Server Error",
+			})
+			return
+		}
+
+		contentType = http.DetectContentType(head[:n])
+	}
+
+	c.Response.Header.Set("Last-Modified", info.ModTime().UTC().Format(TimeFormat))
+
+	// 檔案於串流結束時關閉
+	c.StreamContent(StatusOK, contentType, file, info.Size())
+}
+
+// 根據副檔名判斷 Content-Type，無法判斷時根據數據內容(前 512 bytes)判斷
+func DetectContentType(name string, data []byte) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(data)
+}
+
+// 設置 HTML 模板(由 HttpAnser 於啟動時載入)
+func (c *Context) SetHTMLTemplate(t *template.Template) {
+	c.template = t
+}
+
+// 以名稱為 name 的 HTML 模板回應
+func (c *Context) HTML(code int32, name string, data any) {
+	if c.template == nil {
+		utils.Error("HTML template is not loaded, name: %s", name)
+		c.Response.Data(StatusInternalServerError, MIMEPlain, []byte(StatusText(StatusInternalServerError)))
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	if err := c.template.ExecuteTemplate(&buffer, name, data); err != nil {
+		utils.Error("Failed to execute template %s: %+v", name, err)
+		c.Response.Data(StatusInternalServerError, MIMEPlain, []byte(StatusText(StatusInternalServerError)))
+		return
+	}
+
+	c.Response.Data(code, MIMEHTML, buffer.Bytes())
+}
+
+// 以 Set-Cookie 標頭設置 Cookie
+func (c *Context) SetCookie(cookie *Cookie) {
+	if value := cookie.String(); value != "" {
+		c.Response.Header.Add("Set-Cookie", value)
+	}
+}
@@ -0,0 +1,181 @@
+package ghttp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Session
+// 由 middleware.Sessions 於處理函式執行前載入，處理函式執行後(數據有變更時)保存
+// ====================================================================================================
+
+// Session 保存於 Context.Keys 中所使用的 key
+const SessionKey string = "Session"
+
+// Session Cookie 的屬性
+type SessionOptions struct {
+	Path   string
+	Domain string
+	// Session 的存活秒數，MaxAge <= 0 時為瀏覽器關閉即失效的 Cookie
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite SameSite
+}
+
+func DefaultSessionOptions() SessionOptions {
+	return SessionOptions{
+		Path:     "/",
+		MaxAge:   86400,
+		HttpOnly: true,
+		SameSite: SameSiteLaxMode,
+	}
+}
+
+// 根據 Session Cookie 的屬性生成 Cookie
+func (o *SessionOptions) newCookie(name string, value string) *Cookie {
+	cookie := &Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		Secure:   o.Secure,
+		HttpOnly: o.HttpOnly,
+		SameSite: o.SameSite,
+	}
+
+	if o.MaxAge > 0 {
+		cookie.MaxAge = o.MaxAge
+		cookie.Expires = time.Now().Add(time.Duration(o.MaxAge) * time.Second)
+	}
+
+	return cookie
+}
+
+// 生成刪除 Session Cookie 的 Cookie
+func (o *SessionOptions) expiredCookie(name string) *Cookie {
+	cookie := o.newCookie(name, "")
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(1, 0)
+	return cookie
+}
+
+// Session 的存取介面
+type SessionStore interface {
+	// 根據請求中名為 name 的 Cookie 載入 Session，不存在或已失效時返回新的 Session(Cookie 無效時同時返回錯誤)
+	Get(c *Context, name string) (*Session, error)
+	// 保存 Session，並透過 Set-Cookie 將 Session 資訊寫入回應
+	Save(c *Context, session *Session) error
+}
+
+type Session struct {
+	// Session 唯一碼(CookieStore 不使用)
+	ID string
+	// Session 數據
+	Values map[string]any
+	// 是否為本次請求新建立的 Session
+	IsNew bool
+	// Session Cookie 的名稱
+	name string
+	// 數據是否有變更(有變更才需要保存)
+	modified bool
+	// 是否要求刪除 Session
+	destroyed bool
+	// 是否要求更換 Session 唯一碼(避免 Session fixation，如登入時)
+	regenerate bool
+}
+
+func NewSession(name string) *Session {
+	s := &Session{
+		ID:     "",
+		Values: map[string]any{},
+		IsNew:  true,
+		name:   name,
+	}
+	return s
+}
+
+func (s *Session) Name() string {
+	return s.name
+}
+
+func (s *Session) Get(key string) (value any, exists bool) {
+	value, exists = s.Values[key]
+	return
+}
+
+func (s *Session) Set(key string, value any) {
+	s.Values[key] = value
+	s.modified = true
+}
+
+func (s *Session) Delete(key string) {
+	if _, ok := s.Values[key]; ok {
+		delete(s.Values, key)
+		s.modified = true
+	}
+}
+
+// 清空 Session 數據
+func (s *Session) Clear() {
+	for key := range s.Values {
+		delete(s.Values, key)
+	}
+	s.modified = true
+}
+
+// 刪除 Session，並要求客戶端刪除 Session Cookie
+func (s *Session) Destroy() {
+	s.Clear()
+	s.destroyed = true
+}
+
+// 保存時更換 Session 唯一碼，並使舊的唯一碼失效
+func (s *Session) Regenerate() {
+	s.regenerate = true
+	s.modified = true
+}
+
+// 數據是否有變更，或被要求刪除
+func (s *Session) IsModified() bool {
+	return s.modified || s.destroyed
+}
+
+func (s *Session) IsDestroyed() bool {
+	return s.destroyed
+}
+
+// 保存後重置變更狀態
+func (s *Session) saved() {
+	s.IsNew = false
+	s.modified = false
+	s.regenerate = false
+}
+
+// 取得由 middleware.Sessions 所載入的 Session，未使用 Session 中介函式時返回 nil
+func (c *Context) Session() *Session {
+	if value, ok := c.Keys[SessionKey]; ok {
+		if session, ok := value.(*Session); ok {
+			return session
+		}
+	}
+
+	utils.Error("Session is not loaded, please use middleware.Sessions.")
+	return nil
+}
+
+// 生成隨機的 Session 唯一碼
+func newSessionId() (string, error) {
+	buffer := make([]byte, 32)
+
+	if _, err := rand.Read(buffer); err != nil {
+		return "", errors.Wrap(err, "Failed to generate session id.")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
@@ -0,0 +1,305 @@
+package ghttp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// MemoryStore
+// Session 數據保存於記憶體，Cookie 中只保存 Session 唯一碼
+// ====================================================================================================
+type MemoryStore struct {
+	Options SessionOptions
+	// Session 閒置多久後失效(每次保存時重新計算)
+	ttl      time.Duration
+	sessions map[string]*memorySession
+	// 上次清除過期 Session 的時間
+	lastEvict time.Time
+	sync.Mutex
+}
+
+type memorySession struct {
+	values  map[string]any
+	expires time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		Options:   DefaultSessionOptions(),
+		ttl:       ttl,
+		sessions:  map[string]*memorySession{},
+		lastEvict: time.Now(),
+	}
+	s.Options.MaxAge = int(ttl / time.Second)
+	return s
+}
+
+func (s *MemoryStore) Get(c *Context, name string) (*Session, error) {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	s.evict(now)
+	session := NewSession(name)
+	cookie, err := c.Request.Cookie(name)
+
+	if err != nil {
+		return session, nil
+	}
+
+	ms, ok := s.sessions[cookie.Value]
+
+	if !ok {
+		return session, errors.Errorf("Session %s is not found or expired.", name)
+	}
+
+	if now.After(ms.expires) {
+		delete(s.sessions, cookie.Value)
+		return session, errors.Errorf("Session %s is expired.", name)
+	}
+
+	session.ID = cookie.Value
+	session.IsNew = false
+
+	// 複製一份數據，未保存的變更不影響 Store 中的數據
+	for key, value := range ms.values {
+		session.Values[key] = value
+	}
+
+	return session, nil
+}
+
+func (s *MemoryStore) Save(c *Context, session *Session) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if session.destroyed {
+		delete(s.sessions, session.ID)
+		c.SetCookie(s.Options.expiredCookie(session.name))
+		session.saved()
+		return nil
+	}
+
+	if session.ID == "" || session.regenerate {
+		id, err := newSessionId()
+
+		if err != nil {
+			return err
+		}
+
+		delete(s.sessions, session.ID)
+		session.ID = id
+	}
+
+	ms := &memorySession{
+		values:  make(map[string]any, len(session.Values)),
+		expires: time.Now().Add(s.ttl),
+	}
+
+	for key, value := range session.Values {
+		ms.values[key] = value
+	}
+
+	s.sessions[session.ID] = ms
+	c.SetCookie(s.Options.newCookie(session.name, session.ID))
+	session.saved()
+	return nil
+}
+
+// 當前保存的 Session 數量(包含尚未被清除的過期 Session)
+func (s *MemoryStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.sessions)
+}
+
+// 每經過 ttl 清除一次過期的 Session
+func (s *MemoryStore) evict(now time.Time) {
+	if now.Sub(s.lastEvict) < s.ttl {
+		return
+	}
+
+	for id, ms := range s.sessions {
+		if now.After(ms.expires) {
+			delete(s.sessions, id)
+		}
+	}
+
+	s.lastEvict = now
+}
+
+// ====================================================================================================
+// CookieStore
+// Session 數據以 JSON 編碼後保存於 Cookie 中，以 HMAC-SHA256 簽名，並可選擇以 AES-GCM 加密
+// JSON 解碼後，數值型別的數據將成為 float64
+// ====================================================================================================
+
+// 瀏覽器對單一 Cookie 的大小限制
+const maxCookieSize int = 4096
+
+type CookieStore struct {
+	Options SessionOptions
+	hashKey []byte
+	// 為 nil 時只簽名不加密
+	aead cipher.AEAD
+}
+
+// hashKey 用於簽名(建議 32 或 64 bytes)；blockKey 用於加密，長度須為 16, 24 或 32 bytes，為 nil 時不加密
+func NewCookieStore(hashKey []byte, blockKey []byte) (*CookieStore, error) {
+	if len(hashKey) == 0 {
+		return nil, errors.New("Hash key of CookieStore is required.")
+	}
+
+	s := &CookieStore{
+		Options: DefaultSessionOptions(),
+		hashKey: hashKey,
+	}
+
+	if blockKey != nil {
+		block, err := aes.NewCipher(blockKey)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid block key of CookieStore.")
+		}
+
+		s.aead, err = cipher.NewGCM(block)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create AES-GCM.")
+		}
+	}
+
+	return s, nil
+}
+
+func (s *CookieStore) Get(c *Context, name string) (*Session, error) {
+	session := NewSession(name)
+	cookie, err := c.Request.Cookie(name)
+
+	if err != nil {
+		return session, nil
+	}
+
+	if err = s.decode(name, cookie.Value, &session.Values); err != nil {
+		session.Values = map[string]any{}
+		return session, errors.Wrapf(err, "Failed to decode session %s", name)
+	}
+
+	session.IsNew = false
+	return session, nil
+}
+
+func (s *CookieStore) Save(c *Context, session *Session) error {
+	if session.destroyed {
+		c.SetCookie(s.Options.expiredCookie(session.name))
+		session.saved()
+		return nil
+	}
+
+	value, err := s.encode(session.name, session.Values)
+
+	if err != nil {
+		return err
+	}
+
+	c.SetCookie(s.Options.newCookie(session.name, value))
+	session.saved()
+	return nil
+}
+
+// 編碼格式: base64(時間戳(8 bytes) + 數據) + "." + base64(HMAC(name + "|" + 前段))
+// 加密時，數據為 nonce + 密文(以 name 作為附加驗證數據)
+func (s *CookieStore) encode(name string, values map[string]any) (string, error) {
+	data, err := json.Marshal(values)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to marshal session values.")
+	}
+
+	if s.aead != nil {
+		nonce := make([]byte, s.aead.NonceSize())
+
+		if _, err = rand.Read(nonce); err != nil {
+			return "", errors.Wrap(err, "Failed to generate nonce.")
+		}
+
+		data = s.aead.Seal(nonce, nonce, data, []byte(name))
+	}
+
+	payload := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Unix()))
+	copy(payload[8:], data)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	value := encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(name, encoded))
+
+	if len(name)+len(value) > maxCookieSize {
+		return "", errors.Errorf("Session %s is too large(%d bytes).", name, len(value))
+	}
+
+	return value, nil
+}
+
+func (s *CookieStore) decode(name string, value string, values *map[string]any) error {
+	encoded, mac, ok := strings.Cut(value, ".")
+
+	if !ok {
+		return errors.New("Invalid session cookie format.")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(mac)
+
+	if err != nil || !hmac.Equal(signature, s.sign(name, encoded)) {
+		return errors.New("Invalid session cookie signature.")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+
+	if err != nil || len(payload) < 8 {
+		return errors.New("Invalid session cookie payload.")
+	}
+
+	timestamp := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+
+	if s.Options.MaxAge > 0 && time.Since(timestamp) > time.Duration(s.Options.MaxAge)*time.Second {
+		return errors.New("Session cookie is expired.")
+	}
+
+	data := payload[8:]
+
+	if s.aead != nil {
+		nonceSize := s.aead.NonceSize()
+
+		if len(data) < nonceSize {
+			return errors.New("Invalid session cookie ciphertext.")
+		}
+
+		data, err = s.aead.Open(nil, data[:nonceSize], data[nonceSize:], []byte(name))
+
+		if err != nil {
+			return errors.Wrap(err, "Failed to decrypt session cookie.")
+		}
+	}
+
+	if err = json.Unmarshal(data, values); err != nil {
+		return errors.Wrap(err, "Failed to unmarshal session values.")
+	}
+
+	return nil
+}
+
+func (s *CookieStore) sign(name string, encoded string) []byte {
+	h := hmac.New(sha256.New, s.hashKey)
+	h.Write([]byte(name + "|" + encoded))
+	return h.Sum(nil)
+}
@@ -0,0 +1,192 @@
+package ghttp
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Server-Sent Events
+// 以 Context.Stream 維持連線，事件寫入 SSEStream 後，於之後的迴圈中寫出(可於任何之後的 gos.Run 迴圈中送出事件)
+// ====================================================================================================
+
+type SSEEvent struct {
+	// 事件唯一碼(客戶端重新連線時，透過 Last-Event-ID 標頭傳回)
+	Id string
+	// 事件名稱(空字串時為預設的 message 事件)
+	Event string
+	// 事件數據，包含換行時將拆分為多行 data
+	Data string
+	// 要求客戶端斷線後重新連線的等待時間(0 表示不設置)
+	Retry time.Duration
+}
+
+type SSEStream struct {
+	// 閒置超過此時間時送出註解，避免連線被代理伺服器關閉(0 表示不送出)
+	KeepAliveInterval time.Duration
+	// 尚未寫出的事件
+	pending bytes.Buffer
+	// 最後寫出數據的時間
+	lastWrite time.Time
+	// 伺服器端要求結束串流
+	closing bool
+	// 串流已結束
+	closed bool
+	// 客戶端斷線時呼叫
+	onClose func()
+}
+
+// 以 Server-Sent Events 回應，返回的 SSEStream 可保存於處理函式之外，於之後的迴圈中送出事件
+// 串流期間連線不會讀取新的請求，SSEStream.Close 後關閉連線
+func (c *Context) SSE() *SSEStream {
+	s := &SSEStream{
+		KeepAliveInterval: utils.GosConfig.SSEKeepAliveInterval,
+		lastWrite:         time.Now(),
+	}
+
+	c.Response.Header.Set("Content-Type", MIMEEventStream)
+	c.Response.Header.Set("Cache-Control", "no-cache")
+	c.Response.Header.Set("X-Accel-Buffering", "no")
+	c.Status(StatusOK)
+	c.Stream(s.step)
+	c.sse = s
+
+	// 串流結束後，無法確定客戶端的狀態，因此關閉連線
+	c.KeepAlive = false
+	return s
+}
+
+// 請求是否接受 Server-Sent Events
+func (r *Request) AcceptEventStream() bool {
+	return strings.Contains(r.Header.Get("Accept"), MIMEEventStream)
+}
+
+// 客戶端重新連線時所帶的最後事件唯一碼
+func (r *Request) LastEventId() string {
+	return r.Header.Get("Last-Event-Id")
+}
+
+// 送出事件
+func (s *SSEStream) Send(event SSEEvent) error {
+	if s.closing || s.closed {
+		return errors.New("SSE stream is closed.")
+	}
+
+	if strings.ContainsAny(event.Id, "\r\n\x00") || strings.ContainsAny(event.Event, "\r\n") {
+		return errors.Errorf("Invalid SSE event id(%q) or name(%q).", event.Id, event.Event)
+	}
+
+	if event.Id != "" {
+		s.writeField("id", event.Id)
+	}
+
+	if event.Event != "" {
+		s.writeField("event", event.Event)
+	}
+
+	if event.Retry > 0 {
+		s.writeField("retry", strconv.FormatInt(event.Retry.Milliseconds(), 10))
+	}
+
+	// 統一換行符號後，每行數據各自成為一個 data 欄位
+	data := strings.ReplaceAll(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\r", "\n")
+
+	for _, line := range strings.Split(data, "\n") {
+		s.writeField("data", line)
+	}
+
+	s.pending.WriteByte('\n')
+	return nil
+}
+
+// 送出名為 event 的事件
+func (s *SSEStream) Event(event string, data string) error {
+	return s.Send(SSEEvent{Event: event, Data: data})
+}
+
+// 送出預設的 message 事件
+func (s *SSEStream) Data(data string) error {
+	return s.Send(SSEEvent{Data: data})
+}
+
+// 送出註解(客戶端將忽略)
+func (s *SSEStream) Comment(text string) error {
+	if s.closing || s.closed {
+		return errors.New("SSE stream is closed.")
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		s.pending.WriteString(": ")
+		s.pending.WriteString(strings.TrimRight(line, "\r"))
+		s.pending.WriteByte('\n')
+	}
+
+	s.pending.WriteByte('\n')
+	return nil
+}
+
+// 寫出尚未寫出的事件後，結束串流並關閉連線
+func (s *SSEStream) Close() {
+	s.closing = true
+}
+
+// 串流是否已結束(伺服器端呼叫 Close 或客戶端斷線)
+func (s *SSEStream) IsClosed() bool {
+	return s.closing || s.closed
+}
+
+// 設置客戶端斷線時的 callback(伺服器端呼叫 Close 時不會呼叫)
+func (s *SSEStream) OnClose(callback func()) {
+	s.onClose = callback
+}
+
+func (s *SSEStream) writeField(name string, value string) {
+	s.pending.WriteString(name)
+	s.pending.WriteString(": ")
+	s.pending.WriteString(value)
+	s.pending.WriteByte('\n')
+}
+
+// 由 Context.StreamStep 呼叫，寫出尚未寫出的事件，閒置過久時寫出註解，返回 false 表示串流結束
+func (s *SSEStream) step(w io.Writer) bool {
+	if s.closed {
+		return false
+	}
+
+	if s.pending.Len() == 0 && !s.closing && s.KeepAliveInterval > 0 && time.Since(s.lastWrite) >= s.KeepAliveInterval {
+		s.pending.WriteString(": keep-alive\n\n")
+	}
+
+	if s.pending.Len() > 0 {
+		w.Write(s.pending.Bytes())
+		s.pending.Reset()
+		s.lastWrite = time.Now()
+	}
+
+	if s.closing {
+		s.closed = true
+		return false
+	}
+
+	return true
+}
+
+// 串流尚未結束即重置 Context 時(由 Context.Reset 呼叫)，通知應用層客戶端已斷線
+func (s *SSEStream) disconnect() {
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	s.pending.Reset()
+
+	if s.onClose != nil && !s.closing {
+		s.onClose()
+	}
+}
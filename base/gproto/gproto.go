@@ -0,0 +1,152 @@
+package gproto
+
+import (
+	"encoding/binary"
+
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ====================================================================================================
+// Messages
+// 以數字 id 識別的 protobuf 訊息，於 Tcp0 的一包數據中依序放入 2 bytes 的 id 以及 protobuf 編碼後的數據
+// 收到的訊息解析後，交由對應型別的處理函式(如 func(cid int32, msg *pb.Login))處理
+// ====================================================================================================
+
+// 訊息 id 的長度
+const IdSize int = 2
+
+type messageType struct {
+	id          uint16
+	messageType protoreflect.MessageType
+	// 處理函式(僅註冊型別時為 nil)
+	handler func(cid int32, msg proto.Message)
+}
+
+type Messages struct {
+	// 訊息 id 的位元組順序
+	Order binary.ByteOrder
+	// 無法處理收到的訊息時呼叫(id 未註冊、數據不合法、未設置處理函式)，預設僅輸出警告
+	OnError func(cid int32, id uint16, err error)
+	// key: 訊息 id
+	types map[uint16]*messageType
+	// key: 訊息的完整名稱
+	names map[protoreflect.FullName]*messageType
+}
+
+func NewMessages() *Messages {
+	m := &Messages{
+		Order: binary.LittleEndian,
+		OnError: func(cid int32, id uint16, err error) {
+			utils.Warn("Conn(%d) failed to handle message %d: %+v", cid, id, err)
+		},
+		types: map[uint16]*messageType{},
+		names: map[protoreflect.FullName]*messageType{},
+	}
+	return m
+}
+
+// 以 id 註冊訊息型別 T(如 *pb.Login)以及其處理函式，handler 為 nil 時僅註冊型別(如只會送出的訊息)
+func Register[T proto.Message](m *Messages, id uint16, handler func(cid int32, msg T)) error {
+	var zero T
+	mt := zero.ProtoReflect().Type()
+	name := mt.Descriptor().FullName()
+
+	if t, ok := m.types[id]; ok {
+		return errors.Errorf("Message id %d is already registered by %s.", id, t.messageType.Descriptor().FullName())
+	}
+
+	if t, ok := m.names[name]; ok {
+		return errors.Errorf("Message %s is already registered with id %d.", name, t.id)
+	}
+
+	t := &messageType{
+		id:          id,
+		messageType: mt,
+	}
+
+	if handler != nil {
+		t.handler = func(cid int32, msg proto.Message) {
+			handler(cid, msg.(T))
+		}
+	}
+
+	m.types[id] = t
+	m.names[name] = t
+	return nil
+}
+
+// 取得訊息型別的 id
+func (m *Messages) GetId(msg proto.Message) (uint16, bool) {
+	t, ok := m.names[msg.ProtoReflect().Descriptor().FullName()]
+
+	if !ok {
+		return 0, false
+	}
+
+	return t.id, true
+}
+
+// 將訊息編碼為 id 以及 protobuf 數據
+func (m *Messages) Marshal(msg proto.Message) ([]byte, error) {
+	id, ok := m.GetId(msg)
+
+	if !ok {
+		return nil, errors.Errorf("Message %s is not registered.", msg.ProtoReflect().Descriptor().FullName())
+	}
+
+	data := make([]byte, IdSize, IdSize+proto.Size(msg))
+	m.Order.PutUint16(data, id)
+	data, err := proto.MarshalOptions{}.MarshalAppend(data, msg)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to marshal message %d.", id)
+	}
+
+	return data, nil
+}
+
+// 由 id 以及 protobuf 數據解析訊息
+func (m *Messages) Unmarshal(data []byte) (uint16, proto.Message, error) {
+	if len(data) < IdSize {
+		return 0, nil, errors.Errorf("Message length %d is shorter than id size %d.", len(data), IdSize)
+	}
+
+	id := m.Order.Uint16(data)
+	t, ok := m.types[id]
+
+	if !ok {
+		return id, nil, errors.Errorf("Message id %d is not registered.", id)
+	}
+
+	msg := t.messageType.New().Interface()
+
+	if err := proto.Unmarshal(data[IdSize:], msg); err != nil {
+		return id, nil, errors.Wrapf(err, "Failed to unmarshal message %d.", id)
+	}
+
+	return id, msg, nil
+}
+
+// 作為 Tcp0 的工作處理函式，解析工作中的訊息並交由對應的處理函式
+func (m *Messages) Handle(w *base.Work) {
+	id, msg, err := m.Unmarshal(w.Body.GetData())
+
+	if err == nil {
+		if t := m.types[id]; t.handler != nil {
+			t.handler(w.Index, msg)
+		} else {
+			err = errors.Errorf("Message id %d has no handler.", id)
+		}
+	}
+
+	if err != nil && m.OnError != nil {
+		m.OnError(w.Index, id, err)
+	}
+
+	w.Finish()
+}
@@ -0,0 +1,146 @@
+package base
+
+import (
+	"bytes"
+
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Line
+// 以分隔字元(預設為 '\n')切分的文字協定，每一行作為一個訊息，適用於管理主控台等簡單的文字指令
+// ====================================================================================================
+
+// 行的格式
+type LineFormat struct {
+	// 行的分隔字元
+	Delimiter byte
+	// 讀取時是否移除行尾的 '\r'(分隔字元為 '\n' 時，可同時接受 "\r\n" 與 "\n")
+	TrimCR bool
+	// 寫出時是否於分隔字元前加上 '\r'
+	WriteCR bool
+	// 一行(不含 '\r' 與分隔字元)的長度上限，超過時斷線
+	MaxLineLength int32
+}
+
+func NewLineFormat() *LineFormat {
+	f := &LineFormat{
+		Delimiter:     '\n',
+		TrimCR:        true,
+		WriteCR:       false,
+		MaxLineLength: utils.GosConfig.LineMaxLength,
+	}
+	return f
+}
+
+// 檢查格式是否合法，limit 為讀取緩存可容納的長度
+func (f *LineFormat) Check(limit int32) error {
+	if f.MaxLineLength <= 0 {
+		return errors.Errorf("Invalid max line length %d.", f.MaxLineLength)
+	}
+
+	if f.MaxLineLength+2 > limit {
+		return errors.Errorf("Max line length %d exceeds the read buffer size %d.", f.MaxLineLength, limit)
+	}
+
+	return nil
+}
+
+// 於訊息後加上分隔字元
+func (f *LineFormat) Encode(message []byte) ([]byte, error) {
+	if int32(len(message)) > f.MaxLineLength {
+		return nil, errors.Errorf("Line length %d exceeds %d.", len(message), f.MaxLineLength)
+	}
+
+	if bytes.IndexByte(message, f.Delimiter) != -1 {
+		return nil, errors.Errorf("Line should not contain the delimiter %q.", f.Delimiter)
+	}
+
+	result := make([]byte, 0, len(message)+2)
+	result = append(result, message...)
+
+	if f.WriteCR {
+		result = append(result, '\r')
+	}
+
+	return append(result, f.Delimiter), nil
+}
+
+// 以 LineFormat 切分訊息的 Codec，多個 LineCodec 可共用同一個 LineFormat
+type LineCodec struct {
+	Format *LineFormat
+	// 已檢查過、不含分隔字元的長度(避免每次從頭尋找)
+	scanned int32
+	// 含分隔字元的行長度
+	readLength int32
+}
+
+func NewLineCodec(format *LineFormat) *LineCodec {
+	l := &LineCodec{
+		Format:     format,
+		scanned:    0,
+		readLength: 0,
+	}
+	return l
+}
+
+// 檢查是否滿足：讀取緩存中包含分隔字元(只檢查長度上限內的數據)
+func (l *LineCodec) hasLine(buffer *[]byte, i int32, o int32, length int32) bool {
+	size := int32(len(*buffer))
+
+	if limit := l.Format.MaxLineLength + 2; length > limit {
+		length = limit
+	}
+
+	for l.scanned < length {
+		start := (o + l.scanned) % size
+		end := start + length - l.scanned
+
+		if end > size {
+			end = size
+		}
+
+		if idx := bytes.IndexByte((*buffer)[start:end], l.Format.Delimiter); idx != -1 {
+			l.readLength = l.scanned + int32(idx) + 1
+			l.scanned = 0
+			return true
+		}
+
+		l.scanned += end - start
+	}
+
+	return false
+}
+
+func (l *LineCodec) Decode(c *Conn, buffer *[]byte) ([]byte, bool, error) {
+	if !c.CheckReadable(l.hasLine) {
+		if l.scanned >= l.Format.MaxLineLength+2 {
+			return nil, false, errors.Errorf("Line length exceeds %d.", l.Format.MaxLineLength)
+		}
+		return nil, false, nil
+	}
+
+	c.Read(buffer, l.readLength)
+	line := (*buffer)[:l.readLength-1]
+
+	if l.Format.TrimCR && len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+
+	if int32(len(line)) > l.Format.MaxLineLength {
+		return nil, false, errors.Errorf("Line length %d exceeds %d.", len(line), l.Format.MaxLineLength)
+	}
+
+	return line, true, nil
+}
+
+func (l *LineCodec) Encode(message []byte) ([]byte, error) {
+	return l.Format.Encode(message)
+}
+
+func (l *LineCodec) Reset() {
+	l.scanned = 0
+	l.readLength = 0
+}
@@ -1,21 +1,192 @@
 package base
 
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Tcp0
+// 以長度前綴切分數據，長度前綴的格式由 Tcp0Framing 定義(預設為 4 bytes、LittleEndian、長度不含長度前綴本身)
+// ====================================================================================================
+
+// 長度前綴的格式
+type Tcp0Framing struct {
+	// 長度前綴的 byte 數(1, 2, 4, 8)
+	HeaderSize int32
+	// 長度前綴的位元組順序
+	Order binary.ByteOrder
+	// 長度是否包含長度前綴本身
+	IncludeHeader bool
+	// 一包數據(含長度前綴)的長度上限，超過時斷線(0 表示由 Anser/Asker 根據讀取緩存大小決定)
+	MaxFrameSize int32
+}
+
+func NewTcp0Framing() *Tcp0Framing {
+	f := &Tcp0Framing{
+		HeaderSize:    4,
+		Order:         binary.LittleEndian,
+		IncludeHeader: false,
+		MaxFrameSize:  0,
+	}
+	return f
+}
+
+// 檢查格式是否合法
+func (f *Tcp0Framing) Check() error {
+	switch f.HeaderSize {
+	case 1, 2, 4, 8:
+	default:
+		return errors.Errorf("Invalid Tcp0 header size %d, should be 1, 2, 4 or 8.", f.HeaderSize)
+	}
+
+	if f.Order == nil {
+		return errors.New("Tcp0 byte order is nil.")
+	}
+
+	if f.MaxFrameSize < 0 || (f.MaxFrameSize > 0 && f.MaxFrameSize < f.HeaderSize) {
+		return errors.Errorf("Invalid Tcp0 max frame size %d.", f.MaxFrameSize)
+	}
+
+	return nil
+}
+
+func (f *Tcp0Framing) maxFrameSize() uint64 {
+	if f.MaxFrameSize == 0 {
+		return math.MaxInt32
+	}
+	return uint64(f.MaxFrameSize)
+}
+
+// 返回長度上限已確定的複本：MaxFrameSize 為 0 時以 limit 為上限，超過 limit 時返回錯誤
+func (f *Tcp0Framing) Limit(limit int32) (*Tcp0Framing, error) {
+	if err := f.Check(); err != nil {
+		return nil, err
+	}
+
+	framing := *f
+
+	if framing.MaxFrameSize == 0 {
+		framing.MaxFrameSize = limit
+	} else if framing.MaxFrameSize > limit {
+		return nil, errors.Errorf("Max frame size %d exceeds the read buffer size %d.", framing.MaxFrameSize, limit)
+	}
+
+	return &framing, nil
+}
+
+// 由長度前綴取得數據長度(不含長度前綴)，長度不合法或超過上限時返回錯誤
+func (f *Tcp0Framing) DecodeLength(header []byte) (int32, error) {
+	var n uint64
+
+	switch f.HeaderSize {
+	case 1:
+		n = uint64(header[0])
+	case 2:
+		n = uint64(f.Order.Uint16(header))
+	case 4:
+		n = uint64(f.Order.Uint32(header))
+	case 8:
+		n = f.Order.Uint64(header)
+	default:
+		return 0, errors.Errorf("Invalid Tcp0 header size %d.", f.HeaderSize)
+	}
+
+	headerSize := uint64(f.HeaderSize)
+	maxFrameSize := f.maxFrameSize()
+
+	if f.IncludeHeader {
+		if n < headerSize {
+			return 0, errors.Errorf("Frame length %d is shorter than header size %d.", n, headerSize)
+		}
+	} else if n > maxFrameSize {
+		// 先行檢查，避免加上長度前綴後溢位
+		return 0, errors.Errorf("Frame length %d exceeds %d.", n, maxFrameSize)
+	} else {
+		n += headerSize
+	}
+
+	if n > maxFrameSize {
+		return 0, errors.Errorf("Frame length %d exceeds %d.", n, maxFrameSize)
+	}
+
+	return int32(n - headerSize), nil
+}
+
+// 於數據前加上長度前綴
+func (f *Tcp0Framing) Encode(data []byte) ([]byte, error) {
+	frameSize := uint64(len(data)) + uint64(f.HeaderSize)
+
+	if frameSize > f.maxFrameSize() {
+		return nil, errors.Errorf("Frame length %d exceeds %d.", frameSize, f.maxFrameSize())
+	}
+
+	n := uint64(len(data))
+
+	if f.IncludeHeader {
+		n = frameSize
+	}
+
+	if f.HeaderSize < 8 && n >= 1<<(8*uint(f.HeaderSize)) {
+		return nil, errors.Errorf("Length %d can not be represented by %d bytes.", n, f.HeaderSize)
+	}
+
+	result := make([]byte, frameSize)
+
+	switch f.HeaderSize {
+	case 1:
+		result[0] = byte(n)
+	case 2:
+		f.Order.PutUint16(result, uint16(n))
+	case 4:
+		f.Order.PutUint32(result, uint32(n))
+	case 8:
+		f.Order.PutUint64(result, n)
+	default:
+		return nil, errors.Errorf("Invalid Tcp0 header size %d.", f.HeaderSize)
+	}
+
+	copy(result[f.HeaderSize:], data)
+	return result, nil
+}
+
 type Tcp0 struct {
 	// 讀取狀態值 | 0: 讀取數據長度, 1: 根據前一階段取得的長度，讀取數據
 	State      int8
 	HeaderSize int32
 	ReadLength int32
+	// 長度前綴的格式
+	Framing *Tcp0Framing
 }
 
 func NewTcp0() *Tcp0 {
-	t := &Tcp0{
-		State:      0,
-		HeaderSize: 4,
-	}
-	t.ReadLength = t.HeaderSize
+	t := &Tcp0{}
+	t.SetFraming(NewTcp0Framing())
 	return t
 }
 
+// 設置長度前綴的格式，並重置讀取狀態
+func (t *Tcp0) SetFraming(framing *Tcp0Framing) {
+	t.Framing = framing
+	t.HeaderSize = framing.HeaderSize
+	t.ResetReadLength()
+}
+
+// 由長度前綴取得下次欲讀取的數據長度，長度不合法或超過上限時返回錯誤
+func (t *Tcp0) ReadHeader(header []byte) error {
+	length, err := t.Framing.DecodeLength(header)
+
+	if err != nil {
+		return err
+	}
+
+	t.ReadLength = length
+	t.State = 1
+	return nil
+}
+
 func (t *Tcp0) ResetReadLength() {
 	t.State = 0
 	t.ReadLength = t.HeaderSize
@@ -0,0 +1,142 @@
+package base
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// CertReloader
+// 憑證檔案更新後，於下次交握時自動載入新的憑證，無須重新啟動服務
+// 伺服器端: tls.Config{GetCertificate: reloader.GetCertificate}
+// 客戶端: tls.Config{GetClientCertificate: reloader.GetClientCertificate}
+// ====================================================================================================
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	// 檢查憑證檔案是否更新的最短間隔
+	CheckInterval time.Duration
+	cert          *tls.Certificate
+	// 當前憑證所對應的檔案修改時間
+	certTime time.Time
+	keyTime  time.Time
+	// 上次檢查檔案的時間
+	lastCheck time.Time
+	sync.Mutex
+}
+
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		CheckInterval: 10 * time.Second,
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// 重新載入憑證，載入失敗時保留原本的憑證
+func (r *CertReloader) Reload() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.reload()
+}
+
+func (r *CertReloader) reload() error {
+	certTime, keyTime, err := r.modTimes()
+
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+
+	if err != nil {
+		return errors.Wrapf(err, "Failed to load key pair(%s, %s)", r.certFile, r.keyFile)
+	}
+
+	r.cert = &cert
+	r.certTime = certTime
+	r.keyTime = keyTime
+	r.lastCheck = time.Now()
+	return nil
+}
+
+func (r *CertReloader) modTimes() (certTime time.Time, keyTime time.Time, err error) {
+	var info os.FileInfo
+
+	if info, err = os.Stat(r.certFile); err != nil {
+		return certTime, keyTime, errors.Wrapf(err, "Failed to stat %s", r.certFile)
+	}
+
+	certTime = info.ModTime()
+
+	if info, err = os.Stat(r.keyFile); err != nil {
+		return certTime, keyTime, errors.Wrapf(err, "Failed to stat %s", r.keyFile)
+	}
+
+	return certTime, info.ModTime(), nil
+}
+
+// 取得當前憑證，距離上次檢查超過 CheckInterval 且檔案有更新時，重新載入
+func (r *CertReloader) Certificate() *tls.Certificate {
+	r.Lock()
+	defer r.Unlock()
+
+	if time.Since(r.lastCheck) >= r.CheckInterval {
+		r.lastCheck = time.Now()
+		certTime, keyTime, err := r.modTimes()
+
+		if err != nil {
+			utils.Error("Failed to check certificate: %+v", err)
+		} else if !certTime.Equal(r.certTime) || !keyTime.Equal(r.keyTime) {
+			if err = r.reload(); err != nil {
+				// 可能是憑證與私鑰只更新了其中一個，下次檢查時再行載入
+				utils.Error("Failed to reload certificate: %+v", err)
+			} else {
+				utils.Info("Certificate %s is reloaded.", r.certFile)
+			}
+		}
+	}
+
+	return r.cert
+}
+
+// 供 tls.Config.GetCertificate 使用
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// 供 tls.Config.GetClientCertificate 使用
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// 讀取 PEM 格式的 CA 憑證，用於驗證對方的憑證(tls.Config.RootCAs 或 mTLS 的 tls.Config.ClientCAs)
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read %s", file)
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.Errorf("No certificate is found in %s", file)
+		}
+	}
+
+	return pool, nil
+}
@@ -4,6 +4,11 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/j32u4ukh/gos/utils"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -25,14 +30,20 @@ type TransData struct {
 	// 用於暫存數據，避免反覆宣告變數
 	temp1 int32
 	temp2 int32
+	// 讀取字串與 byte 陣列時的長度上限(0 表示不限制)
+	maxLength int32
+	// 第一個讀取錯誤，發生錯誤後的讀取皆返回零值，直到 Clear 或 ResetIndex
+	err error
 }
 
 func NewTransData() *TransData {
 	td := &TransData{
-		index:    0,
-		length:   0,
-		capacity: 1024,
-		order:    binary.LittleEndian,
+		index:     0,
+		length:    0,
+		capacity:  1024,
+		order:     binary.LittleEndian,
+		maxLength: utils.GosConfig.TransDataMaxLength,
+		err:       nil,
 	}
 	td.data = make([]byte, td.capacity)
 	return td
@@ -71,11 +82,28 @@ func (t *TransData) GetLength() int32 {
 
 func (t *TransData) ResetIndex() {
 	t.index = 0
+	t.err = nil
 }
 
 func (t *TransData) Clear() {
 	t.index = 0
 	t.length = 0
+	t.err = nil
+}
+
+// 設置讀取字串與 byte 陣列時的長度上限(0 表示不限制)，用於處理來自不可信任對象的數據
+func (t *TransData) SetMaxLength(length int32) {
+	t.maxLength = length
+}
+
+// 返回讀取過程中的第一個錯誤(數據不足、長度不合法等)，處理不合法的數據時，可據此拒絕並中斷連線
+func (t *TransData) Err() error {
+	return t.err
+}
+
+// 尚未讀取的數據長度
+func (t *TransData) GetRemaining() int32 {
+	return t.length - t.index
 }
 
 // ==================================================
@@ -195,6 +223,26 @@ func (t *TransData) AddByteArray(v []byte) {
 	addDatas(t, v)
 }
 
+// 以 zigzag 變長編碼寫入有號整數(絕對值越小，佔用的 byte 數越少)
+func (t *TransData) AddVarint(v int64) {
+	var buffer [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buffer[:], v)
+	addDatas(t, buffer[:n])
+}
+
+// 以變長編碼寫入無號整數
+func (t *TransData) AddUVarint(v uint64) {
+	var buffer [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buffer[:], v)
+	addDatas(t, buffer[:n])
+}
+
+// 寫入時間(秒: int64, 奈秒: int32)，時區資訊不會被保留
+func (t *TransData) AddTime(v time.Time) {
+	t.AddInt64(v.Unix())
+	t.AddInt32(int32(v.Nanosecond()))
+}
+
 // ==================================================
 // 插入數據(目前只能插在最前面)
 // ==================================================
@@ -240,8 +288,28 @@ func (t *TransData) PopBoolean() bool {
 	return b == 1
 }
 
+// 確認剩餘的數據足以讀取 n 個 byte，不足或已發生錯誤時返回 false
+func (t *TransData) readable(n int32) bool {
+	if t.err != nil {
+		return false
+	}
+
+	if n > t.length-t.index {
+		t.err = errors.Errorf("Data is too short at index %d, %d bytes required but %d remaining.", t.index, n, t.length-t.index)
+		return false
+	}
+
+	return true
+}
+
 func popNumber[T int8 | int16 | int32 | int64 | uint16 | uint32 | uint64 | float32 | float64](t *TransData, bit byte) T {
-	result := BytesToNumber[T](t.data[t.index:t.index+int32(bit)], t.order)
+	var result T
+
+	if !t.readable(int32(bit)) {
+		return result
+	}
+
+	result = BytesToNumber[T](t.data[t.index:t.index+int32(bit)], t.order)
 	t.index += int32(bit)
 	return result
 }
@@ -263,6 +331,10 @@ func (t *TransData) PopInt64() int64 {
 }
 
 func (t *TransData) PopByte() byte {
+	if !t.readable(1) {
+		return 0
+	}
+
 	result := t.data[t.index]
 	t.index += 1
 	return result
@@ -291,26 +363,151 @@ func (t *TransData) PopFloat64() float64 {
 func (t *TransData) PopJson() map[string]string {
 	result := map[string]string{}
 	bs := t.PopByteArray()
+
+	if t.err != nil {
+		return result
+	}
+
 	err := json.Unmarshal(bs, &result)
 	if err != nil {
+		t.err = errors.Wrapf(err, "Failed to unmarshal json at index %d", t.index-int32(len(bs)))
 		json.Unmarshal([]byte(fmt.Sprintf("{\"error\": \"%v\"}", err)), &result)
 	}
 	return result
 }
 
 func (t *TransData) PopString() string {
-	result := string(t.PopByteArray())
-	return result
+	t.temp1 = t.PopInt32()
+
+	if t.temp1 < 0 && t.err == nil {
+		t.err = errors.Errorf("Invalid string length %d at index %d.", t.temp1, t.index-4)
+	}
+
+	return string(t.popArray(t.temp1))
 }
 
+// 長度為 -1 時返回 nil(同 Marshal 對 nil 的編碼)
 func (t *TransData) PopByteArray() []byte {
 	t.temp1 = t.PopInt32()
-	result := make([]byte, t.temp1)
-	copy(result, t.data[t.index:t.index+t.temp1])
-	t.index += t.temp1
+
+	if t.temp1 == -1 {
+		return nil
+	}
+
+	if t.temp1 < 0 && t.err == nil {
+		t.err = errors.Errorf("Invalid byte array length %d at index %d.", t.temp1, t.index-4)
+	}
+
+	return t.popArray(t.temp1)
+}
+
+// 讀取字串或 byte 陣列的數據部分，有設置上限時，長度不可超過上限
+func (t *TransData) popArray(length int32) []byte {
+	if t.err != nil {
+		return nil
+	}
+
+	if t.maxLength > 0 && length > t.maxLength {
+		t.err = errors.Errorf("Length %d at index %d exceeds the max length %d.", length, t.index-4, t.maxLength)
+		return nil
+	}
+
+	return t.PopRawData(length)
+}
+
+// 取出 n 個 byte 的原始數據
+func (t *TransData) PopRawData(n int32) []byte {
+	if n < 0 && t.err == nil {
+		t.err = errors.Errorf("Invalid length %d at index %d.", n, t.index)
+	}
+
+	if !t.readable(n) {
+		return nil
+	}
+
+	result := make([]byte, n)
+	copy(result, t.data[t.index:t.index+n])
+	t.index += n
+	return result
+}
+
+// 讀取 slice 或 map 的元素個數(-1 表示 nil)，個數不可超過剩餘的數據量
+func (t *TransData) PopLength() int32 {
+	length := t.PopInt32()
+
+	if t.err != nil {
+		return 0
+	}
+
+	if length < -1 || length > t.length-t.index {
+		t.err = errors.Errorf("Invalid length %d at index %d.", length, t.index-4)
+		return 0
+	}
+
+	return length
+}
+
+// 讀取 zigzag 變長編碼的有號整數
+func (t *TransData) PopVarint() int64 {
+	if t.err != nil {
+		return 0
+	}
+
+	result, err := t.popVarint()
+	t.err = err
+	return result
+}
+
+// 讀取變長編碼的無號整數
+func (t *TransData) PopUVarint() uint64 {
+	if t.err != nil {
+		return 0
+	}
+
+	result, err := t.popUVarint()
+	t.err = err
 	return result
 }
 
+// 讀取時間(UTC)
+func (t *TransData) PopTime() time.Time {
+	sec := t.PopInt64()
+	nsec := t.PopInt32()
+
+	if t.err != nil {
+		return time.Time{}
+	}
+
+	if nsec < 0 || nsec >= int32(time.Second) {
+		t.err = errors.Errorf("Invalid nanosecond %d at index %d.", nsec, t.index-4)
+		return time.Time{}
+	}
+
+	return time.Unix(sec, int64(nsec)).UTC()
+}
+
+func (t *TransData) popVarint() (int64, error) {
+	result, n := binary.Varint(t.data[t.index:t.length])
+
+	if n <= 0 {
+		return 0, errors.Errorf("Invalid varint at index %d.", t.index)
+	}
+
+	t.index += int32(n)
+	return result, nil
+}
+
+func (t *TransData) popUVarint() (uint64, error) {
+	result, n := binary.Uvarint(t.data[t.index:t.length])
+
+	if n <= 0 {
+		return 0, errors.Errorf("Invalid uvarint at index %d.", t.index)
+	}
+
+	t.index += int32(n)
+	return result, nil
+}
+
 // ==================================================
 // Tools
 // ==================================================
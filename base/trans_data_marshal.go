@@ -0,0 +1,746 @@
+package base
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Marshal / Unmarshal
+// 根據結構的欄位與標籤，將結構寫入 TransData 或從 TransData 讀出
+// 標籤格式:
+// `td:"-"`: 略過該欄位(未導出的欄位也會被略過)
+// `td:"3"`: 欄位的寫入順序(由小到大)，未指定順序的欄位依宣告順序排在最後
+// `td:"3,varint"` 或 `td:",varint"`: 整數以變長編碼寫入(包含 slice, array, map, 指標中的整數，但不包含 []byte)
+// `td:",fixed"`: 整數以固定長度寫入(預設)，int 與 uint 固定以 8 bytes 寫入
+// 編碼方式:
+// string 與 []byte: int32 長度 + 數據
+// slice 與 map: int32 個數 + 各元素(nil 時個數為 -1)，map 的寫入順序不固定
+// array: 各元素(長度已由型別決定)
+// 指標: 1 byte 是否為 nil + 指向的數值
+// time.Time: 同 AddTime
+// 每個結構型別的編解碼方式只會建立一次，之後皆使用快取
+// ====================================================================================================
+
+const tagName string = "td"
+
+// 未指定順序的欄位
+const noOrder int = math.MaxInt32
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	// key: 結構型別(reflect.Type), value: *structCodec
+	structCodecs sync.Map
+	// 建立編解碼方式時上鎖，避免同時建立同一個型別
+	codecMutex sync.Mutex
+)
+
+// 由 cmd/tdgen 產生的結構會實作此介面，Marshal 與 Unmarshal 將直接使用產生的方法，而不經過反射
+type TransDataMarshaler interface {
+	MarshalTransData(t *TransData)
+	UnmarshalTransData(t *TransData) error
+}
+
+// 將結構寫入 TransData，v 須為結構或指向結構的指標
+func (t *TransData) Marshal(v any) error {
+	rv := reflect.ValueOf(v)
+
+	if m, ok := v.(TransDataMarshaler); ok && !(rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		m.MarshalTransData(t)
+		return nil
+	}
+
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return errors.New("Failed to marshal a nil pointer.")
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return errors.Errorf("Failed to marshal non-struct type %v.", rv.Type())
+	}
+
+	sc, err := getStructCodec(rv.Type())
+
+	if err != nil {
+		return err
+	}
+
+	sc.encode(t, rv)
+	return nil
+}
+
+// 從當前的讀取位置讀出結構，v 須為指向結構的指標，字串與 byte 陣列的長度上限同 SetMaxLength
+func (t *TransData) Unmarshal(v any) error {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.Errorf("Failed to unmarshal into %T, a non-nil pointer is required.", v)
+	}
+
+	if m, ok := v.(TransDataMarshaler); ok {
+		return m.UnmarshalTransData(t)
+	}
+
+	rv = rv.Elem()
+
+	if rv.Kind() != reflect.Struct {
+		return errors.Errorf("Failed to unmarshal into non-struct type %v.", rv.Type())
+	}
+
+	sc, err := getStructCodec(rv.Type())
+
+	if err != nil {
+		return err
+	}
+
+	if t.err != nil {
+		return t.err
+	}
+
+	// 同時記錄於 Err，與其他讀取方法一致
+	t.err = sc.decode(t, rv)
+	return t.err
+}
+
+// ==================================================
+// 編解碼方式
+// ==================================================
+
+type valueCodec struct {
+	encode func(t *TransData, v reflect.Value)
+	decode func(t *TransData, v reflect.Value) error
+}
+
+type fieldCodec struct {
+	*valueCodec
+	name  string
+	index int
+	order int
+}
+
+type structCodec struct {
+	fields []fieldCodec
+}
+
+func (s *structCodec) encode(t *TransData, v reflect.Value) {
+	for i := range s.fields {
+		s.fields[i].encode(t, v.Field(s.fields[i].index))
+	}
+}
+
+func (s *structCodec) decode(t *TransData, v reflect.Value) error {
+	for i := range s.fields {
+		if err := s.fields[i].decode(t, v.Field(s.fields[i].index)); err != nil {
+			return errors.Wrapf(err, "Failed to decode field %s", s.fields[i].name)
+		}
+	}
+	return nil
+}
+
+func (s *structCodec) valueCodec() *valueCodec {
+	return &valueCodec{encode: s.encode, decode: s.decode}
+}
+
+// 取得結構的編解碼方式，尚未建立時建立並快取
+func getStructCodec(typ reflect.Type) (*structCodec, error) {
+	if sc, ok := structCodecs.Load(typ); ok {
+		return sc.(*structCodec), nil
+	}
+
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+
+	// 建立過程中遇到的結構型別(全部建立成功後才加入快取)
+	building := map[reflect.Type]*structCodec{}
+	sc, err := buildStructCodec(typ, building)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for bt, bsc := range building {
+		structCodecs.Store(bt, bsc)
+	}
+
+	return sc, nil
+}
+
+func buildStructCodec(typ reflect.Type, building map[reflect.Type]*structCodec) (*structCodec, error) {
+	if sc, ok := structCodecs.Load(typ); ok {
+		return sc.(*structCodec), nil
+	}
+
+	// 遞迴型別(如鏈結串列)，欄位將在外層建立完成後補上
+	if sc, ok := building[typ]; ok {
+		return sc, nil
+	}
+
+	sc := &structCodec{fields: []fieldCodec{}}
+	building[typ] = sc
+	orders := map[int]string{}
+	var sf reflect.StructField
+	var tag string
+	var order int
+	var varint bool
+	var vc *valueCodec
+	var err error
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf = typ.Field(i)
+		tag = sf.Tag.Get(tagName)
+
+		if tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		order, varint, err = parseTag(tag)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid tag of %v.%s", typ, sf.Name)
+		}
+
+		if order != noOrder {
+			if name, ok := orders[order]; ok {
+				return nil, errors.Errorf("Field %v.%s has the same order %d as %s.", typ, sf.Name, order, name)
+			}
+			orders[order] = sf.Name
+		}
+
+		vc, err = buildCodec(sf.Type, varint, building)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to build codec of %v.%s", typ, sf.Name)
+		}
+
+		sc.fields = append(sc.fields, fieldCodec{valueCodec: vc, name: sf.Name, index: i, order: order})
+	}
+
+	sort.SliceStable(sc.fields, func(i, j int) bool {
+		return sc.fields[i].order < sc.fields[j].order
+	})
+
+	return sc, nil
+}
+
+// 解析標籤，返回欄位順序，以及整數是否以變長編碼寫入
+func parseTag(tag string) (order int, varint bool, err error) {
+	name, option, _ := strings.Cut(tag, ",")
+	order = noOrder
+
+	if name != "" {
+		order, err = strconv.Atoi(name)
+
+		if err != nil || order <= 0 || order == noOrder {
+			return 0, false, errors.Errorf("Invalid order %q.", name)
+		}
+	}
+
+	switch option {
+	case "", "fixed":
+	case "varint":
+		varint = true
+	default:
+		return 0, false, errors.Errorf("Unknown option %q.", option)
+	}
+
+	return order, varint, nil
+}
+
+func buildCodec(typ reflect.Type, varint bool, building map[reflect.Type]*structCodec) (*valueCodec, error) {
+	if typ == timeType {
+		return timeCodec, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return boolCodec, nil
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if varint {
+			return varintCodec, nil
+		}
+		return fixedIntCodec(int32(typ.Size()), true), nil
+
+	case reflect.Int:
+		if varint {
+			return varintCodec, nil
+		}
+		return fixedIntCodec(8, true), nil
+
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if varint {
+			return uvarintCodec, nil
+		}
+		return fixedIntCodec(int32(typ.Size()), false), nil
+
+	case reflect.Uint, reflect.Uintptr:
+		if varint {
+			return uvarintCodec, nil
+		}
+		return fixedIntCodec(8, false), nil
+
+	case reflect.Float32:
+		return float32Codec, nil
+
+	case reflect.Float64:
+		return float64Codec, nil
+
+	case reflect.String:
+		return stringCodec, nil
+
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return bytesCodec, nil
+		}
+
+		elem, err := buildCodec(typ.Elem(), varint, building)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return sliceCodec(typ, elem), nil
+
+	case reflect.Array:
+		elem, err := buildCodec(typ.Elem(), varint, building)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return arrayCodec(elem), nil
+
+	case reflect.Map:
+		key, err := buildCodec(typ.Key(), varint, building)
+
+		if err != nil {
+			return nil, err
+		}
+
+		elem, err := buildCodec(typ.Elem(), varint, building)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return mapCodec(typ, key, elem), nil
+
+	case reflect.Pointer:
+		elem, err := buildCodec(typ.Elem(), varint, building)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return pointerCodec(typ, elem), nil
+
+	case reflect.Struct:
+		sc, err := buildStructCodec(typ, building)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return sc.valueCodec(), nil
+	}
+
+	return nil, errors.Errorf("Unsupported type %v.", typ)
+}
+
+// ==================================================
+// 各型別的編解碼
+// ==================================================
+
+// 預留 n 個 byte 並返回該區段，供直接寫入數據
+func (t *TransData) reserve(n int32) []byte {
+	if t.index+n >= t.capacity {
+		t.SetCapacity(t.index + n)
+	}
+
+	result := t.data[t.index : t.index+n]
+	t.index += n
+	t.length += n
+	return result
+}
+
+// 以固定長度(1, 2, 4, 8 bytes)寫入整數
+func (t *TransData) putUint(v uint64, size int32) {
+	b := t.reserve(size)
+
+	switch size {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		t.order.PutUint16(b, uint16(v))
+	case 4:
+		t.order.PutUint32(b, uint32(v))
+	default:
+		t.order.PutUint64(b, v)
+	}
+}
+
+// 讀取固定長度(1, 2, 4, 8 bytes)的整數
+func (t *TransData) popUint(size int32) (uint64, error) {
+	if !t.readable(size) {
+		return 0, t.err
+	}
+
+	b := t.data[t.index : t.index+size]
+	t.index += size
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(t.order.Uint16(b)), nil
+	case 4:
+		return uint64(t.order.Uint32(b)), nil
+	default:
+		return t.order.Uint64(b), nil
+	}
+}
+
+// 寫入 int32 長度(nil 時為 -1)
+func (t *TransData) putLength(length int, isNil bool) {
+	if isNil {
+		length = -1
+	}
+	t.putUint(uint64(uint32(int32(length))), 4)
+}
+
+var boolCodec = &valueCodec{
+	encode: func(t *TransData, v reflect.Value) {
+		t.AddBoolean(v.Bool())
+	},
+	decode: func(t *TransData, v reflect.Value) error {
+		u, err := t.popUint(1)
+
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(u == 1)
+		return nil
+	},
+}
+
+func fixedIntCodec(size int32, signed bool) *valueCodec {
+	if signed {
+		return &valueCodec{
+			encode: func(t *TransData, v reflect.Value) {
+				t.putUint(uint64(v.Int()), size)
+			},
+			decode: func(t *TransData, v reflect.Value) error {
+				u, err := t.popUint(size)
+
+				if err != nil {
+					return err
+				}
+
+				// 符號擴展
+				shift := 64 - 8*size
+				v.SetInt(int64(u<<shift) >> shift)
+				return nil
+			},
+		}
+	}
+
+	return &valueCodec{
+		encode: func(t *TransData, v reflect.Value) {
+			t.putUint(v.Uint(), size)
+		},
+		decode: func(t *TransData, v reflect.Value) error {
+			u, err := t.popUint(size)
+
+			if err != nil {
+				return err
+			}
+
+			v.SetUint(u)
+			return nil
+		},
+	}
+}
+
+var varintCodec = &valueCodec{
+	encode: func(t *TransData, v reflect.Value) {
+		t.AddVarint(v.Int())
+	},
+	decode: func(t *TransData, v reflect.Value) error {
+		i := t.PopVarint()
+
+		if t.err != nil {
+			return t.err
+		}
+
+		if v.OverflowInt(i) {
+			return errors.Errorf("Varint %d overflows %v.", i, v.Type())
+		}
+
+		v.SetInt(i)
+		return nil
+	},
+}
+
+var uvarintCodec = &valueCodec{
+	encode: func(t *TransData, v reflect.Value) {
+		t.AddUVarint(v.Uint())
+	},
+	decode: func(t *TransData, v reflect.Value) error {
+		u := t.PopUVarint()
+
+		if t.err != nil {
+			return t.err
+		}
+
+		if v.OverflowUint(u) {
+			return errors.Errorf("Uvarint %d overflows %v.", u, v.Type())
+		}
+
+		v.SetUint(u)
+		return nil
+	},
+}
+
+var float32Codec = &valueCodec{
+	encode: func(t *TransData, v reflect.Value) {
+		t.putUint(uint64(math.Float32bits(float32(v.Float()))), 4)
+	},
+	decode: func(t *TransData, v reflect.Value) error {
+		u, err := t.popUint(4)
+
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(float64(math.Float32frombits(uint32(u))))
+		return nil
+	},
+}
+
+var float64Codec = &valueCodec{
+	encode: func(t *TransData, v reflect.Value) {
+		t.putUint(math.Float64bits(v.Float()), 8)
+	},
+	decode: func(t *TransData, v reflect.Value) error {
+		u, err := t.popUint(8)
+
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(math.Float64frombits(u))
+		return nil
+	},
+}
+
+var stringCodec = &valueCodec{
+	encode: func(t *TransData, v reflect.Value) {
+		s := v.String()
+		t.putLength(len(s), false)
+		copy(t.reserve(int32(len(s))), s)
+	},
+	decode: func(t *TransData, v reflect.Value) error {
+		s := t.PopString()
+
+		if t.err != nil {
+			return t.err
+		}
+
+		v.SetString(s)
+		return nil
+	},
+}
+
+var bytesCodec = &valueCodec{
+	encode: func(t *TransData, v reflect.Value) {
+		b := v.Bytes()
+		t.putLength(len(b), v.IsNil())
+		addDatas(t, b)
+	},
+	decode: func(t *TransData, v reflect.Value) error {
+		b := t.PopByteArray()
+
+		if t.err != nil {
+			return t.err
+		}
+
+		if b == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		v.SetBytes(b)
+		return nil
+	},
+}
+
+var timeCodec = &valueCodec{
+	encode: func(t *TransData, v reflect.Value) {
+		tm := v.Interface().(time.Time)
+		t.putUint(uint64(tm.Unix()), 8)
+		t.putUint(uint64(tm.Nanosecond()), 4)
+	},
+	decode: func(t *TransData, v reflect.Value) error {
+		tm := t.PopTime()
+
+		if t.err != nil {
+			return t.err
+		}
+
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	},
+}
+
+func sliceCodec(typ reflect.Type, elem *valueCodec) *valueCodec {
+	return &valueCodec{
+		encode: func(t *TransData, v reflect.Value) {
+			length := v.Len()
+			t.putLength(length, v.IsNil())
+
+			for i := 0; i < length; i++ {
+				elem.encode(t, v.Index(i))
+			}
+		},
+		decode: func(t *TransData, v reflect.Value) error {
+			length := int(t.PopLength())
+
+			if t.err != nil {
+				return t.err
+			}
+
+			if length < 0 {
+				v.Set(reflect.Zero(typ))
+				return nil
+			}
+
+			var err error
+
+			s := reflect.MakeSlice(typ, length, length)
+
+			for i := 0; i < length; i++ {
+				if err = elem.decode(t, s.Index(i)); err != nil {
+					return errors.Wrapf(err, "Failed to decode element %d", i)
+				}
+			}
+
+			v.Set(s)
+			return nil
+		},
+	}
+}
+
+func arrayCodec(elem *valueCodec) *valueCodec {
+	return &valueCodec{
+		encode: func(t *TransData, v reflect.Value) {
+			for i := 0; i < v.Len(); i++ {
+				elem.encode(t, v.Index(i))
+			}
+		},
+		decode: func(t *TransData, v reflect.Value) error {
+			for i := 0; i < v.Len(); i++ {
+				if err := elem.decode(t, v.Index(i)); err != nil {
+					return errors.Wrapf(err, "Failed to decode element %d", i)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func mapCodec(typ reflect.Type, key *valueCodec, elem *valueCodec) *valueCodec {
+	return &valueCodec{
+		encode: func(t *TransData, v reflect.Value) {
+			t.putLength(v.Len(), v.IsNil())
+			iter := v.MapRange()
+
+			for iter.Next() {
+				key.encode(t, iter.Key())
+				elem.encode(t, iter.Value())
+			}
+		},
+		decode: func(t *TransData, v reflect.Value) error {
+			length := int(t.PopLength())
+
+			if t.err != nil {
+				return t.err
+			}
+
+			if length < 0 {
+				v.Set(reflect.Zero(typ))
+				return nil
+			}
+
+			var err error
+
+			m := reflect.MakeMapWithSize(typ, length)
+
+			for i := 0; i < length; i++ {
+				k := reflect.New(typ.Key()).Elem()
+
+				if err = key.decode(t, k); err != nil {
+					return errors.Wrapf(err, "Failed to decode key %d", i)
+				}
+
+				e := reflect.New(typ.Elem()).Elem()
+
+				if err = elem.decode(t, e); err != nil {
+					return errors.Wrapf(err, "Failed to decode value of %v", k)
+				}
+
+				m.SetMapIndex(k, e)
+			}
+
+			v.Set(m)
+			return nil
+		},
+	}
+}
+
+func pointerCodec(typ reflect.Type, elem *valueCodec) *valueCodec {
+	return &valueCodec{
+		encode: func(t *TransData, v reflect.Value) {
+			if v.IsNil() {
+				addData(t, 0)
+				return
+			}
+
+			addData(t, 1)
+			elem.encode(t, v.Elem())
+		},
+		decode: func(t *TransData, v reflect.Value) error {
+			flag, err := t.popUint(1)
+
+			if err != nil {
+				return err
+			}
+
+			switch flag {
+			case 0:
+				v.Set(reflect.Zero(typ))
+				return nil
+			case 1:
+				p := reflect.New(typ.Elem())
+
+				if err = elem.decode(t, p.Elem()); err != nil {
+					return err
+				}
+
+				v.Set(p)
+				return nil
+			default:
+				return errors.Errorf("Invalid pointer flag %d.", flag)
+			}
+		},
+	}
+}
@@ -0,0 +1,677 @@
+package base
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Udp
+// UDP 沒有連線，UdpListener 根據對方的位置建立 UdpConn 作為虛擬連線，使其可如 TCP 連線般由 Anser 管理(取得 cid、讀取超時)
+// UdpConn 讀取時於每個 datagram 前加上 2 bytes 的長度，寫出時再依長度拆分回 datagram，使 Conn 的串流緩存保留 datagram 的邊界
+// ====================================================================================================
+
+// datagram 種類(datagram 的第 1 個 byte)
+const (
+	// 不保證送達與順序的訊息
+	UdpUnreliable byte = iota
+	// 保證送達與順序的訊息(後接 4 bytes 序號)
+	UdpReliable
+	// 可靠訊息的確認(後接 4 bytes 序號)
+	UdpAck
+	// 維持連線
+	UdpPing
+	UdpPong
+)
+
+// datagram 的長度上限(須小於 Conn 的緩衝長度，避免一個 datagram 無法放入讀取緩存)
+const UdpMaxDatagramSize int = 8 * 1024
+
+// 可靠訊息提前到達或尚未確認的數量上限
+const UdpWindowSize uint32 = 1024
+
+// 長度前綴
+const udpLengthSize int = 2
+
+// 種類 + 序號
+const udpSeqHeaderSize int = 5
+
+// 每個虛擬連線可暫存的 datagram 數量，超過時丟棄
+const udpRecvBuffer int = 64
+
+// ====================================================================================================
+// UdpConn
+// ====================================================================================================
+
+type UdpConn struct {
+	laddr net.Addr
+	raddr net.Addr
+	// 寫出 datagram
+	writeTo func(data []byte) (int, error)
+	// 關閉時呼叫(從 UdpListener 中移除，或關閉客戶端的 socket)
+	onClose func()
+	// 收到的 datagram
+	recvCh chan []byte
+	// 讀取時間上限變更時通知讀取中的 Read
+	deadlineCh chan bool
+	closeCh    chan bool
+	closeOnce  sync.Once
+	mu         sync.Mutex
+	// 讀取時間上限
+	readDeadline time.Time
+	// 關閉的原因
+	err error
+	// 讀取中的 datagram(含長度前綴)，以及已讀取的位置
+	reading []byte
+	readIdx int
+	// 尚未湊滿一個 datagram 的寫出數據
+	writing []byte
+}
+
+func newUdpConn(laddr net.Addr, raddr net.Addr) *UdpConn {
+	c := &UdpConn{
+		laddr:      laddr,
+		raddr:      raddr,
+		recvCh:     make(chan []byte, udpRecvBuffer),
+		deadlineCh: make(chan bool, 1),
+		closeCh:    make(chan bool),
+		reading:    make([]byte, 0, UdpMaxDatagramSize+udpLengthSize),
+		writing:    []byte{},
+	}
+	return c
+}
+
+// 將連線位置轉換為 UDP 位置(Listen 與 Bind 以 TCP 位置指定 ip 與 port)
+func ToUdpAddr(addr net.Addr) (*net.UDPAddr, error) {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a, nil
+	case *net.TCPAddr:
+		return &net.UDPAddr{IP: a.IP, Port: a.Port, Zone: a.Zone}, nil
+	default:
+		return nil, errors.Errorf("Udp does not support %s address %s.", addr.Network(), addr)
+	}
+}
+
+// 以 UDP 連線至 raddr(客戶端)，返回的 UdpConn 讀寫方式與 UdpListener 所建立的虛擬連線相同
+func DialUdp(raddr *net.UDPAddr) (*UdpConn, error) {
+	conn, err := net.DialUDP("udp", nil, raddr)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to dial udp %s.", raddr)
+	}
+
+	c := newUdpConn(conn.LocalAddr(), conn.RemoteAddr())
+	c.writeTo = conn.Write
+	c.onClose = func() {
+		conn.Close()
+	}
+
+	go func() {
+		buffer := make([]byte, 65536)
+
+		for {
+			n, err := conn.Read(buffer)
+
+			if err != nil {
+				// 如對方的 port 未開啟(ICMP port unreachable)，或連線已關閉
+				c.close(err)
+				return
+			}
+
+			c.receive(buffer[:n])
+		}
+	}()
+
+	return c, nil
+}
+
+// 收到 datagram(由讀取 socket 的 goroutine 呼叫)，緩存已滿或過大時丟棄
+func (c *UdpConn) receive(data []byte) {
+	if len(data) > UdpMaxDatagramSize {
+		utils.Warn("Datagram from %s is too large: %d", c.raddr, len(data))
+		return
+	}
+
+	datagram := make([]byte, len(data))
+	copy(datagram, data)
+
+	select {
+	case c.recvCh <- datagram:
+	default:
+		utils.Warn("Datagram from %s is dropped, buffer is full.", c.raddr)
+	}
+}
+
+func (c *UdpConn) Read(p []byte) (int, error) {
+	if c.readIdx >= len(c.reading) {
+		datagram, err := c.next()
+
+		if err != nil {
+			return 0, err
+		}
+
+		c.reading = c.reading[:udpLengthSize+len(datagram)]
+		binary.LittleEndian.PutUint16(c.reading, uint16(len(datagram)))
+		copy(c.reading[udpLengthSize:], datagram)
+		c.readIdx = 0
+	}
+
+	n := copy(p, c.reading[c.readIdx:])
+	c.readIdx += n
+	return n, nil
+}
+
+// 等待下一個 datagram，直到讀取時間上限或連線關閉
+func (c *UdpConn) next() ([]byte, error) {
+	for {
+		c.mu.Lock()
+		deadline := c.readDeadline
+		c.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+
+			if d <= 0 {
+				return nil, os.ErrDeadlineExceeded
+			}
+
+			timer = time.NewTimer(d)
+			timeout = timer.C
+		}
+
+		select {
+		case datagram := <-c.recvCh:
+			if timer != nil {
+				timer.Stop()
+			}
+			return datagram, nil
+
+		case <-c.closeCh:
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, c.err
+
+		case <-c.deadlineCh:
+			// 讀取時間上限已變更，重新計算
+			if timer != nil {
+				timer.Stop()
+			}
+
+		case <-timeout:
+			return nil, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// 寫入以長度前綴分隔的 datagram，湊滿一個 datagram 才寫出
+func (c *UdpConn) Write(p []byte) (int, error) {
+	c.writing = append(c.writing, p...)
+	idx := 0
+
+	for len(c.writing)-idx >= udpLengthSize {
+		length := int(binary.LittleEndian.Uint16(c.writing[idx:]))
+
+		if len(c.writing)-idx-udpLengthSize < length {
+			break
+		}
+
+		idx += udpLengthSize
+
+		if _, err := c.writeTo(c.writing[idx : idx+length]); err != nil {
+			c.writing = c.writing[:0]
+			return 0, errors.Wrapf(err, "Failed to write datagram to %s.", c.raddr)
+		}
+
+		idx += length
+	}
+
+	c.writing = c.writing[:copy(c.writing, c.writing[idx:])]
+	return len(p), nil
+}
+
+func (c *UdpConn) Close() error {
+	c.close(net.ErrClosed)
+	return nil
+}
+
+func (c *UdpConn) close(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.closeCh)
+
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+}
+
+func (c *UdpConn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+func (c *UdpConn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+func (c *UdpConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *UdpConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+
+	select {
+	case c.deadlineCh <- true:
+	default:
+	}
+	return nil
+}
+
+// UDP 寫出不會阻塞，不支援寫出時間上限
+func (c *UdpConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// ====================================================================================================
+// UdpListener
+// ====================================================================================================
+
+type UdpListener struct {
+	conn *net.UDPConn
+	mu   sync.Mutex
+	// 以對方的位置管理虛擬連線
+	peers map[string]*UdpConn
+	// 虛擬連線數上限(達上限時，忽略未知位置的 datagram)
+	maxPeers int
+	acceptCh chan *UdpConn
+	closeCh  chan bool
+	once     sync.Once
+}
+
+// 監聽 laddr，收到未知位置的 datagram 時，建立新的虛擬連線(可由 Accept 取得)
+// 虛擬連線(包含尚未被 Accept 的)最多 maxPeers 個，避免偽造的來源位置使記憶體無限制地增長
+func ListenUdp(laddr *net.UDPAddr, backlog int, maxPeers int) (*UdpListener, error) {
+	conn, err := net.ListenUDP("udp", laddr)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to listen udp at port %d.", laddr.Port)
+	}
+
+	l := &UdpListener{
+		conn:     conn,
+		peers:    map[string]*UdpConn{},
+		maxPeers: maxPeers,
+		acceptCh: make(chan *UdpConn, backlog),
+		closeCh:  make(chan bool),
+	}
+
+	go l.serve()
+	return l, nil
+}
+
+func (l *UdpListener) serve() {
+	buffer := make([]byte, 65536)
+
+	for {
+		n, addr, err := l.conn.ReadFromUDP(buffer)
+
+		if err != nil {
+			select {
+			case <-l.closeCh:
+				return
+			default:
+				utils.Error("Failed to read udp: %+v", err)
+				continue
+			}
+		}
+
+		key := addr.String()
+		l.mu.Lock()
+		peer, ok := l.peers[key]
+
+		if !ok && len(l.peers) >= l.maxPeers {
+			l.mu.Unlock()
+			utils.Warn("Too many udp peers(%d), %s is dropped.", l.maxPeers, key)
+			continue
+		}
+
+		if !ok {
+			peer = newUdpConn(l.conn.LocalAddr(), addr)
+			peer.writeTo = func(data []byte) (int, error) {
+				return l.conn.WriteToUDP(data, addr)
+			}
+			peer.onClose = func() {
+				l.remove(key)
+			}
+			l.peers[key] = peer
+		}
+
+		l.mu.Unlock()
+
+		if !ok {
+			select {
+			case l.acceptCh <- peer:
+			default:
+				utils.Warn("Too many pending udp peers, %s is dropped.", key)
+				peer.Close()
+				continue
+			}
+		}
+
+		peer.receive(buffer[:n])
+	}
+}
+
+func (l *UdpListener) remove(key string) {
+	l.mu.Lock()
+	delete(l.peers, key)
+	l.mu.Unlock()
+}
+
+func (l *UdpListener) Accept() (net.Conn, error) {
+	select {
+	case peer := <-l.acceptCh:
+		return peer, nil
+	case <-l.closeCh:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *UdpListener) Close() error {
+	var err error
+
+	l.once.Do(func() {
+		close(l.closeCh)
+		err = l.conn.Close()
+	})
+
+	return err
+}
+
+func (l *UdpListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// 當前的虛擬連線數
+func (l *UdpListener) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.peers)
+}
+
+// ====================================================================================================
+// Udp 協定狀態
+// 每個連線各自的 datagram 讀取狀態，以及可靠訊息的序號、確認、重送與排序
+// ====================================================================================================
+
+// 尚未確認的可靠訊息
+type udpPending struct {
+	seq      uint32
+	frame    []byte
+	sentTime time.Time
+	nResend  int32
+}
+
+type udpMessage struct {
+	payload  []byte
+	reliable bool
+}
+
+type Udp struct {
+	// 讀取狀態值 | 0: 讀取 datagram 長度, 1: 根據前一階段取得的長度，讀取 datagram
+	State      int8
+	ReadLength int32
+	// 最後交付的訊息是否為可靠訊息，回應時沿用
+	Reliable bool
+	// 未確認的可靠訊息重送間隔
+	ResendInterval time.Duration
+	// 重送次數上限，超過時視為斷線
+	MaxResends int32
+
+	// ========== 送出 ==========
+	sendSeq  uint32
+	pendings []*udpPending
+
+	// ========== 接收 ==========
+	// 下一個應交付的可靠訊息序號
+	recvSeq uint32
+	// 提前到達的可靠訊息
+	early map[uint32][]byte
+	// 待交付的訊息(工作結構不足時保留至下次迴圈)
+	messages []*udpMessage
+}
+
+func NewUdp() *Udp {
+	u := &Udp{
+		ResendInterval: utils.GosConfig.UdpResendInterval,
+		MaxResends:     utils.GosConfig.UdpMaxResends,
+		pendings:       []*udpPending{},
+		early:          map[uint32][]byte{},
+		messages:       []*udpMessage{},
+	}
+	u.Reset()
+	return u
+}
+
+// 連線中斷或重新連線時，重置所有狀態
+func (u *Udp) Reset() {
+	u.State = 0
+	u.ReadLength = int32(udpLengthSize)
+	u.Reliable = false
+	u.sendSeq = 0
+	u.pendings = u.pendings[:0]
+	u.recvSeq = 0
+	u.messages = u.messages[:0]
+
+	for seq := range u.early {
+		delete(u.early, seq)
+	}
+}
+
+// 檢查是否滿足：可讀長度 大於 欲讀取長度
+func (u *Udp) ReadableChecker(buffer *[]byte, i int32, o int32, length int32) bool {
+	return length >= u.ReadLength
+}
+
+// 從 c 的讀取緩存中讀取一個 datagram，返回的數據於下次讀取前有效
+func (u *Udp) ReadDatagram(c *Conn, buffer *[]byte) ([]byte, bool) {
+	for c.CheckReadable(u.ReadableChecker) {
+		if u.State == 0 {
+			c.Read(buffer, int32(udpLengthSize))
+			u.ReadLength = int32(binary.LittleEndian.Uint16((*buffer)[:udpLengthSize]))
+			u.State = 1
+		} else {
+			length := u.ReadLength
+			c.Read(buffer, length)
+			u.State = 0
+			u.ReadLength = int32(udpLengthSize)
+			return (*buffer)[:length], true
+		}
+	}
+	return nil, false
+}
+
+// 處理收到的 datagram，可交付的訊息保存至 PopMessage 取出，返回需要回應的 datagram(確認或 Pong)
+func (u *Udp) Handle(datagram []byte) ([]byte, error) {
+	if len(datagram) == 0 {
+		return nil, errors.New("Empty datagram.")
+	}
+
+	kind := datagram[0]
+
+	switch kind {
+	case UdpUnreliable:
+		u.push(datagram[1:], false)
+		return nil, nil
+
+	case UdpReliable, UdpAck:
+		if len(datagram) < udpSeqHeaderSize {
+			return nil, errors.Errorf("Invalid datagram length %d of kind %d.", len(datagram), kind)
+		}
+
+		seq := binary.LittleEndian.Uint32(datagram[1:udpSeqHeaderSize])
+
+		if kind == UdpAck {
+			u.ack(seq)
+			return nil, nil
+		}
+
+		return u.receive(seq, datagram[udpSeqHeaderSize:])
+
+	case UdpPing:
+		return EncodeUdpDatagram(UdpPong, 0, nil), nil
+
+	case UdpPong:
+		return nil, nil
+
+	default:
+		return nil, errors.Errorf("Invalid datagram kind %d.", kind)
+	}
+}
+
+// 依序號交付可靠訊息，重複的訊息只回應確認，超出範圍的訊息直接丟棄(由對方重送)
+func (u *Udp) receive(seq uint32, payload []byte) ([]byte, error) {
+	diff := seq - u.recvSeq
+
+	// 已交付過的訊息(確認可能遺失)
+	if int32(diff) < 0 {
+		return EncodeUdpDatagram(UdpAck, seq, nil), nil
+	}
+
+	if diff >= UdpWindowSize {
+		return nil, errors.Errorf("Reliable seq %d is out of window, expected: %d.", seq, u.recvSeq)
+	}
+
+	if diff == 0 {
+		u.push(payload, true)
+		u.recvSeq++
+
+		// 交付因順序而暫存的訊息
+		for {
+			data, ok := u.early[u.recvSeq]
+
+			if !ok {
+				break
+			}
+
+			delete(u.early, u.recvSeq)
+			u.messages = append(u.messages, &udpMessage{payload: data, reliable: true})
+			u.recvSeq++
+		}
+
+	} else if _, ok := u.early[seq]; !ok {
+		data := make([]byte, len(payload))
+		copy(data, payload)
+		u.early[seq] = data
+	}
+
+	return EncodeUdpDatagram(UdpAck, seq, nil), nil
+}
+
+func (u *Udp) push(payload []byte, reliable bool) {
+	data := make([]byte, len(payload))
+	copy(data, payload)
+	u.messages = append(u.messages, &udpMessage{payload: data, reliable: reliable})
+}
+
+func (u *Udp) ack(seq uint32) {
+	for i, pending := range u.pendings {
+		if pending.seq == seq {
+			u.pendings = append(u.pendings[:i], u.pendings[i+1:]...)
+			return
+		}
+	}
+}
+
+// 是否有待交付的訊息
+func (u *Udp) HasMessage() bool {
+	return len(u.messages) > 0
+}
+
+// 取出下一個待交付的訊息
+func (u *Udp) PopMessage() (payload []byte, reliable bool) {
+	message := u.messages[0]
+	u.messages[0] = nil
+	u.messages = u.messages[1:]
+	u.Reliable = message.reliable
+	return message.payload, message.reliable
+}
+
+// 尚未確認的可靠訊息數量
+func (u *Udp) Unacked() int {
+	return len(u.pendings)
+}
+
+// 將訊息編碼為 datagram(含長度前綴)，可靠訊息將保存至確認為止
+func (u *Udp) Encode(payload []byte, reliable bool) ([]byte, error) {
+	if len(payload)+udpSeqHeaderSize > UdpMaxDatagramSize {
+		return nil, errors.Errorf("Message length %d exceeds %d.", len(payload), UdpMaxDatagramSize-udpSeqHeaderSize)
+	}
+
+	if !reliable {
+		return EncodeUdpDatagram(UdpUnreliable, 0, payload), nil
+	}
+
+	if uint32(len(u.pendings)) >= UdpWindowSize {
+		return nil, errors.Errorf("Too many unacknowledged messages: %d.", len(u.pendings))
+	}
+
+	frame := EncodeUdpDatagram(UdpReliable, u.sendSeq, payload)
+	u.pendings = append(u.pendings, &udpPending{seq: u.sendSeq, frame: frame, sentTime: time.Now()})
+	u.sendSeq++
+	return frame, nil
+}
+
+// 返回超過重送間隔仍未確認的可靠訊息，超過重送次數上限時返回錯誤
+func (u *Udp) Resend(now time.Time) ([][]byte, error) {
+	var frames [][]byte
+
+	for _, pending := range u.pendings {
+		if now.Sub(pending.sentTime) < u.ResendInterval {
+			continue
+		}
+
+		if pending.nResend >= u.MaxResends {
+			return nil, errors.Errorf("Reliable message %d is not acknowledged after %d resends.", pending.seq, pending.nResend)
+		}
+
+		pending.nResend++
+		pending.sentTime = now
+		frames = append(frames, pending.frame)
+	}
+
+	return frames, nil
+}
+
+// 編碼 datagram(含長度前綴)，seq 只用於 UdpReliable 與 UdpAck
+func EncodeUdpDatagram(kind byte, seq uint32, payload []byte) []byte {
+	size := 1
+
+	if kind == UdpReliable || kind == UdpAck {
+		size = udpSeqHeaderSize
+	}
+
+	frame := make([]byte, udpLengthSize+size+len(payload))
+	binary.LittleEndian.PutUint16(frame, uint16(size+len(payload)))
+	frame[udpLengthSize] = kind
+
+	if size == udpSeqHeaderSize {
+		binary.LittleEndian.PutUint32(frame[udpLengthSize+1:], seq)
+	}
+
+	copy(frame[udpLengthSize+size:], payload)
+	return frame
+}
@@ -0,0 +1,84 @@
+package base
+
+import (
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ====================================================================================================
+// Unix
+// 以 Unix domain socket(檔案路徑)作為連線位置，供同一台主機上的服務互相連線，數據的讀寫方式與 TCP 連線相同
+// 路徑以 @ 開頭時為 Linux 的 abstract socket，不會建立檔案
+// ====================================================================================================
+
+// 確認 socket 檔案是否仍有人監聽的時間上限
+const unixDialTimeout time.Duration = time.Second
+
+// 監聽 laddr，並將 socket 檔案的權限設為 mode，返回的 listener 關閉時將一併移除 socket 檔案
+// 若 socket 檔案已存在且無人監聽(前次未正常關閉所遺留)，則先移除；若已存在的不是 socket，或仍有人監聽，則返回錯誤
+func ListenUnix(laddr *net.UnixAddr, mode os.FileMode) (*net.UnixListener, error) {
+	if laddr == nil || laddr.Name == "" {
+		return nil, errors.New("Unix socket path is empty.")
+	}
+
+	abstract := strings.HasPrefix(laddr.Name, "@")
+
+	if !abstract {
+		if err := removeStaleUnixSocket(laddr.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.ListenUnix("unix", laddr)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to listen at %s.", laddr.Name)
+	}
+
+	if !abstract {
+		if err = os.Chmod(laddr.Name, mode); err != nil {
+			listener.Close()
+			return nil, errors.Wrapf(err, "Failed to change mode of %s to %v.", laddr.Name, mode)
+		}
+	}
+
+	return listener, nil
+}
+
+// 以 Unix domain socket 連線至 raddr
+func DialUnix(raddr *net.UnixAddr) (*net.UnixConn, error) {
+	return net.DialUnix("unix", nil, raddr)
+}
+
+// 移除無人監聽的 socket 檔案
+func removeStaleUnixSocket(path string) error {
+	info, err := os.Lstat(path)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "Failed to stat %s.", path)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return errors.Errorf("%s already exists and is not a socket.", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, unixDialTimeout)
+
+	if err == nil {
+		conn.Close()
+		return errors.Errorf("%s is already in use.", path)
+	}
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "Failed to remove stale socket %s.", path)
+	}
+
+	return nil
+}
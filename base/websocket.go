@@ -0,0 +1,432 @@
+package base
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ====================================================================================================
+// WebSocket(RFC 6455)
+// ====================================================================================================
+
+// 幀的類型
+const (
+	WsContinuation byte = 0x0
+	WsText         byte = 0x1
+	WsBinary       byte = 0x2
+	WsClose        byte = 0x8
+	WsPing         byte = 0x9
+	WsPong         byte = 0xA
+)
+
+// 關閉連線的狀態碼
+const (
+	CloseNormalClosure    uint16 = 1000
+	CloseGoingAway        uint16 = 1001
+	CloseProtocolError    uint16 = 1002
+	CloseUnsupportedData  uint16 = 1003
+	CloseNoStatusReceived uint16 = 1005
+	CloseAbnormalClosure  uint16 = 1006
+	CloseInvalidPayload   uint16 = 1007
+	ClosePolicyViolation  uint16 = 1008
+	CloseMessageTooBig    uint16 = 1009
+	CloseInternalError    uint16 = 1011
+)
+
+// 計算 Sec-WebSocket-Accept 所使用的 GUID
+const webSocketGUID string = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// 控制幀的 Payload 長度上限
+const maxControlPayload int64 = 125
+
+// 違反協定的錯誤，Code 為關閉連線時所使用的狀態碼
+type WebSocketError struct {
+	Code    uint16
+	Message string
+}
+
+func (e *WebSocketError) Error() string {
+	return fmt.Sprintf("WebSocket error(%d): %s", e.Code, e.Message)
+}
+
+func wsError(code uint16, format string, args ...any) *WebSocketError {
+	return &WebSocketError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// 根據 Sec-WebSocket-Key 計算 Sec-WebSocket-Accept
+func WebSocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// 生成隨機的 Sec-WebSocket-Key
+func NewWebSocketKey() string {
+	key := make([]byte, 16)
+	rand.Read(key)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// 檢查 Sec-WebSocket-Key 是否為 16 bytes 數據的 base64 編碼
+func IsValidWebSocketKey(key string) bool {
+	data, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(data) == 16
+}
+
+// 將 payload 編碼為單一幀(FIN = 1)，客戶端送出的幀須以隨機的遮罩加密
+func EncodeWebSocketFrame(opcode byte, payload []byte, mask bool) []byte {
+	length := len(payload)
+	size := 2 + length
+	var b1 byte
+
+	switch {
+	case length <= int(maxControlPayload):
+		b1 = byte(length)
+	case length <= 0xFFFF:
+		b1 = 126
+		size += 2
+	default:
+		b1 = 127
+		size += 8
+	}
+
+	if mask {
+		b1 |= 0x80
+		size += 4
+	}
+
+	frame := make([]byte, size)
+	frame[0] = 0x80 | opcode
+	frame[1] = b1
+	idx := 2
+
+	switch b1 & 0x7F {
+	case 126:
+		binary.BigEndian.PutUint16(frame[idx:], uint16(length))
+		idx += 2
+	case 127:
+		binary.BigEndian.PutUint64(frame[idx:], uint64(length))
+		idx += 8
+	}
+
+	if mask {
+		maskKey := frame[idx : idx+4]
+		rand.Read(maskKey)
+		idx += 4
+
+		for i := 0; i < length; i++ {
+			frame[idx+i] = payload[i] ^ maskKey[i%4]
+		}
+	} else {
+		copy(frame[idx:], payload)
+	}
+
+	return frame
+}
+
+// 生成關閉幀的 Payload(狀態碼 + 原因)
+func EncodeClosePayload(code uint16, reason string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+
+	// 控制幀的 Payload 長度上限為 125 bytes
+	if len(reason) > int(maxControlPayload)-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, code)
+	copy(payload[2:], reason)
+	return payload
+}
+
+// 解析關閉幀的 Payload，沒有狀態碼時返回 CloseNoStatusReceived
+func ParseClosePayload(payload []byte) (uint16, string, error) {
+	if len(payload) == 0 {
+		return CloseNoStatusReceived, "", nil
+	}
+
+	if len(payload) == 1 {
+		return 0, "", wsError(CloseProtocolError, "Invalid close payload length 1.")
+	}
+
+	code := binary.BigEndian.Uint16(payload)
+
+	if !isValidCloseCode(code) {
+		return 0, "", wsError(CloseProtocolError, "Invalid close code %d.", code)
+	}
+
+	if !utf8.Valid(payload[2:]) {
+		return 0, "", wsError(CloseInvalidPayload, "Close reason is not valid UTF-8.")
+	}
+
+	return code, string(payload[2:]), nil
+}
+
+// 可出現於關閉幀的狀態碼(1005, 1006 等僅供本地使用)
+func isValidCloseCode(code uint16) bool {
+	switch {
+	case code >= 1000 && code <= 1003:
+		return true
+	case code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+type WebSocketState int8
+
+const (
+	// 等待 HTTP 升級交握
+	WS_HANDSHAKE WebSocketState = iota
+	// 讀取幀的前 2 bytes
+	WS_FRAME_HEADER
+	// 讀取延伸長度與遮罩
+	WS_FRAME_EXTENDED
+	// 讀取 Payload
+	WS_FRAME_PAYLOAD
+	// 已關閉
+	WS_CLOSED
+)
+
+// 單一連線的 WebSocket 狀態，負責解析幀與重組分段的訊息
+type WebSocket struct {
+	State WebSocketState
+	// 是否為客戶端(伺服器端收到的幀須有遮罩，客戶端收到的幀不可有遮罩)
+	isClient bool
+	// 訊息長度上限
+	MaxMessageSize int64
+	// 最後收到的訊息類型(WsText 或 WsBinary)，回應時沿用
+	MessageType byte
+	// 是否已送出關閉幀
+	CloseSent bool
+
+	// ========== 當前幀 ==========
+	fin           bool
+	opcode        byte
+	masked        bool
+	maskKey       [4]byte
+	extLength     int32
+	payloadLength int64
+	payloadRead   int64
+	// 控制幀的 Payload
+	control []byte
+
+	// ========== 分段的訊息 ==========
+	// 訊息類型(0 表示沒有讀取中的訊息)
+	messageType byte
+	message     []byte
+}
+
+func NewWebSocket(isClient bool, maxMessageSize int64) *WebSocket {
+	ws := &WebSocket{
+		isClient:       isClient,
+		MaxMessageSize: maxMessageSize,
+		control:        make([]byte, 0, maxControlPayload),
+		message:        []byte{},
+	}
+	ws.Reset()
+	return ws
+}
+
+// 重置為等待交握的狀態(連線中斷或重新連線時)
+func (ws *WebSocket) Reset() {
+	ws.State = WS_HANDSHAKE
+	ws.MessageType = WsBinary
+	ws.CloseSent = false
+	ws.messageType = 0
+	ws.message = ws.message[:0]
+	ws.control = ws.control[:0]
+}
+
+// 完成交握，開始讀取幀
+func (ws *WebSocket) Open() {
+	ws.State = WS_FRAME_HEADER
+}
+
+func (ws *WebSocket) IsOpen() bool {
+	return ws.State >= WS_FRAME_HEADER && ws.State < WS_CLOSED && !ws.CloseSent
+}
+
+// 從 c 的讀取緩存中解析幀，buffer 為暫存空間
+// 讀取到完整的訊息(WsText, WsBinary)或控制幀(WsClose, WsPing, WsPong)時，ok 為 true
+// 返回的 payload 於下次呼叫前有效；違反協定時返回 *WebSocketError
+func (ws *WebSocket) ReadMessage(c *Conn, buffer *[]byte) (opcode byte, payload []byte, ok bool, err error) {
+	for {
+		switch ws.State {
+		case WS_FRAME_HEADER:
+			if c.ReadableLength < 2 {
+				return 0, nil, false, nil
+			}
+
+			c.Read(buffer, 2)
+
+			if err = ws.parseHeader((*buffer)[0], (*buffer)[1]); err != nil {
+				return 0, nil, false, err
+			}
+
+			ws.State = WS_FRAME_EXTENDED
+
+		case WS_FRAME_EXTENDED:
+			if c.ReadableLength < ws.extLength {
+				return 0, nil, false, nil
+			}
+
+			c.Read(buffer, ws.extLength)
+
+			if err = ws.parseExtended((*buffer)[:ws.extLength]); err != nil {
+				return 0, nil, false, err
+			}
+
+			ws.State = WS_FRAME_PAYLOAD
+
+		case WS_FRAME_PAYLOAD:
+			if ws.payloadRead < ws.payloadLength {
+				n := ws.payloadLength - ws.payloadRead
+
+				if n > int64(c.ReadableLength) {
+					n = int64(c.ReadableLength)
+				}
+
+				if n > int64(len(*buffer)) {
+					n = int64(len(*buffer))
+				}
+
+				if n == 0 {
+					return 0, nil, false, nil
+				}
+
+				c.Read(buffer, int32(n))
+				chunk := (*buffer)[:n]
+
+				if ws.masked {
+					for i := range chunk {
+						chunk[i] ^= ws.maskKey[(ws.payloadRead+int64(i))%4]
+					}
+				}
+
+				if ws.opcode >= WsClose {
+					ws.control = append(ws.control, chunk...)
+				} else {
+					ws.message = append(ws.message, chunk...)
+				}
+
+				ws.payloadRead += n
+				continue
+			}
+
+			ws.State = WS_FRAME_HEADER
+
+			// 控制幀可穿插於分段的訊息之間
+			if ws.opcode >= WsClose {
+				return ws.opcode, ws.control, true, nil
+			}
+
+			if ws.opcode != WsContinuation {
+				ws.messageType = ws.opcode
+			}
+
+			if !ws.fin {
+				continue
+			}
+
+			opcode = ws.messageType
+			payload = ws.message
+			ws.messageType = 0
+			ws.message = ws.message[:0]
+
+			if opcode == WsText && !utf8.Valid(payload) {
+				return 0, nil, false, wsError(CloseInvalidPayload, "Text message is not valid UTF-8.")
+			}
+
+			ws.MessageType = opcode
+			return opcode, payload, true, nil
+
+		default:
+			return 0, nil, false, nil
+		}
+	}
+}
+
+func (ws *WebSocket) parseHeader(b0 byte, b1 byte) error {
+	if b0&0x70 != 0 {
+		return wsError(CloseProtocolError, "Reserved bits are set.")
+	}
+
+	ws.fin = b0&0x80 != 0
+	ws.opcode = b0 & 0x0F
+	ws.masked = b1&0x80 != 0
+	ws.payloadLength = int64(b1 & 0x7F)
+	ws.payloadRead = 0
+	ws.extLength = 0
+
+	// 客戶端送出的幀須有遮罩，伺服器端送出的幀不可有遮罩
+	if ws.masked == ws.isClient {
+		return wsError(CloseProtocolError, "Invalid frame masking(masked: %v).", ws.masked)
+	}
+
+	switch ws.opcode {
+	case WsClose, WsPing, WsPong:
+		if !ws.fin || ws.payloadLength > maxControlPayload {
+			return wsError(CloseProtocolError, "Invalid control frame(opcode: %d).", ws.opcode)
+		}
+		ws.control = ws.control[:0]
+	case WsText, WsBinary:
+		if ws.messageType != 0 {
+			return wsError(CloseProtocolError, "Expect a continuation frame, got opcode %d.", ws.opcode)
+		}
+	case WsContinuation:
+		if ws.messageType == 0 {
+			return wsError(CloseProtocolError, "Unexpected continuation frame.")
+		}
+	default:
+		return wsError(CloseProtocolError, "Unknown opcode %d.", ws.opcode)
+	}
+
+	switch ws.payloadLength {
+	case 126:
+		ws.extLength = 2
+	case 127:
+		ws.extLength = 8
+	}
+
+	if ws.masked {
+		ws.extLength += 4
+	}
+
+	return nil
+}
+
+func (ws *WebSocket) parseExtended(data []byte) error {
+	switch ws.payloadLength {
+	case 126:
+		ws.payloadLength = int64(binary.BigEndian.Uint16(data))
+		data = data[2:]
+	case 127:
+		length := binary.BigEndian.Uint64(data)
+
+		if length>>63 != 0 {
+			return wsError(CloseProtocolError, "Invalid payload length.")
+		}
+
+		ws.payloadLength = int64(length)
+		data = data[8:]
+	}
+
+	if ws.opcode < WsClose && int64(len(ws.message))+ws.payloadLength > ws.MaxMessageSize {
+		return wsError(CloseMessageTooBig, "Message exceeds %d bytes.", ws.MaxMessageSize)
+	}
+
+	if ws.masked {
+		copy(ws.maskKey[:], data)
+	}
+
+	return nil
+}
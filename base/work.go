@@ -1,6 +1,8 @@
 package base
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"time"
 
@@ -62,6 +64,10 @@ type Work struct {
 	Length int32
 	// 數據封裝容器
 	Body *TransData
+	// TLS 連線狀態(非 TLS 連線時為 nil)
+	TLS *tls.ConnectionState
+	// SendTransData 所使用的長度前綴格式(為 nil 時同 TransData.FormData)
+	Framing *Tcp0Framing
 }
 
 func NewWork(id int32) *Work {
@@ -89,6 +95,14 @@ func (w *Work) Add(work *Work) {
 	curr.Next = work
 }
 
+// 取得對方的憑證，非 TLS 連線或對方未提供憑證時返回 nil
+func (w *Work) PeerCertificate() *x509.Certificate {
+	if w.TLS != nil && len(w.TLS.PeerCertificates) > 0 {
+		return w.TLS.PeerCertificates[0]
+	}
+	return nil
+}
+
 func (w *Work) Read() []byte {
 	return w.Data
 }
@@ -101,7 +115,19 @@ func (w *Work) Send() {
 
 // 格式化數據寫入緩存
 func (w *Work) SendTransData() {
-	data := w.Body.FormData()
+	if w.Framing == nil {
+		w.send(w.Body.FormData())
+		return
+	}
+
+	data, err := w.Framing.Encode(w.Body.GetData())
+
+	if err != nil {
+		utils.Error("Work(%d) failed to form data: %+v", w.id, err)
+		w.Finish()
+		return
+	}
+
 	w.send(data)
 }
 
@@ -109,6 +135,12 @@ func (w *Work) SendTransData() {
 func (w *Work) send(data []byte) {
 	w.Body.ResetIndex()
 	w.Length = int32(len(data))
+
+	// 數據超出緩衝大小時擴充
+	if w.Length > int32(len(w.Data)) {
+		w.Data = make([]byte, w.Length)
+	}
+
 	copy(w.Data[:w.Length], data)
 	w.State = WORK_OUTPUT
 }
@@ -128,6 +160,7 @@ func (w *Work) Release() {
 	w.Length = 0
 	w.State = WORK_FREE
 	w.Body.Clear()
+	w.TLS = nil
 }
 
 func CheckWorks(works *Work) {
@@ -0,0 +1,710 @@
+// tdgen 根據 Go 的結構定義，產生不經過反射的 TransData 編解碼方法，數據格式與 base.TransData 的 Marshal / Unmarshal 相同
+//
+// 使用方式(於結構定義所在的檔案中):
+//
+//	//go:generate go run github.com/j32u4ukh/gos/cmd/tdgen -type=Player,Item
+//
+// 將為每個結構產生:
+//
+//	func (v *Player) MarshalTransData(td *base.TransData)
+//	func (v *Player) UnmarshalTransData(td *base.TransData) error
+//	func (v *Player) TransDataSchema() uint64
+//
+// 並產生常數 TransDataSchema(可由 -schema 修改名稱)，為所有結構的綜合雜湊值。
+// 結構的欄位名稱、順序或型別改變時，雜湊值也會改變，
+// 可將其作為 Asker 的 introduction 送出，由 Anser 在連線時比對，以發現雙方的訊息格式不一致。
+// introduction 和一般的訊息相同，交由工作處理函式處理，以 base.Dispatcher 為例:
+//
+//	// Asker: 連線(含重新連線)後，首先送出 introduction
+//	td := base.NewTransData()
+//	td.AddByte(SystemKind)
+//	td.AddUInt16(SchemaService)
+//	td.AddUInt64(TransDataSchema)
+//	introduction := td.FormData()
+//	asker, err := ask.NewAsker(define.Tcp0, site, laddr, nWork, onEvents, &introduction, nil)
+//
+//	// Anser: 雜湊值不符時中斷該連線
+//	dispatcher.Handle(SystemKind, SchemaService, func(c *base.Command) {
+//		if c.Body.PopUInt64() != TransDataSchema || c.Body.Err() != nil {
+//			gos.Disconnect(port, c.Index)
+//		}
+//		c.Finish()
+//	})
+//
+// 欄位所參考的、同一個套件中的結構將自動一併產生。
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	typeNames  = flag.String("type", "", "comma-separated list of struct names; required")
+	output     = flag.String("output", "", "output file name; default <source>_transdata.go")
+	schemaName = flag.String("schema", "TransDataSchema", "name of the generated schema hash constant")
+)
+
+func main() {
+	flag.Parse()
+
+	if *typeNames == "" {
+		fmt.Fprintln(os.Stderr, "tdgen: -type is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	outputName := *output
+
+	if outputName == "" {
+		if source := os.Getenv("GOFILE"); source != "" {
+			outputName = strings.TrimSuffix(source, ".go") + "_transdata.go"
+		} else {
+			outputName = strings.ToLower(strings.Split(*typeNames, ",")[0]) + "_transdata.go"
+		}
+	}
+
+	// 相對路徑視為相對於套件目錄
+	if !filepath.IsAbs(outputName) {
+		outputName = filepath.Join(dir, outputName)
+	}
+
+	g, err := newGenerator(dir, outputName)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tdgen: %v\n", err)
+		os.Exit(1)
+	}
+
+	src, err := g.generate(strings.Split(*typeNames, ","), *schemaName)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tdgen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err = os.WriteFile(outputName, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "tdgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// ====================================================================================================
+// 型別解析
+// ====================================================================================================
+
+// 數據格式上的型別
+type typeInfo struct {
+	// bool, int8 ~ int64, int, uint8 ~ uint64, uint, float32, float64, string, bytes, slice, array, map, pointer, time, struct
+	kind string
+	// Go 的型別表示式(如 Level, []Item, map[string]int64)
+	expr string
+	// 陣列長度的表示式
+	length string
+	key    *typeInfo
+	elem   *typeInfo
+}
+
+type field struct {
+	name   string
+	order  int
+	varint bool
+	info   *typeInfo
+}
+
+// 未指定順序的欄位
+const noOrder int = math.MaxInt32
+
+var basicKinds = map[string]string{
+	"bool":    "bool",
+	"int8":    "int8",
+	"int16":   "int16",
+	"int32":   "int32",
+	"rune":    "int32",
+	"int64":   "int64",
+	"int":     "int",
+	"uint8":   "uint8",
+	"byte":    "uint8",
+	"uint16":  "uint16",
+	"uint32":  "uint32",
+	"uint64":  "uint64",
+	"uint":    "uint",
+	"uintptr": "uint",
+	"float32": "float32",
+	"float64": "float64",
+	"string":  "string",
+}
+
+type generator struct {
+	packageName string
+	// 套件中所有的型別定義
+	specs map[string]*ast.TypeSpec
+	// 已解析的結構欄位
+	structs map[string][]*field
+	buffer  bytes.Buffer
+	// 暫存變數的編號
+	nVar int
+}
+
+func newGenerator(dir string, outputName string) (*generator, error) {
+	fset := token.NewFileSet()
+	filter := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != filepath.Base(outputName)
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	g := &generator{
+		specs:   map[string]*ast.TypeSpec{},
+		structs: map[string][]*field{},
+	}
+
+	for name, pkg := range pkgs {
+		g.packageName = name
+
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+					for _, spec := range gd.Specs {
+						g.specs[spec.(*ast.TypeSpec).Name.Name] = spec.(*ast.TypeSpec)
+					}
+				}
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// 解析結構的欄位(依寫入順序排列)，並加入 structs
+func (g *generator) parseStruct(name string) error {
+	if _, ok := g.structs[name]; ok {
+		return nil
+	}
+
+	spec, ok := g.specs[name]
+
+	if !ok {
+		return fmt.Errorf("type %s not found", name)
+	}
+
+	st, ok := spec.Type.(*ast.StructType)
+
+	if !ok {
+		return fmt.Errorf("type %s is not a struct", name)
+	}
+
+	fields := []*field{}
+	g.structs[name] = fields
+	orders := map[int]string{}
+
+	for _, f := range st.Fields.List {
+		tag := ""
+
+		if f.Tag != nil {
+			value, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(value).Get("td")
+		}
+
+		names := []string{}
+
+		for _, ident := range f.Names {
+			names = append(names, ident.Name)
+		}
+
+		// 嵌入的欄位，以型別名稱作為欄位名稱
+		if len(names) == 0 {
+			expr := f.Type
+
+			if star, ok := expr.(*ast.StarExpr); ok {
+				expr = star.X
+			}
+
+			switch e := expr.(type) {
+			case *ast.Ident:
+				names = append(names, e.Name)
+			case *ast.SelectorExpr:
+				names = append(names, e.Sel.Name)
+			}
+		}
+
+		for _, fieldName := range names {
+			if tag == "-" || !ast.IsExported(fieldName) {
+				continue
+			}
+
+			order, varint, err := parseTag(tag)
+
+			if err != nil {
+				return fmt.Errorf("invalid tag of %s.%s: %v", name, fieldName, err)
+			}
+
+			if order != noOrder {
+				if other, ok := orders[order]; ok {
+					return fmt.Errorf("field %s.%s has the same order %d as %s", name, fieldName, order, other)
+				}
+				orders[order] = fieldName
+			}
+
+			info, err := g.parseType(f.Type)
+
+			if err != nil {
+				return fmt.Errorf("field %s.%s: %v", name, fieldName, err)
+			}
+
+			fields = append(fields, &field{name: fieldName, order: order, varint: varint, info: info})
+		}
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].order < fields[j].order
+	})
+
+	g.structs[name] = fields
+	return nil
+}
+
+// 與 base.TransData 的標籤格式相同
+func parseTag(tag string) (order int, varint bool, err error) {
+	name, option, _ := strings.Cut(tag, ",")
+	order = noOrder
+
+	if name != "" {
+		order, err = strconv.Atoi(name)
+
+		if err != nil || order <= 0 || order == noOrder {
+			return 0, false, fmt.Errorf("invalid order %q", name)
+		}
+	}
+
+	switch option {
+	case "", "fixed":
+	case "varint":
+		varint = true
+	default:
+		return 0, false, fmt.Errorf("unknown option %q", option)
+	}
+
+	return order, varint, nil
+}
+
+func (g *generator) parseType(expr ast.Expr) (*typeInfo, error) {
+	info := &typeInfo{expr: types.ExprString(expr)}
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if kind, ok := basicKinds[e.Name]; ok {
+			info.kind = kind
+			return info, nil
+		}
+
+		spec, ok := g.specs[e.Name]
+
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", e.Name)
+		}
+
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			info.kind = "struct"
+
+			if err := g.parseStruct(e.Name); err != nil {
+				return nil, err
+			}
+
+			return info, nil
+		}
+
+		// 以其他型別定義的型別(如 type Level int32)，沿用其格式
+		underlying, err := g.parseType(spec.Type)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// 以結構定義的型別不會繼承其方法
+		if underlying.kind == "struct" {
+			return nil, fmt.Errorf("unsupported type %s, use %s instead", e.Name, underlying.expr)
+		}
+
+		underlying.expr = info.expr
+		return underlying, nil
+
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == "time" && e.Sel.Name == "Time" {
+			info.kind = "time"
+			return info, nil
+		}
+
+	case *ast.StarExpr:
+		elem, err := g.parseType(e.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		info.kind = "pointer"
+		info.elem = elem
+		return info, nil
+
+	case *ast.ArrayType:
+		elem, err := g.parseType(e.Elt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		info.elem = elem
+
+		if e.Len != nil {
+			info.kind = "array"
+			info.length = types.ExprString(e.Len)
+		} else if elem.expr == "byte" || elem.expr == "uint8" {
+			info.kind = "bytes"
+		} else {
+			info.kind = "slice"
+		}
+
+		return info, nil
+
+	case *ast.MapType:
+		key, err := g.parseType(e.Key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		elem, err := g.parseType(e.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		info.kind = "map"
+		info.key = key
+		info.elem = elem
+		return info, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", info.expr)
+}
+
+// ====================================================================================================
+// 結構描述與雜湊值
+// ====================================================================================================
+
+// 結構描述，包含欄位名稱、順序與格式，所參考的結構將展開
+func (g *generator) schema(name string, visited map[string]bool) string {
+	if visited[name] {
+		return name
+	}
+
+	visited[name] = true
+	var sb strings.Builder
+	sb.WriteString(name + "{")
+
+	for _, f := range g.structs[name] {
+		sb.WriteString(f.name + ":" + g.wireType(f.info, f.varint, visited) + ";")
+	}
+
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (g *generator) wireType(info *typeInfo, varint bool, visited map[string]bool) string {
+	switch info.kind {
+	case "int8", "int16", "int32", "int64", "int":
+		if varint {
+			return "varint"
+		}
+	case "uint8", "uint16", "uint32", "uint64", "uint":
+		if varint {
+			return "uvarint"
+		}
+	case "slice":
+		return "[]" + g.wireType(info.elem, varint && info.elem.kind != "uint8", visited)
+	case "array":
+		return "[" + info.length + "]" + g.wireType(info.elem, varint, visited)
+	case "map":
+		return "map[" + g.wireType(info.key, varint, visited) + "]" + g.wireType(info.elem, varint, visited)
+	case "pointer":
+		return "*" + g.wireType(info.elem, varint, visited)
+	case "struct":
+		return g.schema(info.expr, visited)
+	}
+	return info.kind
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// ====================================================================================================
+// 產生程式碼
+// ====================================================================================================
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buffer, format, args...)
+}
+
+// 取得不重複的暫存變數名稱
+func (g *generator) tmp(prefix string) string {
+	g.nVar++
+	return fmt.Sprintf("%s%d", prefix, g.nVar)
+}
+
+func (g *generator) generate(names []string, schemaName string) ([]byte, error) {
+	for _, name := range names {
+		if err := g.parseStruct(strings.TrimSpace(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	structNames := []string{}
+
+	for name := range g.structs {
+		structNames = append(structNames, name)
+	}
+
+	sort.Strings(structNames)
+	schemas := []string{}
+
+	for _, name := range structNames {
+		schemas = append(schemas, g.schema(name, map[string]bool{}))
+	}
+
+	// 先產生方法，再根據內容決定需要匯入的套件
+	for i, name := range structNames {
+		g.generateStruct(name, hash(schemas[i]))
+	}
+
+	body := g.buffer.String()
+	g.buffer.Reset()
+	g.printf("// Code generated by tdgen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\nimport (\n", g.packageName)
+
+	if strings.Contains(body, "fmt.") {
+		g.printf("\"fmt\"\n")
+	}
+
+	if strings.Contains(body, "time.") {
+		g.printf("\"time\"\n")
+	}
+
+	g.printf("\n\"github.com/j32u4ukh/gos/base\"\n)\n\n")
+	g.printf("// 所有結構的綜合雜湊值，雙方不一致時表示訊息格式不同\n")
+	g.printf("const %s uint64 = %#016x\n", schemaName, hash(strings.Join(schemas, "\n")))
+	g.buffer.WriteString(body)
+
+	src, err := format.Source(g.buffer.Bytes())
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %v\n%s", err, g.buffer.String())
+	}
+
+	return src, nil
+}
+
+func (g *generator) generateStruct(name string, schema uint64) {
+	fields := g.structs[name]
+
+	g.printf("\nfunc (v *%s) TransDataSchema() uint64 {\nreturn %#016x\n}\n", name, schema)
+
+	g.printf("\nfunc (v *%s) MarshalTransData(td *base.TransData) {\n", name)
+	for _, f := range fields {
+		g.encode("v."+f.name, f.info, f.varint)
+	}
+	g.printf("}\n")
+
+	// 讀取失敗時 TransData 將記錄錯誤並返回零值，因此只需在最後檢查一次
+	g.printf("\nfunc (v *%s) UnmarshalTransData(td *base.TransData) error {\n", name)
+	for _, f := range fields {
+		g.decode("v."+f.name, f.info, f.varint, name+"."+f.name)
+	}
+	g.printf("if err := td.Err(); err != nil {\nreturn fmt.Errorf(\"Failed to unmarshal %s: %%w\", err)\n}\n", name)
+	g.printf("return nil\n}\n")
+}
+
+// 數值型別寫入與讀取時使用的方法及型別
+var numberMethods = map[string][2]string{
+	"bool":    {"Boolean", "bool"},
+	"int8":    {"Int8", "int8"},
+	"int16":   {"Int16", "int16"},
+	"int32":   {"Int32", "int32"},
+	"int64":   {"Int64", "int64"},
+	"int":     {"Int64", "int64"},
+	"uint8":   {"Byte", "byte"},
+	"uint16":  {"UInt16", "uint16"},
+	"uint32":  {"UInt32", "uint32"},
+	"uint64":  {"UInt64", "uint64"},
+	"uint":    {"UInt64", "uint64"},
+	"float32": {"Float32", "float32"},
+	"float64": {"Float64", "float64"},
+}
+
+// 型別不同時加上轉型
+func convert(typ string, target string, expr string) string {
+	if typ == target {
+		return expr
+	}
+	return target + "(" + expr + ")"
+}
+
+func isSigned(kind string) bool {
+	return strings.HasPrefix(kind, "int")
+}
+
+func isInteger(kind string) bool {
+	return strings.HasPrefix(kind, "int") || strings.HasPrefix(kind, "uint")
+}
+
+func (g *generator) encode(expr string, info *typeInfo, varint bool) {
+	switch info.kind {
+	case "string":
+		g.printf("td.AddString(%s)\n", convert(info.expr, "string", expr))
+
+	case "bytes":
+		g.printf("if %s == nil {\ntd.AddInt32(-1)\n} else {\ntd.AddByteArray(%s)\n}\n", expr, convert(info.expr, "[]byte", expr))
+
+	case "time":
+		g.printf("td.AddTime(%s)\n", expr)
+
+	case "struct":
+		g.printf("%s.MarshalTransData(td)\n", expr)
+
+	case "pointer":
+		g.printf("if %s == nil {\ntd.AddByte(0)\n} else {\ntd.AddByte(1)\n", expr)
+		g.encode("(*"+expr+")", info.elem, varint)
+		g.printf("}\n")
+
+	case "slice":
+		i := g.tmp("i")
+		g.printf("if %s == nil {\ntd.AddInt32(-1)\n} else {\ntd.AddInt32(int32(len(%s)))\n", expr, expr)
+		g.printf("for %s := range %s {\n", i, expr)
+		g.encode(expr+"["+i+"]", info.elem, varint && info.elem.kind != "uint8")
+		g.printf("}\n}\n")
+
+	case "array":
+		i := g.tmp("i")
+		g.printf("for %s := range %s {\n", i, expr)
+		g.encode(expr+"["+i+"]", info.elem, varint)
+		g.printf("}\n")
+
+	case "map":
+		k, e := g.tmp("k"), g.tmp("e")
+		g.printf("if %s == nil {\ntd.AddInt32(-1)\n} else {\ntd.AddInt32(int32(len(%s)))\n", expr, expr)
+		g.printf("for %s, %s := range %s {\n", k, e, expr)
+		g.encode(k, info.key, varint)
+		g.encode(e, info.elem, varint)
+		g.printf("}\n}\n")
+
+	default:
+		if varint && isInteger(info.kind) {
+			if isSigned(info.kind) {
+				g.printf("td.AddVarint(%s)\n", convert(info.expr, "int64", expr))
+			} else {
+				g.printf("td.AddUVarint(%s)\n", convert(info.expr, "uint64", expr))
+			}
+			return
+		}
+
+		method := numberMethods[info.kind]
+		g.printf("td.Add%s(%s)\n", method[0], convert(info.expr, method[1], expr))
+	}
+}
+
+func (g *generator) decode(target string, info *typeInfo, varint bool, path string) {
+	switch info.kind {
+	case "string":
+		g.printf("%s = %s\n", target, convert("string", info.expr, "td.PopString()"))
+
+	case "bytes":
+		g.printf("%s = %s\n", target, convert("[]byte", info.expr, "td.PopByteArray()"))
+
+	case "time":
+		g.printf("%s = td.PopTime()\n", target)
+
+	case "struct":
+		err := g.tmp("err")
+		g.printf("if %s := %s.UnmarshalTransData(td); %s != nil {\nreturn fmt.Errorf(\"Failed to unmarshal %s: %%w\", %s)\n}\n", err, target, err, path, err)
+
+	case "pointer":
+		g.printf("switch td.PopByte() {\ncase 0:\n%s = nil\ncase 1:\n%s = new(%s)\n", target, target, info.elem.expr)
+		g.decode("(*"+target+")", info.elem, varint, path)
+		g.printf("default:\nreturn fmt.Errorf(\"Failed to unmarshal %s: invalid pointer flag.\")\n}\n", path)
+
+	case "slice":
+		n, i := g.tmp("n"), g.tmp("i")
+		g.printf("if %s := td.PopLength(); %s == -1 {\n%s = nil\n} else {\n%s = make(%s, %s)\n", n, n, target, target, info.expr, n)
+		g.printf("for %s := range %s {\n", i, target)
+		g.decode(target+"["+i+"]", info.elem, varint && info.elem.kind != "uint8", path)
+		g.printf("}\n}\n")
+
+	case "array":
+		i := g.tmp("i")
+		g.printf("for %s := range %s {\n", i, target)
+		g.decode(target+"["+i+"]", info.elem, varint, path)
+		g.printf("}\n")
+
+	case "map":
+		n, j, k, e := g.tmp("n"), g.tmp("j"), g.tmp("k"), g.tmp("e")
+		g.printf("if %s := td.PopLength(); %s == -1 {\n%s = nil\n} else {\n%s = make(%s, %s)\n", n, n, target, target, info.expr, n)
+		g.printf("for %s := int32(0); %s < %s; %s++ {\n", j, j, n, j)
+		g.printf("var %s %s\nvar %s %s\n", k, info.key.expr, e, info.elem.expr)
+		g.decode(k, info.key, varint, path)
+		g.decode(e, info.elem, varint, path)
+		g.printf("%s[%s] = %s\n}\n}\n", target, k, e)
+
+	default:
+		if varint && isInteger(info.kind) {
+			basic, method := "uint64", "td.PopUVarint()"
+
+			if isSigned(info.kind) {
+				basic, method = "int64", "td.PopVarint()"
+			}
+
+			if info.expr == basic {
+				g.printf("%s = %s\n", target, method)
+				return
+			}
+
+			// 數值須在欄位型別的範圍內
+			x := g.tmp("x")
+			g.printf("%s := %s\n", x, method)
+			g.printf("if %s(%s(%s)) != %s {\nreturn fmt.Errorf(\"Failed to unmarshal %s: %%d overflows %s.\", %s)\n}\n", basic, info.expr, x, x, path, info.expr, x)
+			g.printf("%s = %s(%s)\n", target, info.expr, x)
+			return
+		}
+
+		method := numberMethods[info.kind]
+		g.printf("%s = %s\n", target, convert(method[1], info.expr, "td.Pop"+method[0]+"()"))
+	}
+}
@@ -0,0 +1,6 @@
+package define
+
+import "math"
+
+// 處理函式鏈呼叫 Abort 後，索引值將被設為此值，使後續的處理函式不再被執行(ghttp.Context 與 base.Command 共用)
+const AbortIndex int = math.MaxInt32 >> 1
@@ -6,15 +6,41 @@ const (
 	// 前 4 碼為數據長度，後面才是實際要傳的數據
 	Tcp0 SocketType = iota
 	Http
+	// 經由 HTTP 升級交握後，以 RFC 6455 的幀傳輸訊息
+	WebSocket
+	// 以對方位置作為虛擬連線的 UDP，訊息可選擇是否保證送達與順序
+	Udp
+	// 以分隔字元(預設為 '\n')切分的文字協定，每一行作為一個訊息
+	Line
 )
 
+// 自定義協定的 SocketType 須大於等於 Custom(由 base.RegisterCodec 註冊)
+const Custom SocketType = 128
+
+// 自定義協定的名稱
+var customNames = map[SocketType]string{}
+
+// 設置自定義協定的名稱(由 base.RegisterCodec 呼叫)
+func SetCustomName(s SocketType, name string) {
+	customNames[s] = name
+}
+
 func (s SocketType) String() string {
 	switch s {
 	case Tcp0:
 		return "Tcp0"
 	case Http:
 		return "Http"
+	case WebSocket:
+		return "WebSocket"
+	case Udp:
+		return "Udp"
+	case Line:
+		return "Line"
 	default:
+		if name, ok := customNames[s]; ok {
+			return name
+		}
 		return "Null"
 	}
 }
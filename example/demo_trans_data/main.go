@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/j32u4ukh/gos/base"
 )
@@ -11,6 +12,7 @@ func main() {
 	// demo2()
 	demo3()
 	// demo4()
+	// demo5()
 }
 
 func demo1() {
@@ -95,3 +97,33 @@ func demo4() {
 	str := td.PopString()
 	fmt.Printf("i32: %d, empty: %s, str: %s\n", i32, empty, str)
 }
+
+type Message struct {
+	Kind    byte   `td:"1"`
+	Service uint16 `td:"2"`
+	Id      int64  `td:",varint"`
+	Content string
+	Tags    []string
+	Time    time.Time
+}
+
+func demo5() {
+	td := base.NewTransData()
+	msg := &Message{Kind: 1, Service: 2, Id: 9527, Content: "Hello", Tags: []string{"a", "b"}, Time: time.Now()}
+
+	if err := td.Marshal(msg); err != nil {
+		fmt.Printf("Failed to marshal: %+v\n", err)
+		return
+	}
+
+	fmt.Printf("data: %+v\n", td.GetData())
+	td.ResetIndex()
+	result := &Message{}
+
+	if err := td.Unmarshal(result); err != nil {
+		fmt.Printf("Failed to unmarshal: %+v\n", err)
+		return
+	}
+
+	fmt.Printf("result: %+v\n", result)
+}
@@ -1,6 +1,7 @@
 package gos
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"strconv"
@@ -32,18 +33,67 @@ func init() {
 // 指定要監聽的 port，並生成 Anser 物件
 func Listen(socketType define.SocketType, port int32) (ans.IAnswer, error) {
 	if _, ok := server.anserMap[port]; !ok {
-		laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+		anser, err := newAnser(socketType, port)
+		if err != nil {
+			return nil, err
+		}
+		server.anserMap[port] = anser
+	}
+	return server.anserMap[port], nil
+}
+
+// 指定要監聽的 port，並以 TLS 加密連線(須於 StartListen 之前呼叫)
+// 該 port 已註冊過(如已透過 Listen 以明文監聽)時，返回錯誤
+func ListenTLS(socketType define.SocketType, port int32, config *tls.Config) (ans.IAnswer, error) {
+	if _, ok := server.anserMap[port]; ok {
+		return nil, errors.Errorf("Port %d is already registered.", port)
+	}
+
+	anser, err := newAnser(socketType, port)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 設定 TLS 成功後才註冊，失敗時釋放已佔用的 port
+	if err = anser.UseTLS(config); err != nil {
+		anser.StopListen()
+		return nil, errors.Wrapf(err, "Failed to use TLS on port %d.", port)
+	}
+
+	server.anserMap[port] = anser
+	return anser, nil
+}
+
+func newAnser(socketType define.SocketType, port int32) (ans.IAnswer, error) {
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	anser, err := ans.NewAnser(
+		socketType,
+		laddr,
+		utils.GosConfig.AnswerConnectNumbers[socketType],
+		utils.GosConfig.AnswerWorkNumbers[socketType])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to listen on port %d.", port)
+	}
+	return anser, nil
+}
+
+// 指定要監聽的 Unix domain socket 檔案路徑，並生成 Anser 物件
+// 啟動時將移除前次遺留、無人監聽的 socket 檔案，並將檔案權限設為 utils.GosConfig.UnixSocketMode；StopListen 時移除 socket 檔案
+func ListenUnix(socketType define.SocketType, path string) (ans.IAnswer, error) {
+	if _, ok := server.unixAnserMap[path]; !ok {
+		laddr := &net.UnixAddr{Name: path, Net: "unix"}
 		anser, err := ans.NewAnser(
 			socketType,
 			laddr,
 			utils.GosConfig.AnswerConnectNumbers[socketType],
 			utils.GosConfig.AnswerWorkNumbers[socketType])
 		if err != nil {
-			return nil, errors.Wrapf(err, "Failed to listen on port %d.", port)
+			return nil, errors.Wrapf(err, "Failed to listen on %s.", path)
 		}
-		server.anserMap[port] = anser
+		server.unixAnserMap[path] = anser
 	}
-	return server.anserMap[port], nil
+	return server.unixAnserMap[path], nil
 }
 
 // 開始所有已註冊的監聽
@@ -52,6 +102,31 @@ func StartListen() {
 	for _, anser = range server.anserMap {
 		go anser.Listen()
 	}
+	for _, anser = range server.unixAnserMap {
+		go anser.Listen()
+	}
+}
+
+// 停止所有已註冊的監聽(已建立的連線不受影響)，並移除 Unix domain socket 檔案
+func StopListen() error {
+	var anser ans.IAnswer
+	var port int32
+	var path string
+	var err error
+
+	for port, anser = range server.anserMap {
+		if e := anser.StopListen(); e != nil && err == nil {
+			err = errors.Wrapf(e, "Failed to stop listening on port %d.", port)
+		}
+	}
+
+	for path, anser = range server.unixAnserMap {
+		if e := anser.StopListen(); e != nil && err == nil {
+			err = errors.Wrapf(e, "Failed to stop listening on %s.", path)
+		}
+	}
+
+	return err
 }
 
 // 向位置 ip:port 送出連線請求，利用 serverId 來識別多個連線
@@ -60,25 +135,66 @@ func StartListen() {
 // port: server port
 // socketType: 協定類型
 func Bind(serverId int32, ip string, port int, socketType define.SocketType, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (ask.IAsker, error) {
+	laddr := &net.TCPAddr{IP: net.ParseIP(ip), Port: port, Zone: ""}
+	return bind(serverId, laddr, socketType, onEvents, introduction, heartbeat)
+}
+
+// 同 Bind，向 Unix domain socket 檔案路徑 path 送出連線請求
+func BindUnix(serverId int32, path string, socketType define.SocketType, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (ask.IAsker, error) {
+	laddr := &net.UnixAddr{Name: path, Net: "unix"}
+	return bind(serverId, laddr, socketType, onEvents, introduction, heartbeat)
+}
+
+func bind(serverId int32, laddr net.Addr, socketType define.SocketType, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (ask.IAsker, error) {
 	if _, ok := server.askerMap[serverId]; !ok {
-		laddr := &net.TCPAddr{IP: net.ParseIP(ip), Port: port, Zone: ""}
-		asker, err := ask.NewAsker(
-			socketType,
-			serverId,
-			laddr,
-			utils.GosConfig.AskerWorkNumbers[socketType],
-			onEvents,
-			introduction,
-			heartbeat,
-		)
+		asker, err := newAsker(serverId, laddr, socketType, onEvents, introduction, heartbeat)
 		if err != nil {
-			return nil, errors.Wrapf(err, "Failed to create an Asker for %s:%d.", ip, port)
+			return nil, err
 		}
 		server.askerMap[serverId] = asker
 	}
 	return server.askerMap[serverId], nil
 }
 
+// 同 Bind，並以 TLS 加密連線(須於 StartConnect 之前呼叫)
+// 該 serverId 已註冊過(如已透過 Bind 以明文連線)時，返回錯誤
+func BindTLS(serverId int32, ip string, port int, socketType define.SocketType, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte, config *tls.Config) (ask.IAsker, error) {
+	if _, ok := server.askerMap[serverId]; ok {
+		return nil, errors.Errorf("Server id %d is already registered.", serverId)
+	}
+
+	laddr := &net.TCPAddr{IP: net.ParseIP(ip), Port: port, Zone: ""}
+	asker, err := newAsker(serverId, laddr, socketType, onEvents, introduction, heartbeat)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 設定 TLS 成功後才註冊
+	if err = asker.UseTLS(config); err != nil {
+		return nil, errors.Wrapf(err, "Failed to use TLS for %s:%d.", ip, port)
+	}
+
+	server.askerMap[serverId] = asker
+	return asker, nil
+}
+
+func newAsker(serverId int32, laddr net.Addr, socketType define.SocketType, onEvents base.OnEventsFunc, introduction *[]byte, heartbeat *[]byte) (ask.IAsker, error) {
+	asker, err := ask.NewAsker(
+		socketType,
+		serverId,
+		laddr,
+		utils.GosConfig.AskerWorkNumbers[socketType],
+		onEvents,
+		introduction,
+		heartbeat,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create an Asker for %s.", laddr)
+	}
+	return asker, nil
+}
+
 // 開始所有已註冊的監聽
 func StartConnect() error {
 	var asker ask.IAsker
@@ -117,6 +233,10 @@ func Run(run func()) {
 			anser.Handler()
 		}
 
+		for _, anser = range server.unixAnserMap {
+			anser.Handler()
+		}
+
 		// 處理各個 asker 讀取到的數據
 		for _, asker = range server.askerMap {
 			asker.Handler()
@@ -141,6 +261,10 @@ func RunAns() {
 	for _, anser = range server.anserMap {
 		anser.Handler()
 	}
+
+	for _, anser = range server.unixAnserMap {
+		anser.Handler()
+	}
 }
 
 func SendToClient(port int32, cid int32, data *[]byte, length int32) error {
@@ -154,6 +278,18 @@ func SendToClient(port int32, cid int32, data *[]byte, length int32) error {
 	return errors.New(fmt.Sprintf("Hasn't listen to port %d", port))
 }
 
+// 傳送數據給透過 ListenUnix 監聽 path 的 Anser 的客戶端
+func SendToUnixClient(path string, cid int32, data *[]byte, length int32) error {
+	if anser, ok := server.unixAnserMap[path]; ok {
+		err := anser.Write(cid, data, length)
+		if err != nil {
+			return errors.Wrap(err, "Failed to send to unix client.")
+		}
+		return nil
+	}
+	return errors.Errorf("Hasn't listen to %s", path)
+}
+
 func RunAsk() {
 	var asker ask.IAsker
 	// 處理各個 asker 讀取到的數據
@@ -163,6 +299,14 @@ func RunAsk() {
 }
 
 func SendTransDataToServer(serverId int32, td *base.TransData) error {
+	// Tcp0Asker 以其設置的格式加上長度前綴
+	if asker, ok := server.askerMap[serverId].(*ask.Tcp0Asker); ok {
+		if err := asker.WriteFrame(td.GetData()); err != nil {
+			return errors.Wrap(err, "Failed to send transdata to server.")
+		}
+		return nil
+	}
+
 	data := td.FormData()
 	err := SendToServer(serverId, &data, int32(len(data)))
 	if err != nil {
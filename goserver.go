@@ -13,6 +13,8 @@ import (
 type goserver struct {
 	// key: port; value: *Anser
 	anserMap map[int32]ans.IAnswer
+	// key: Unix domain socket 檔案路徑; value: *Anser
+	unixAnserMap map[string]ans.IAnswer
 	// key: server id; value: *Asker
 	askerMap map[int32]ask.IAsker
 	// 啟動後，最大的 id 值 + 1，作為動態建立 Asker 時的 id 值
@@ -24,6 +26,7 @@ type goserver struct {
 func newGoserver() *goserver {
 	g := &goserver{
 		anserMap:     map[int32]ans.IAnswer{},
+		unixAnserMap: map[string]ans.IAnswer{},
 		askerMap:     map[int32]ask.IAsker{},
 		nextServerId: 0,
 		frameTime:    20 * time.Millisecond,
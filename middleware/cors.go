@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+)
+
+// ====================================================================================================
+// Cors
+// ====================================================================================================
+type CorsConfig struct {
+	// 允許的來源，"*" 表示允許所有來源
+	AllowOrigins []string
+	// 預檢請求所回應的 Access-Control-Allow-Methods
+	AllowMethods []string
+	// 預檢請求所回應的 Access-Control-Allow-Headers，為空時回應請求所要求的標頭
+	AllowHeaders []string
+	// 允許瀏覽器讀取的回應標頭
+	ExposeHeaders []string
+	// 是否允許攜帶 Cookie 等憑證(此時不會以 "*" 回應來源)
+	AllowCredentials bool
+	// 預檢請求結果的快取時間(0 表示不設置)
+	MaxAge time.Duration
+}
+
+func DefaultCorsConfig() CorsConfig {
+	config := CorsConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{
+			ghttp.MethodHead,
+			ghttp.MethodGet,
+			ghttp.MethodPost,
+			ghttp.MethodPut,
+			ghttp.MethodPatch,
+			ghttp.MethodDelete,
+			ghttp.MethodOptions,
+		},
+		AllowHeaders:     []string{},
+		ExposeHeaders:    []string{},
+		AllowCredentials: false,
+		MaxAge:           12 * time.Hour,
+	}
+	return config
+}
+
+// 跨來源資源共用，預檢請求將直接以 204 回應，不會執行後續的處理函式
+func Cors(config CorsConfig) ans.HandlerFunc {
+	allowAll := false
+	origins := map[string]bool{}
+
+	for _, origin := range config.AllowOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		origins[origin] = true
+	}
+
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge / time.Second))
+
+	return func(c *ghttp.Context) {
+		origin := c.Request.Header.Get("Origin")
+
+		// 非跨來源請求
+		if origin == "" {
+			return
+		}
+
+		isPreflight := c.Method == ghttp.MethodOptions && c.Request.Header.Get("Access-Control-Request-Method") != ""
+
+		if !allowAll && !origins[origin] {
+			if isPreflight {
+				c.AbortWithStatus(ghttp.StatusForbidden)
+			}
+			return
+		}
+
+		if isPreflight {
+			setAllowOrigin(c, origin, allowAll && !config.AllowCredentials, config.AllowCredentials)
+			c.Response.Header.Set("Access-Control-Allow-Methods", allowMethods)
+
+			if allowHeaders != "" {
+				c.Response.Header.Set("Access-Control-Allow-Headers", allowHeaders)
+			} else if requestHeaders := c.Request.Header.Get("Access-Control-Request-Headers"); requestHeaders != "" {
+				c.Response.Header.Set("Access-Control-Allow-Headers", requestHeaders)
+				c.Response.Header.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if config.MaxAge > 0 {
+				c.Response.Header.Set("Access-Control-Max-Age", maxAge)
+			}
+
+			c.AbortWithStatus(ghttp.StatusNoContent)
+			return
+		}
+
+		c.Next()
+
+		// 於後續處理函式執行完畢後再設置標頭，避免被處理函式覆寫
+		setAllowOrigin(c, origin, allowAll && !config.AllowCredentials, config.AllowCredentials)
+
+		if exposeHeaders != "" {
+			c.Response.Header.Set("Access-Control-Expose-Headers", exposeHeaders)
+		}
+	}
+}
+
+func setAllowOrigin(c *ghttp.Context, origin string, wildcard bool, credentials bool) {
+	if wildcard {
+		c.Response.Header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		c.Response.Header.Set("Access-Control-Allow-Origin", origin)
+		c.Response.Header.Add("Vary", "Origin")
+	}
+
+	if credentials {
+		c.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"strings"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+// ====================================================================================================
+// Gzip
+// ====================================================================================================
+
+// 小於此長度的 Body 不進行壓縮
+const GzipMinLength int32 = 256
+
+// 根據請求的 Accept-Encoding，以 gzip 壓縮回應的 Body(level 同 compress/gzip 的壓縮等級)
+func Gzip(level int) ans.HandlerFunc {
+	return func(c *ghttp.Context) {
+		accepted := acceptGzip(c.Request.Header.Get("Accept-Encoding"))
+
+		c.Next()
+
+		if !accepted || !shouldCompress(c) {
+			return
+		}
+
+		var buffer bytes.Buffer
+		writer, err := gzip.NewWriterLevel(&buffer, level)
+
+		if err != nil {
+			utils.Error("Failed to new gzip writer: %+v", err)
+			return
+		}
+
+		writer.Write(c.Response.Body[:c.Response.BodyLength])
+
+		if err = writer.Close(); err != nil {
+			utils.Error("Failed to compress body: %+v", err)
+			return
+		}
+
+		// 壓縮後未能縮小(如已壓縮過的數據)，則維持原本的 Body
+		if int32(buffer.Len()) >= c.Response.BodyLength {
+			return
+		}
+
+		c.Response.SetBody(buffer.Bytes(), int32(buffer.Len()))
+		c.Response.SetContentLength()
+		c.Response.Header.Set("Content-Encoding", "gzip")
+		c.Response.Header.Add("Vary", "Accept-Encoding")
+	}
+}
+
+// 回應是否適合壓縮
+func shouldCompress(c *ghttp.Context) bool {
+	switch {
+	// 非同步回應或無 Body 的回應
+	case c.Code == -1 || c.Code == ghttp.StatusNoContent || c.Code == ghttp.StatusNotModified:
+		return false
+	case c.Method == ghttp.MethodHead || c.Response.IsChunked() || c.IsStreaming():
+		return false
+	case c.Response.BodyLength < GzipMinLength:
+		return false
+	// 範圍請求的 Content-Range 對應的是未壓縮的數據
+	case c.Response.Header.Get("Content-Range") != "":
+		return false
+	case c.Response.Header.Get("Content-Encoding") != "":
+		return false
+	}
+	return true
+}
+
+// 解析 Accept-Encoding，判斷客戶端是否接受 gzip(q=0 表示不接受)
+func acceptGzip(acceptEncoding string) bool {
+	for _, token := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(token, ";")
+		coding := strings.TrimSpace(params[0])
+
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
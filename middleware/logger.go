@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+// ====================================================================================================
+// AccessLog
+// ====================================================================================================
+
+// 於後續處理函式執行完畢後，透過 utils.Info 記錄請求的存取紀錄
+func AccessLog() ans.HandlerFunc {
+	return func(c *ghttp.Context) {
+		start := time.Now()
+		method := c.Method
+		query := c.Query
+
+		c.Next()
+
+		utils.Info("method=%s path=%s status=%d size=%d latency=%s request_id=%s",
+			method, query, c.Code, c.Response.BodyLength, time.Since(start), GetRequestId(c))
+	}
+}
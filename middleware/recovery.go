@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+// ====================================================================================================
+// Recovery
+// ====================================================================================================
+
+// 攔截後續處理函式的 panic，並回應 500 Internal Server Error
+// isDebug 為 true 時，回應中將附上 panic 的內容與堆疊資訊
+func Recovery(isDebug bool) ans.HandlerFunc {
+	return func(c *ghttp.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				stack := string(debug.Stack())
+				utils.Error("method: %s, query: %s, panic: %+v\n%s", c.Method, c.Query, err, stack)
+
+				obj := ghttp.H{
+					"error": "Internal Server Error",
+				}
+
+				if isDebug {
+					obj["panic"] = fmt.Sprintf("%+v", err)
+					obj["stack"] = stack
+				}
+
+				c.AbortWithStatusJSON(ghttp.StatusInternalServerError, obj)
+			}
+		}()
+		c.Next()
+	}
+}
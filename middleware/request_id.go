@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+)
+
+// ====================================================================================================
+// RequestId
+// ====================================================================================================
+const (
+	// 請求 ID 所使用的標頭
+	RequestIdHeader = "X-Request-Id"
+	// 請求 ID 存放於 Context 的鍵值
+	RequestIdKey = "RequestId"
+	// 沿用客戶端提供的請求 ID 時，所允許的最大長度
+	maxRequestIdLength = 128
+)
+
+// 沿用請求中的 X-Request-Id(若無則生成新的請求 ID)，存放於 Context 並寫入回應標頭
+func RequestId() ans.HandlerFunc {
+	return func(c *ghttp.Context) {
+		id := c.Request.Header.Get(RequestIdHeader)
+
+		if id == "" || len(id) > maxRequestIdLength {
+			id = newRequestId()
+		}
+
+		c.Set(RequestIdKey, id)
+		c.Next()
+		c.Response.Header.Set(RequestIdHeader, id)
+	}
+}
+
+// 取得當前請求的請求 ID，若未使用 RequestId 中介函式則返回空字串
+func GetRequestId(c *ghttp.Context) string {
+	if value, ok := c.Get(RequestIdKey); ok {
+		if id, ok := value.(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+func newRequestId() string {
+	bs := make([]byte, 16)
+	// crypto/rand 讀取失敗時保留全零的 ID，不影響請求本身
+	rand.Read(bs)
+	return hex.EncodeToString(bs)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+// ====================================================================================================
+// Sessions
+// ====================================================================================================
+
+// 從 store 載入名為 name 的 Session(透過 c.Session() 取得)，處理函式執行後，若 Session 有變更則保存
+// 非同步回應的請求，須於回應前自行呼叫 store.Save
+func Sessions(name string, store ghttp.SessionStore) ans.HandlerFunc {
+	return func(c *ghttp.Context) {
+		session, err := store.Get(c, name)
+
+		if err != nil {
+			// 無效或過期的 Session，以新的 Session 繼續處理請求
+			utils.Warn("Failed to load session: %+v", err)
+		}
+
+		c.Set(ghttp.SessionKey, session)
+		c.Next()
+
+		if session.IsModified() {
+			if err = store.Save(c, session); err != nil {
+				utils.Error("Failed to save session %s: %+v", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+const port int = 18948
+
+var anser *ans.HttpAnser
+var loop *testutil.Loop
+
+// 延遲回應的請求所屬的連線
+var pending = make(chan int32, 10)
+
+func TestMain(m *testing.M) {
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser = a.(*ans.HttpAnser)
+
+	// 不在處理函式中回應，之後再透過 GetContext(-1) 與 Send 回應
+	anser.GET("/async", func(c *ghttp.Context) {
+		pending <- c.Cid
+	})
+
+	loop = testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+type reply struct {
+	res  *http.Response
+	body string
+	err  error
+}
+
+// 送出請求，並由 respond 以非同步的方式回應
+func asyncGet(t *testing.T, respond func(c *ghttp.Context)) (*http.Response, string) {
+	replies := make(chan reply, 1)
+
+	go func() {
+		res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/async", port))
+
+		if err != nil {
+			replies <- reply{err: err}
+			return
+		}
+
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		replies <- reply{res: res, body: string(body), err: err}
+	}()
+
+	var cid int32
+
+	select {
+	case cid = <-pending:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for request.")
+	}
+
+	loop.Do(func() {
+		c := anser.GetContext(-1)
+		c.Cid = cid
+		respond(c)
+		anser.Send(c)
+	})
+
+	select {
+	case r := <-replies:
+		if r.err != nil {
+			t.Fatalf("Failed to get response: %+v", r.err)
+		}
+
+		return r.res, r.body
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for response.")
+	}
+
+	return nil, ""
+}
+
+// 回收至 contextPool 的 Context 須先重置，前一次回應的標頭與 Cookie 不可出現在下一次回應中
+func TestAsyncResponsesInRow(t *testing.T) {
+	first, body := asyncGet(t, func(c *ghttp.Context) {
+		c.Response.SetHeader("X-First", "1")
+		c.SetCookie(&ghttp.Cookie{Name: "first", Value: "1"})
+		c.String(ghttp.StatusOK, "first")
+	})
+
+	if body != "first" || first.Header.Get("X-First") != "1" || len(first.Cookies()) != 1 {
+		t.Fatalf("First response, body: %q, header: %+v", body, first.Header)
+	}
+
+	second, body := asyncGet(t, func(c *ghttp.Context) {
+		c.String(ghttp.StatusOK, "second")
+	})
+
+	if body != "second" {
+		t.Errorf("Second body: %q", body)
+	}
+
+	if second.Header.Get("X-First") != "" || len(second.Cookies()) != 0 {
+		t.Errorf("Second response has headers of the first one: %+v", second.Header)
+	}
+}
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/base/ghttp"
+)
+
+type Address struct {
+	City string `json:"city" binding:"required"`
+}
+
+type User struct {
+	Name    string   `json:"name" form:"name" binding:"required,min=2,max=8"`
+	Age     int      `json:"age" form:"age" binding:"min=0,max=150"`
+	Role    string   `json:"role" form:"role" binding:"oneof=admin user"`
+	Email   string   `json:"email" form:"email" binding:"omitempty,regex=^[a-z]+@[a-z]+\\.(com|org)$"`
+	Tags    []string `json:"tags" form:"tag" binding:"max=3"`
+	Address *Address `json:"address"`
+}
+
+type Uri struct {
+	Id    uint64  `uri:"id" binding:"required"`
+	Score float64 `uri:"score"`
+}
+
+type Header struct {
+	Token   string        `header:"X-Token" binding:"len=4"`
+	Timeout time.Duration `header:"X-Timeout"`
+}
+
+type Counter struct {
+	Count int     `json:"count" binding:"min=1"`
+	Kind  string  `json:"kind" binding:"oneof=a b"`
+	Limit *int    `json:"limit" binding:"max=10"`
+	Note  *string `json:"note" binding:"min=2"`
+}
+
+type errorBody struct {
+	Error   string             `json:"error"`
+	Message string             `json:"message"`
+	Fields  []ghttp.FieldError `json:"fields"`
+}
+
+func newContext(contentType string, body string) *ghttp.Context {
+	c := ghttp.NewContext(0)
+	c.Request.Method = ghttp.MethodPost
+
+	if contentType != "" {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+
+	if body != "" {
+		c.Request.SetBody([]byte(body), int32(len(body)))
+	}
+
+	return c
+}
+
+func responseError(t *testing.T, c *ghttp.Context) *errorBody {
+	if c.Code != ghttp.StatusBadRequest || !c.IsAborted() {
+		t.Fatalf("code: %d, aborted: %v", c.Code, c.IsAborted())
+	}
+
+	e := &errorBody{}
+	json.Unmarshal(c.Response.Body[:c.Response.BodyLength], e)
+	return e
+}
+
+func TestBindJson(t *testing.T) {
+	c := newContext("application/json; charset=utf-8",
+		`{"name":"gos","age":3,"role":"admin","email":"a@b.com","tags":["x"],"address":{"city":"Taipei"}}`)
+	user := &User{}
+
+	if err := c.Bind(user); err != nil || user.Name != "gos" || user.Address.City != "Taipei" {
+		t.Errorf("err: %+v, user: %+v", err, user)
+	}
+}
+
+func TestBindJsonValidation(t *testing.T) {
+	c := newContext("application/json",
+		`{"name":"g","age":200,"role":"root","email":"A@b.net","tags":["1","2","3","4"],"address":{}}`)
+
+	if err := c.Bind(&User{}); err == nil {
+		t.Fatal("Expect validation error.")
+	}
+
+	e := responseError(t, c)
+	rules := map[string]string{}
+
+	for _, fe := range e.Fields {
+		rules[fe.Field] = fe.Rule
+	}
+
+	expected := map[string]string{
+		"name":         "min",
+		"age":          "max",
+		"role":         "oneof",
+		"email":        "regex",
+		"tags":         "max",
+		"address.city": "required",
+	}
+
+	for field, rule := range expected {
+		if rules[field] != rule {
+			t.Errorf("field: %s, rule: %s, fields: %+v", field, rules[field], e.Fields)
+		}
+	}
+}
+
+func TestBindMalformedJson(t *testing.T) {
+	c := newContext("application/json", `{"name":`)
+
+	if err := c.Bind(&User{}); err == nil {
+		t.Fatal("Expect decode error.")
+	}
+
+	if e := responseError(t, c); e.Message == "" {
+		t.Errorf("error: %+v", e)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	c := newContext("application/x-www-form-urlencoded", "name=gos&age=7&tag=a&tag=b")
+	c.Request.ParseParams("role=user")
+	user := &User{}
+
+	if err := c.Bind(user); err != nil || user.Age != 7 || len(user.Tags) != 2 || user.Role != "user" {
+		t.Errorf("err: %+v, user: %+v", err, user)
+	}
+}
+
+func TestBindQueryTypeError(t *testing.T) {
+	c := newContext("", "")
+	c.Request.ParseParams("name=gos&age=old")
+
+	if err := c.BindQuery(&User{}); err == nil {
+		t.Fatal("Expect type error.")
+	}
+
+	if e := responseError(t, c); len(e.Fields) != 1 || e.Fields[0].Field != "age" || e.Fields[0].Rule != "type" {
+		t.Errorf("error: %+v", e)
+	}
+}
+
+func TestBindUriAndHeader(t *testing.T) {
+	c := newContext("", "")
+	c.Request.SetPathParam("id", "42", int64(42))
+	c.Request.SetPathParam("score", "9.5", "9.5")
+	uri := &Uri{}
+
+	if err := c.BindUri(uri); err != nil || uri.Id != 42 || uri.Score != 9.5 {
+		t.Errorf("err: %+v, uri: %+v", err, uri)
+	}
+
+	c.Request.Header.Set("x-token", "abcd")
+	c.Request.Header.Set("X-Timeout", "1.5s")
+	header := &Header{}
+
+	if err := c.BindHeader(header); err != nil || header.Token != "abcd" || header.Timeout != 1500*time.Millisecond {
+		t.Errorf("err: %+v, header: %+v", err, header)
+	}
+}
+
+func TestBindZeroValue(t *testing.T) {
+	// 零值同樣需通過 required 以外的規則，nil 指標則略過
+	c := newContext("application/json", `{"note":""}`)
+
+	if err := c.Bind(&Counter{}); err == nil {
+		t.Fatal("Expect validation error.")
+	}
+
+	e := responseError(t, c)
+	rules := map[string]string{}
+
+	for _, fe := range e.Fields {
+		rules[fe.Field] = fe.Rule
+	}
+
+	expected := map[string]string{"count": "min", "kind": "oneof", "note": "min"}
+
+	if len(rules) != len(expected) {
+		t.Errorf("fields: %+v", e.Fields)
+	}
+
+	for field, rule := range expected {
+		if rules[field] != rule {
+			t.Errorf("field: %s, rule: %s, fields: %+v", field, rules[field], e.Fields)
+		}
+	}
+}
+
+func TestInvalidRule(t *testing.T) {
+	cases := map[string]any{
+		"param": &struct {
+			Age int `form:"age" binding:"min=abc"`
+		}{},
+		"type": &struct {
+			At time.Time `form:"at" binding:"min=1"`
+		}{},
+		"regex": &struct {
+			Name string `form:"name" binding:"regex=[a-"`
+		}{},
+		"unknown": &struct {
+			Name string `form:"name" binding:"email"`
+		}{},
+	}
+
+	for name, obj := range cases {
+		c := newContext("", "")
+
+		if err := c.BindQuery(obj); !errors.Is(err, ghttp.ErrInvalidRule) {
+			t.Errorf("%s, err: %+v", name, err)
+		}
+
+		if c.Code != ghttp.StatusInternalServerError || !c.IsAborted() {
+			t.Errorf("%s, code: %d, aborted: %v", name, c.Code, c.IsAborted())
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+const port int = 18933
+
+// 超過 HttpBodyMemoryLimit，將暫存於檔案中
+const bodySize int = 3 * 1024 * 1024
+
+func TestMain(m *testing.M) {
+	utils.GosConfig.HttpMaxBodySize = 4 * 1024 * 1024
+	utils.GosConfig.DisconnectTime = 0
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.HttpAnser)
+	anser.POST("/upload", func(c *ghttp.Context) {
+		// 逐段讀取 Body
+		hash := sha256.New()
+		n, err := io.Copy(hash, c.BodyReader())
+		c.Json(ghttp.StatusOK, ghttp.H{
+			"length": n,
+			"sha256": hex.EncodeToString(hash.Sum(nil)),
+			"ok":     err == nil,
+		})
+	})
+	anser.GET("/download", func(c *ghttp.Context) {
+		data := payload()
+		sent := 0
+		c.Stream(func(w io.Writer) bool {
+			end := sent + 64*1024
+
+			if end > len(data) {
+				end = len(data)
+			}
+
+			w.Write(data[sent:end])
+			sent = end
+			return sent < len(data)
+		})
+	})
+	anser.POST("/form", func(c *ghttp.Context) {
+		result := ghttp.H{
+			"name":   c.PostForm("name"),
+			"tags":   c.PostFormArray("tag"),
+			"q":      c.QueryArray("q"),
+			"search": c.Params["q"],
+		}
+
+		if file, err := c.FormFile("avatar"); err == nil {
+			f, _ := file.Open()
+			data, _ := io.ReadAll(f)
+			f.Close()
+			result["file"] = file.Filename + ":" + string(data)
+		}
+
+		c.Json(ghttp.StatusOK, result)
+	})
+	// 大型 Body 需多次迴圈才能傳輸完畢，因此迴圈之間不休眠
+	go anser.Listen()
+	testutil.StartWithInterval(anser.Handler, 0)
+	os.Exit(m.Run())
+}
+
+func payload() []byte {
+	data := make([]byte, bodySize)
+
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	return data
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func upload(t *testing.T, body io.Reader, length int64) string {
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("http://127.0.0.1:%d/upload", port), body)
+	req.ContentLength = length
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("Failed to upload: %+v", err)
+	}
+
+	defer res.Body.Close()
+	bs, _ := io.ReadAll(res.Body)
+	return string(bs)
+}
+
+func TestLargeBody(t *testing.T) {
+	data := payload()
+	expected := fmt.Sprintf(`{"length":%d,"ok":true,"sha256":"%s"}`, len(data), digest(data))
+
+	if result := upload(t, bytes.NewReader(data), int64(len(data))); result != expected {
+		t.Errorf("result: %s", result)
+	}
+}
+
+func TestLargeChunkedBody(t *testing.T) {
+	data := payload()
+	expected := fmt.Sprintf(`{"length":%d,"ok":true,"sha256":"%s"}`, len(data), digest(data))
+
+	// 長度未知時，net/http 將以分塊傳輸的方式送出
+	if result := upload(t, io.MultiReader(bytes.NewReader(data)), -1); result != expected {
+		t.Errorf("result: %s", result)
+	}
+}
+
+// 因大型 Body 而擴充的緩衝區，於重置後縮回預設容量
+func TestBodyShrink(t *testing.T) {
+	c := ghttp.NewContext(0)
+	data := bytes.Repeat([]byte("x"), 256*1024)
+	c.Request.SetBody(data, int32(len(data)))
+	c.Response.SetBody(data, int32(len(data)))
+
+	if len(c.Request.Body) < len(data) || len(c.Response.Body) < len(data) {
+		t.Fatalf("request: %d, response: %d", len(c.Request.Body), len(c.Response.Body))
+	}
+
+	c.Reset()
+
+	if len(c.Request.Body) != 64*1024 || len(c.Response.Body) != 64*1024 {
+		t.Errorf("request: %d, response: %d", len(c.Request.Body), len(c.Response.Body))
+	}
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	fmt.Fprintf(conn, "POST /upload HTTP/1.1\r\nHost: localhost\r\nContent-Length: %d\r\n\r\n", 5*1024*1024)
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to read response: %+v", err)
+	}
+
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+}
+
+// 不含換行的 Header 超過 HttpMaxHeaderSize 時，回應 431 並斷線，而非停止讀取
+func TestHeaderTooLarge(t *testing.T) {
+	for _, request := range []string{
+		strings.Repeat("G", 20*1024),
+		"GET /upload HTTP/1.1\r\nX-Long: " + strings.Repeat("x", 20*1024),
+		"GET /upload HTTP/1.1\r\n" + strings.Repeat("X-Many: x\r\n", 1024) + "\r\n",
+	} {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+		if err != nil {
+			t.Fatalf("Failed to dial: %+v", err)
+		}
+
+		conn.SetDeadline(time.Now().Add(3 * time.Second))
+		conn.Write([]byte(request))
+		reader := bufio.NewReader(conn)
+		res, err := http.ReadResponse(reader, nil)
+
+		if err != nil {
+			t.Fatalf("Failed to read response: %+v", err)
+		}
+
+		if res.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+			t.Errorf("code: %d", res.StatusCode)
+		}
+
+		io.Copy(io.Discard, res.Body)
+
+		if _, err = reader.ReadByte(); err != io.EOF {
+			t.Errorf("err: %v", err)
+		}
+
+		conn.Close()
+	}
+}
+
+func TestStreamResponse(t *testing.T) {
+	res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/download", port))
+
+	if err != nil {
+		t.Fatalf("Failed to download: %+v", err)
+	}
+
+	defer res.Body.Close()
+	bs, err := io.ReadAll(res.Body)
+
+	if err != nil || len(res.TransferEncoding) == 0 || digest(bs) != digest(payload()) {
+		t.Errorf("err: %+v, length: %d, transfer encoding: %v", err, len(bs), res.TransferEncoding)
+	}
+}
+
+type formResult struct {
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags"`
+	Q      []string `json:"q"`
+	Search string   `json:"search"`
+	File   string   `json:"file"`
+}
+
+func postForm(t *testing.T, contentType string, body io.Reader) *formResult {
+	res, err := http.Post(fmt.Sprintf("http://127.0.0.1:%d/form?q=a%%20b&q=c%%26d", port), contentType, body)
+
+	if err != nil {
+		t.Fatalf("Failed to post form: %+v", err)
+	}
+
+	defer res.Body.Close()
+	r := &formResult{}
+	bs, _ := io.ReadAll(res.Body)
+	json.Unmarshal(bs, r)
+	return r
+}
+
+func TestUrlencodedForm(t *testing.T) {
+	form := url.Values{
+		"name": {"小明 & co"},
+		"tag":  {"go", "http+"},
+	}
+	r := postForm(t, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+
+	if r.Name != "小明 & co" || strings.Join(r.Tags, ",") != "go,http+" ||
+		strings.Join(r.Q, ",") != "a b,c&d" || r.Search != "a b" {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+func TestMultipartForm(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	writer.WriteField("name", "gos")
+	writer.WriteField("tag", "a")
+	writer.WriteField("tag", "b")
+	part, _ := writer.CreateFormFile("avatar", "avatar.txt")
+	part.Write([]byte("image data"))
+	writer.Close()
+
+	r := postForm(t, writer.FormDataContentType(), &buffer)
+
+	if r.Name != "gos" || strings.Join(r.Tags, ",") != "a,b" || r.File != "avatar.txt:image data" {
+		t.Errorf("result: %+v", r)
+	}
+}
@@ -0,0 +1,207 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+const port int = 18946
+
+func TestMain(m *testing.M) {
+	utils.GosConfig.HttpMaxBodySize = 1024
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.HttpAnser)
+	anser.POST("/echo", func(c *ghttp.Context) {
+		c.Response.Header.Set("X-Trailer", c.Request.Trailer.Get("X-Checksum"))
+		c.Response.Header.Set("X-Header", c.Request.Header.Get("X-Checksum"))
+		c.Response.Header.Set("X-Host", c.Request.Header.Get("Host")+","+c.Request.Trailer.Get("Host"))
+		c.WriteChunk(c.ReadBytes())
+	})
+	anser.GET("/chunk", func(c *ghttp.Context) {
+		c.WriteChunk([]byte("hello "))
+		c.WriteChunk([]byte("world"))
+		c.Response.Trailer.Set("X-Checksum", "abc")
+	})
+	anser.GET("/stream", func(c *ghttp.Context) {
+		parts := []string{"a", "bc", "def"}
+		sent := 0
+		c.Response.Trailer.Set("X-Parts", fmt.Sprint(len(parts)))
+		c.Stream(func(w io.Writer) bool {
+			w.Write([]byte(parts[sent]))
+			sent++
+			return sent < len(parts)
+		})
+	})
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+// 以原始連線送出請求，並返回回應
+func rawRequest(t *testing.T, request string) *http.Response {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	conn.Write([]byte(request))
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to read response: %+v", err)
+	}
+
+	return res
+}
+
+func TestChunkedRequest(t *testing.T) {
+	res := rawRequest(t, "POST /echo HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n"+
+		"5;ext=1\r\nhello\r\n6\r\n world\r\n0\r\nX-Checksum: abc\r\n\r\n")
+	defer res.Body.Close()
+	bs, _ := io.ReadAll(res.Body)
+
+	if res.StatusCode != http.StatusOK || string(bs) != "hello world" || res.Header.Get("X-Trailer") != "abc" {
+		t.Errorf("code: %d, body: %q, trailer: %q", res.StatusCode, bs, res.Header.Get("X-Trailer"))
+	}
+
+	// Trailer 不會併入 Header
+	if res.Header.Get("X-Header") != "" {
+		t.Errorf("trailer merged into header: %q", res.Header.Get("X-Header"))
+	}
+}
+
+func TestForbiddenTrailer(t *testing.T) {
+	res := rawRequest(t, "POST /echo HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n"+
+		"5\r\nhello\r\n0\r\nhost: evil\r\nX-Checksum: abc\r\n\r\n")
+	defer res.Body.Close()
+
+	// 不得出現於 Trailer 的欄位將被忽略，其餘欄位照常保存
+	if res.Header.Get("X-Host") != "localhost," || res.Header.Get("X-Trailer") != "abc" {
+		t.Errorf("host: %q, trailer: %q", res.Header.Get("X-Host"), res.Header.Get("X-Trailer"))
+	}
+}
+
+func TestMalformedChunk(t *testing.T) {
+	cases := map[string]string{
+		// 分塊數據之後缺少 CRLF
+		"missing CRLF": "5\r\nhelloX\r\n0\r\n\r\n",
+		// 分塊數據之後多了一個空行
+		"extra CRLF": "5\r\nhello\r\n\r\n0\r\n\r\n",
+		// 無法解析的分塊長度
+		"invalid size": "zz\r\nhello\r\n0\r\n\r\n",
+	}
+
+	for name, body := range cases {
+		res := rawRequest(t, "POST /echo HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n"+body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s, code: %d", name, res.StatusCode)
+		}
+	}
+}
+
+func TestWriteChunk(t *testing.T) {
+	res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/chunk", port))
+
+	if err != nil {
+		t.Fatalf("Failed to get: %+v", err)
+	}
+
+	defer res.Body.Close()
+	bs, _ := io.ReadAll(res.Body)
+
+	// Trailer 於讀取完 Body 後才會取得
+	if string(bs) != "hello world" || len(res.TransferEncoding) == 0 || res.Trailer.Get("X-Checksum") != "abc" {
+		t.Errorf("body: %q, transfer encoding: %v, trailer: %v", bs, res.TransferEncoding, res.Trailer)
+	}
+}
+
+func TestStreamTrailer(t *testing.T) {
+	res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/stream", port))
+
+	if err != nil {
+		t.Fatalf("Failed to get: %+v", err)
+	}
+
+	defer res.Body.Close()
+	bs, _ := io.ReadAll(res.Body)
+
+	if string(bs) != "abcdef" || res.Trailer.Get("X-Parts") != "3" {
+		t.Errorf("body: %q, trailer: %v", bs, res.Trailer)
+	}
+}
+
+func TestAskerReadChunked(t *testing.T) {
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+	a, err := ask.NewHttpAsker(1, laddr, 1, 10)
+
+	if err != nil {
+		t.Fatalf("Failed to new HttpAsker: %+v", err)
+	}
+
+	// HttpAsker 於送出請求時才建立連線
+	asker := a.(*ask.HttpAsker)
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	results := make(chan string, 1)
+	loop.Do(func() {
+		req := asker.NewRequest(ghttp.MethodGet, fmt.Sprintf("127.0.0.1:%d/chunk", port), nil)
+		asker.Send(req, func(c *ghttp.Context) {
+			results <- fmt.Sprintf("%d %s %s", c.Response.Code, c.Response.Body[:c.Response.BodyLength], c.Response.Trailer.Get("X-Checksum"))
+		})
+	})
+
+	select {
+	case r := <-results:
+		if r != "200 hello world abc" {
+			t.Errorf("result: %q", r)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for response.")
+	}
+}
+
+func TestChunkTooLarge(t *testing.T) {
+	// 小分塊之後緊接著一個宣告長度極大的分塊，兩者相加超過 int32 的範圍
+	res := rawRequest(t, "POST /echo HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n"+
+		"5\r\nhello\r\n7fffffff\r\n")
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+
+	// 多個分塊累計超過上限
+	chunk := fmt.Sprintf("200\r\n%s\r\n", strings.Repeat("a", 0x200))
+	res = rawRequest(t, "POST /echo HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n"+
+		chunk+chunk+chunk+"0\r\n\r\n")
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+}
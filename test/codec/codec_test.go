@@ -0,0 +1,244 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+	"github.com/pkg/errors"
+)
+
+const port int = 18942
+
+const Netstring define.SocketType = define.Custom + 1
+
+// 可封裝的訊息長度上限，超過時 Encode 返回錯誤
+const maxMessageLength int = 64
+
+// 以 netstring(如 "5:hello,")切分訊息
+type netstring struct {
+	// 讀取狀態值 | 0: 讀取長度, 1: 讀取數據與結尾的 ','
+	state      int8
+	readLength int32
+}
+
+func newNetstring() base.Codec {
+	return &netstring{}
+}
+
+// 於環狀緩存中尋找 ':'
+func (n *netstring) hasHeader(buffer *[]byte, i int32, o int32, length int32) bool {
+	size := int32(len(*buffer))
+
+	for k := int32(0); k < length && k < 10; k++ {
+		if (*buffer)[(o+k)%size] == ':' {
+			n.readLength = k + 1
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *netstring) hasData(buffer *[]byte, i int32, o int32, length int32) bool {
+	return length >= n.readLength
+}
+
+func (n *netstring) Decode(c *base.Conn, buffer *[]byte) ([]byte, bool, error) {
+	if n.state == 0 {
+		if !c.CheckReadable(n.hasHeader) {
+			if c.ReadableLength >= 10 {
+				return nil, false, errors.New("Netstring header too long.")
+			}
+			return nil, false, nil
+		}
+
+		c.Read(buffer, n.readLength)
+		length, err := strconv.Atoi(string((*buffer)[:n.readLength-1]))
+
+		if err != nil {
+			return nil, false, errors.Wrap(err, "Invalid netstring length.")
+		}
+
+		n.state = 1
+		n.readLength = int32(length) + 1
+	}
+
+	if !c.CheckReadable(n.hasData) {
+		return nil, false, nil
+	}
+
+	c.Read(buffer, n.readLength)
+	n.state = 0
+
+	if (*buffer)[n.readLength-1] != ',' {
+		return nil, false, errors.New("Netstring should end with ','.")
+	}
+
+	return (*buffer)[:n.readLength-1], true, nil
+}
+
+func (n *netstring) Encode(message []byte) ([]byte, error) {
+	if len(message) > maxMessageLength {
+		return nil, errors.Errorf("Netstring length %d exceeds %d.", len(message), maxMessageLength)
+	}
+
+	return []byte(fmt.Sprintf("%d:%s,", len(message), message)), nil
+}
+
+func (n *netstring) Reset() {
+	n.state = 0
+	n.readLength = 0
+}
+
+func TestMain(m *testing.M) {
+	if err := base.RegisterCodec(Netstring, "Netstring", newNetstring); err != nil {
+		fmt.Printf("Failed to register codec: %+v\n", err)
+		os.Exit(1)
+	}
+
+	utils.GosConfig.DisconnectTime = 0
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewAnser(Netstring, laddr, utils.GosConfig.AnswerConnectNumbers[Netstring], utils.GosConfig.AnswerWorkNumbers[Netstring])
+
+	if err != nil {
+		fmt.Printf("Failed to new anser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.CodecAnser)
+
+	// 回應收到的訊息
+	anser.SetWorkHandler(func(w *base.Work) {
+		data := w.Body.GetData()
+		w.Body.Clear()
+		w.Body.AddRawData(append([]byte("echo:"), data...))
+		w.Send()
+	})
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func TestRegister(t *testing.T) {
+	if Netstring.String() != "Netstring" {
+		t.Errorf("name: %s", Netstring)
+	}
+
+	if err := base.RegisterCodec(Netstring, "Other", newNetstring); err == nil {
+		t.Errorf("Registering a SocketType twice should fail.")
+	}
+
+	if err := base.RegisterCodec(define.Tcp0, "Tcp0", newNetstring); err == nil {
+		t.Errorf("Registering a reserved SocketType should fail.")
+	}
+
+	if _, err := ans.NewAnser(define.Custom+2, &net.TCPAddr{}, 1, 1); err == nil {
+		t.Errorf("Unregistered SocketType should fail.")
+	}
+}
+
+func TestRawClient(t *testing.T) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	// 分多次寫出，並於一次寫出中包含兩個訊息
+	conn.Write([]byte("5:he"))
+	time.Sleep(20 * time.Millisecond)
+	conn.Write([]byte("llo,2:hi,"))
+
+	expected := "10:echo:hello,7:echo:hi,"
+	reply := make([]byte, len(expected))
+
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("Failed to read: %+v", err)
+	}
+
+	if string(reply) != expected {
+		t.Errorf("reply: %s", reply)
+	}
+
+	// 數據不合法時，伺服器端立即斷線
+	conn.Write([]byte("2:hi!"))
+
+	if _, err = conn.Read(reply); err != io.EOF {
+		t.Errorf("err: %v", err)
+	}
+}
+
+// 回應無法封裝時，伺服器端記錄錯誤並斷線，而非默默丟棄回應
+func TestEncodeError(t *testing.T) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	// 加上 "echo:" 後超過 maxMessageLength
+	message := strings.Repeat("x", maxMessageLength)
+	fmt.Fprintf(conn, "%d:%s,", len(message), message)
+
+	if _, err = conn.Read(make([]byte, 16)); err != io.EOF {
+		t.Errorf("err: %v", err)
+	}
+}
+
+func TestAsker(t *testing.T) {
+	replies := make(chan string, 2)
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+	intro := []byte("intro")
+	a, err := ask.NewAsker(Netstring, 1, laddr, utils.GosConfig.AskerWorkNumbers[Netstring], nil, &intro, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new asker: %+v", err)
+	}
+
+	asker := a.(*ask.CodecAsker)
+	asker.SetWorkHandler(func(w *base.Work) {
+		replies <- string(w.Body.GetData())
+		w.Finish()
+	})
+
+	if err = asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	// 自我介紹亦由 Codec 封裝
+	expect := func(expected string) {
+		select {
+		case reply := <-replies:
+			if reply != expected {
+				t.Errorf("reply: %s, expected: %s", reply, expected)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeout waiting for %s.", expected)
+		}
+	}
+
+	expect("echo:intro")
+	data := []byte("world")
+	loop.Do(func() { asker.Write(&data, int32(len(data))) })
+	expect("echo:world")
+}
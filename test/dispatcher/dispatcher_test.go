@@ -0,0 +1,244 @@
+package test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+const port int = 18945
+
+const (
+	SystemKind byte = 0
+	GameKind   byte = 1
+	ErrorKind  byte = 255
+)
+
+const (
+	LoginService uint16 = 0
+	EchoService  uint16 = 1
+	UpperService uint16 = 2
+	ScoreService uint16 = 3
+)
+
+func reply(c *base.Command, kind byte, service uint16, message string) {
+	c.Body.Clear()
+	c.Body.AddByte(kind)
+	c.Body.AddUInt16(service)
+	c.Body.AddString(message)
+	c.SendTransData()
+}
+
+func TestMain(m *testing.M) {
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewTcp0Anser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new Tcp0Anser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.Tcp0Anser)
+
+	// 已登入的連線
+	logins := map[int32]bool{}
+
+	dispatcher := base.NewDispatcher()
+	dispatcher.Use(func(c *base.Command) {
+		c.Set("trace", "global")
+	})
+	dispatcher.NoCommand = func(c *base.Command) {
+		reply(c, ErrorKind, 0, fmt.Sprintf("unknown(%d, %d)", c.Kind, c.Service))
+	}
+	dispatcher.Handle(SystemKind, LoginService, func(c *base.Command) {
+		logins[c.Index] = true
+		reply(c, SystemKind, LoginService, "ok")
+	})
+
+	// 需登入後才可使用的指令
+	game := dispatcher.Group(GameKind, func(c *base.Command) {
+		if !logins[c.Index] {
+			c.Abort()
+			reply(c, ErrorKind, 0, "unauthorized")
+		}
+	})
+	game.Handle(EchoService, func(c *base.Command) {
+		trace, _ := c.Get("trace")
+		reply(c, GameKind, EchoService, fmt.Sprintf("%s:%s", trace, c.Body.PopString()))
+	})
+	game.Handle(UpperService, func(c *base.Command) {
+		trace, _ := c.Get("trace")
+		c.Set("trace", fmt.Sprintf("%s,upper", trace))
+		c.Next()
+	}, func(c *base.Command) {
+		trace, _ := c.Get("trace")
+		reply(c, GameKind, UpperService, fmt.Sprintf("%s:%s", trace, strings.ToUpper(c.Body.PopString())))
+	})
+
+	// 數據不合法時，回應錯誤
+	dispatcher.Handle(SystemKind, ScoreService, func(c *base.Command) {
+		name := c.Body.PopString()
+		score := c.Body.PopInt32()
+
+		if err := c.Body.Err(); err != nil {
+			reply(c, ErrorKind, ScoreService, "invalid score")
+			return
+		}
+
+		reply(c, SystemKind, ScoreService, fmt.Sprintf("%s:%d", name, score))
+	})
+
+	anser.SetWorkHandler(dispatcher.Dispatch)
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func TestDispatch(t *testing.T) {
+	replies := make(chan string, 10)
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+	a, err := ask.NewTcp0Asker(1, laddr, 1, 10, nil, nil, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new Tcp0Asker: %+v", err)
+	}
+
+	asker := a.(*ask.Tcp0Asker)
+	asker.SetWorkHandler(func(w *base.Work) {
+		kind := w.Body.PopByte()
+		service := w.Body.PopUInt16()
+		replies <- fmt.Sprintf("%d-%d %s", kind, service, w.Body.PopString())
+		w.Finish()
+	})
+
+	if err = asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	send := func(kind byte, service uint16, message string) {
+		td := base.NewTransData()
+		td.AddByte(kind)
+		td.AddUInt16(service)
+		td.AddString(message)
+		loop.Do(func() { asker.WriteFrame(td.GetData()) })
+	}
+
+	expect := func(expected string) {
+		select {
+		case r := <-replies:
+			if r != expected {
+				t.Errorf("reply: %q, expected: %q", r, expected)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeout waiting for %s.", expected)
+		}
+	}
+
+	// 群組的中介函式拒絕未登入的連線
+	send(GameKind, EchoService, "hello")
+	expect("255-0 unauthorized")
+
+	send(SystemKind, LoginService, "player")
+	expect("0-0 ok")
+
+	send(GameKind, EchoService, "hello")
+	expect("1-1 global:hello")
+
+	// 全域、群組與指令的中介函式依序執行
+	send(GameKind, UpperService, "hello")
+	expect("1-2 global,upper:HELLO")
+
+	// 未註冊的指令
+	send(GameKind, 9, "")
+	expect("255-0 unknown(1, 9)")
+
+	// 數據不足以讀取標頭
+	loop.Do(func() { asker.WriteFrame([]byte{GameKind}) })
+	expect("255-0 unknown(0, 0)")
+}
+
+func TestRejectBadPacket(t *testing.T) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	framing := base.NewTcp0Framing()
+
+	// 字串長度超過實際的數據量
+	td := base.NewTransData()
+	td.AddByte(SystemKind)
+	td.AddUInt16(ScoreService)
+	td.AddInt32(1000)
+	td.AddRawData([]byte("abc"))
+	frame, _ := framing.Encode(td.GetData())
+	conn.Write(frame)
+
+	// 伺服器端未崩潰，並回應錯誤
+	header := make([]byte, framing.HeaderSize)
+
+	if _, err = io.ReadFull(conn, header); err != nil {
+		t.Fatalf("Failed to read: %+v", err)
+	}
+
+	data := make([]byte, binary.LittleEndian.Uint32(header))
+
+	if _, err = io.ReadFull(conn, data); err != nil {
+		t.Fatalf("Failed to read: %+v", err)
+	}
+
+	td = base.LoadTransData(data)
+	td.ResetIndex()
+
+	if kind, service, message := td.PopByte(), td.PopUInt16(), td.PopString(); kind != ErrorKind || service != ScoreService || message != "invalid score" {
+		t.Errorf("kind: %d, service: %d, message: %s", kind, service, message)
+	}
+}
+
+// 處理函式鏈於註冊時組合，之後註冊的全域中介函式仍會套用；重複使用的 Command 不保留前次的數據
+func TestReuseCommand(t *testing.T) {
+	d := base.NewDispatcher()
+	traces := []string{}
+	d.Handle(GameKind, EchoService, func(c *base.Command) {
+		_, exists := c.Get("message")
+		traces = append(traces, fmt.Sprintf("%s:%v", c.Body.PopString(), exists))
+		c.Set("message", true)
+		c.Finish()
+	})
+	d.Use(func(c *base.Command) {
+		traces = append(traces, "use")
+	})
+	d.NoCommand = func(c *base.Command) {
+		traces = append(traces, "none")
+		c.Finish()
+	}
+
+	for _, message := range []string{"first", "second"} {
+		w := base.NewWork(0)
+		w.Body.AddByte(GameKind)
+		w.Body.AddUInt16(EchoService)
+		w.Body.AddString(message)
+		d.Dispatch(w)
+	}
+
+	d.Dispatch(base.NewWork(0))
+
+	if trace := strings.Join(traces, ","); trace != "use,first:false,use,second:false,use,none" {
+		t.Errorf("trace: %s", trace)
+	}
+}
@@ -0,0 +1,196 @@
+package test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+const port int = 18941
+
+// 2 bytes、BigEndian、長度含長度前綴，數據上限 64 bytes
+func newFraming() *base.Tcp0Framing {
+	framing := base.NewTcp0Framing()
+	framing.HeaderSize = 2
+	framing.Order = binary.BigEndian
+	framing.IncludeHeader = true
+	framing.MaxFrameSize = 64
+	return framing
+}
+
+func TestMain(m *testing.M) {
+	utils.GosConfig.DisconnectTime = 0
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewTcp0Anser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new Tcp0Anser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.Tcp0Anser)
+
+	if err = anser.SetFraming(newFraming()); err != nil {
+		fmt.Printf("Failed to set framing: %+v\n", err)
+		os.Exit(1)
+	}
+
+	// 回應收到的數據
+	anser.SetWorkHandler(func(w *base.Work) {
+		data := w.Body.GetData()
+		w.Body.Clear()
+		w.Body.AddRawData(append([]byte("echo:"), data...))
+		w.SendTransData()
+	})
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func dial(t *testing.T) net.Conn {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	return conn
+}
+
+func TestRawClient(t *testing.T) {
+	conn := dial(t)
+	defer conn.Close()
+
+	// 分兩次寫出，確認可處理分包
+	conn.Write([]byte{0x00, 0x07, 'h'})
+	time.Sleep(20 * time.Millisecond)
+	conn.Write([]byte("ello"))
+
+	reply := make([]byte, 12)
+
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("Failed to read: %+v", err)
+	}
+
+	if binary.BigEndian.Uint16(reply) != 12 || string(reply[2:]) != "echo:hello" {
+		t.Errorf("reply: %v", reply)
+	}
+}
+
+func TestAsker(t *testing.T) {
+	replies := make(chan string, 1)
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+	a, err := ask.NewTcp0Asker(1, laddr, 1, 10, nil, nil, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new Tcp0Asker: %+v", err)
+	}
+
+	asker := a.(*ask.Tcp0Asker)
+
+	if err = asker.SetFraming(newFraming()); err != nil {
+		t.Fatalf("Failed to set framing: %+v", err)
+	}
+
+	asker.SetWorkHandler(func(w *base.Work) {
+		replies <- string(w.Body.GetData())
+		w.Finish()
+	})
+
+	if err = asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	loop.Do(func() { err = asker.WriteFrame([]byte("hi")) })
+
+	if err != nil {
+		t.Fatalf("Failed to write: %+v", err)
+	}
+
+	select {
+	case reply := <-replies:
+		if reply != "echo:hi" {
+			t.Errorf("reply: %s", reply)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for reply.")
+	}
+
+	// 超過長度上限的數據無法寫出
+	loop.Do(func() { err = asker.WriteFrame(make([]byte, 63)) })
+
+	if err == nil {
+		t.Errorf("Writing an oversized frame should fail.")
+	}
+}
+
+func TestOversizedFrame(t *testing.T) {
+	for _, header := range [][]byte{{0xff, 0xff}, {0x00, 0x01}} {
+		conn := dial(t)
+
+		// 長度超過上限，或短於長度前綴時，伺服器端立即斷線
+		conn.Write(header)
+		buffer := make([]byte, 1)
+
+		if _, err := conn.Read(buffer); err != io.EOF {
+			t.Errorf("header: %v, err: %v", header, err)
+		}
+
+		conn.Close()
+	}
+}
+
+func TestFraming(t *testing.T) {
+	framing := base.NewTcp0Framing()
+	framing.HeaderSize = 8
+	framing.Order = binary.BigEndian
+	frame, err := framing.Encode([]byte("abc"))
+
+	if err != nil || binary.BigEndian.Uint64(frame) != 3 || string(frame[8:]) != "abc" {
+		t.Errorf("frame: %v, err: %v", frame, err)
+	}
+
+	if length, err := framing.DecodeLength(frame[:8]); err != nil || length != 3 {
+		t.Errorf("length: %d, err: %v", length, err)
+	}
+
+	// 8 bytes 的最大長度不可溢位
+	if _, err = framing.DecodeLength([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}); err == nil {
+		t.Errorf("Decoding an oversized length should fail.")
+	}
+
+	// 1 byte 無法表示超過 255 的長度
+	framing = base.NewTcp0Framing()
+	framing.HeaderSize = 1
+
+	if _, err = framing.Encode(make([]byte, 256)); err == nil {
+		t.Errorf("Encoding a length over 255 should fail.")
+	}
+
+	framing.HeaderSize = 3
+
+	if err = framing.Check(); err == nil {
+		t.Errorf("Header size 3 should be invalid.")
+	}
+
+	// 長度上限超過讀取緩存大小
+	framing = base.NewTcp0Framing()
+	framing.MaxFrameSize = 1 << 30
+
+	if _, err = framing.Limit(64 * 1024); err == nil {
+		t.Errorf("Max frame size over the limit should fail.")
+	}
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/base/gproto"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const port int = 18944
+
+const (
+	LoginId  uint16 = 1
+	ResultId uint16 = 2
+)
+
+// 無法處理的訊息 id
+var failures chan uint16
+
+func TestMain(m *testing.M) {
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewTcp0Anser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new Tcp0Anser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.Tcp0Anser)
+	failures = make(chan uint16, 10)
+	messages := gproto.NewMessages()
+	messages.OnError = func(cid int32, id uint16, err error) {
+		failures <- id
+	}
+
+	// 回應名稱的長度
+	gproto.Register(messages, LoginId, func(cid int32, msg *wrapperspb.StringValue) {
+		anser.SendMessage(cid, wrapperspb.Int32(int32(len(msg.Value))))
+	})
+	gproto.Register[*wrapperspb.Int32Value](messages, ResultId, nil)
+	anser.SetMessages(messages)
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func TestRegister(t *testing.T) {
+	messages := gproto.NewMessages()
+
+	if err := gproto.Register[*wrapperspb.StringValue](messages, LoginId, nil); err != nil {
+		t.Fatalf("Failed to register: %+v", err)
+	}
+
+	if err := gproto.Register[*wrapperspb.Int32Value](messages, LoginId, nil); err == nil {
+		t.Errorf("Registering an id twice should fail.")
+	}
+
+	if err := gproto.Register[*wrapperspb.StringValue](messages, ResultId, nil); err == nil {
+		t.Errorf("Registering a message type twice should fail.")
+	}
+
+	data, err := messages.Marshal(wrapperspb.String("gos"))
+
+	if err != nil {
+		t.Fatalf("Failed to marshal: %+v", err)
+	}
+
+	id, msg, err := messages.Unmarshal(data)
+
+	if err != nil || id != LoginId || msg.(*wrapperspb.StringValue).Value != "gos" {
+		t.Errorf("id: %d, msg: %v, err: %v", id, msg, err)
+	}
+
+	if _, err = messages.Marshal(wrapperspb.Bool(true)); err == nil {
+		t.Errorf("Marshaling an unregistered message should fail.")
+	}
+}
+
+func TestAsker(t *testing.T) {
+	results := make(chan int32, 1)
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+	a, err := ask.NewTcp0Asker(1, laddr, 1, 10, nil, nil, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new Tcp0Asker: %+v", err)
+	}
+
+	asker := a.(*ask.Tcp0Asker)
+	messages := gproto.NewMessages()
+	gproto.Register[*wrapperspb.StringValue](messages, LoginId, nil)
+	gproto.Register(messages, ResultId, func(cid int32, msg *wrapperspb.Int32Value) {
+		results <- msg.Value
+	})
+	gproto.Register[*wrapperspb.BoolValue](messages, 99, nil)
+	asker.SetMessages(messages)
+
+	if err = asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	loop.Do(func() { err = asker.SendMessage(wrapperspb.String("player")) })
+
+	if err != nil {
+		t.Fatalf("Failed to send: %+v", err)
+	}
+
+	select {
+	case result := <-results:
+		if result != 6 {
+			t.Errorf("result: %d", result)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for result.")
+	}
+
+	// 伺服器端未註冊的 id
+	loop.Do(func() { asker.SendMessage(wrapperspb.Bool(true)) })
+
+	// 伺服器端已註冊、但沒有處理函式的 id
+	loop.Do(func() { asker.SendMessage(wrapperspb.Int32(1)) })
+
+	// 數據不合法
+	td := base.NewTransData()
+	td.AddUInt16(LoginId)
+	td.AddByte(0xff)
+	loop.Do(func() { asker.WriteFrame(td.GetData()) })
+
+	for _, expected := range []uint16{99, ResultId, LoginId} {
+		select {
+		case id := <-failures:
+			if id != expected {
+				t.Errorf("id: %d, expected: %d", id, expected)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeout waiting for failure of %d.", expected)
+		}
+	}
+}
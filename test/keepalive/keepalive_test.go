@@ -0,0 +1,159 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+const port int = 18949
+
+// 單一連線的請求數上限
+const maxRequests int32 = 3
+
+// 閒置多久未收到新的請求即斷線
+const idleTimeout time.Duration = 300 * time.Millisecond
+
+func TestMain(m *testing.M) {
+	utils.GosConfig.HttpMaxKeepAliveRequests = maxRequests
+	utils.GosConfig.HttpKeepAliveTimeout = idleTimeout
+	// 標註斷線後立即關閉連線，以便檢查伺服器是否已斷線
+	utils.GosConfig.DisconnectTime = 0
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.HttpAnser)
+	anser.GET("/echo/<n>", func(c *ghttp.Context) {
+		c.Json(ghttp.StatusOK, c.Params["n"])
+	})
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func dial(t *testing.T) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	return conn, bufio.NewReader(conn)
+}
+
+func request(n int) string {
+	return fmt.Sprintf("GET /echo/%d HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n", n)
+}
+
+// 讀取一個回應，並檢查 Body(JSON 字串)與是否維持連線(Connection: close 將反映於 res.Close)
+func expect(t *testing.T, reader *bufio.Reader, body string, keepAlive bool) {
+	res, err := http.ReadResponse(reader, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to read response %s: %+v", body, err)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to read body %s: %+v", body, err)
+	}
+
+	if string(data) != strconv.Quote(body) || res.Close == keepAlive {
+		t.Errorf("body: %q, keep-alive: %v, expected: %q, %v", data, !res.Close, body, keepAlive)
+	}
+}
+
+// 讀取到 EOF(或連線被重置)，表示伺服器已關閉連線
+func expectClosed(t *testing.T, reader *bufio.Reader) {
+	if _, err := reader.ReadByte(); err == nil {
+		t.Error("Connection should be closed.")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Errorf("Timeout waiting for connection to be closed: %+v", err)
+	}
+}
+
+// 同一連線上依序送出多個請求，達到請求數上限時回應 Connection: close 並斷線
+func TestSequential(t *testing.T) {
+	conn, reader := dial(t)
+	defer conn.Close()
+
+	for i := 1; i <= int(maxRequests); i++ {
+		if _, err := conn.Write([]byte(request(i))); err != nil {
+			t.Fatalf("Failed to write request %d: %+v", i, err)
+		}
+
+		expect(t, reader, fmt.Sprint(i), i < int(maxRequests))
+	}
+
+	expectClosed(t, reader)
+}
+
+// 一次送出多個請求(pipelining)，依序回應
+func TestPipelined(t *testing.T) {
+	conn, reader := dial(t)
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(request(1) + request(2))); err != nil {
+		t.Fatalf("Failed to write requests: %+v", err)
+	}
+
+	expect(t, reader, "1", true)
+	expect(t, reader, "2", true)
+
+	// 連線仍可繼續使用
+	if _, err := conn.Write([]byte(request(3))); err != nil {
+		t.Fatalf("Failed to write request 3: %+v", err)
+	}
+
+	expect(t, reader, "3", false)
+	expectClosed(t, reader)
+}
+
+// 閒置超過 HttpKeepAliveTimeout 未送出新的請求，伺服器將關閉連線
+func TestIdleTimeout(t *testing.T) {
+	conn, reader := dial(t)
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(request(1))); err != nil {
+		t.Fatalf("Failed to write request: %+v", err)
+	}
+
+	expect(t, reader, "1", true)
+	start := time.Now()
+	expectClosed(t, reader)
+
+	if elapsed := time.Since(start); elapsed < idleTimeout/2 {
+		t.Errorf("Connection closed too early: %v", elapsed)
+	}
+}
+
+// 請求指定 Connection: close 時，回應後即斷線
+func TestConnectionClose(t *testing.T) {
+	conn, reader := dial(t)
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("GET /echo/1 HTTP/1.1\r\nHost: 127.0.0.1\r\nConnection: close\r\n\r\n")); err != nil {
+		t.Fatalf("Failed to write request: %+v", err)
+	}
+
+	expect(t, reader, "1", false)
+	expectClosed(t, reader)
+}
@@ -0,0 +1,253 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+const port int = 18943
+
+// 測試閒置超時的 LineAnser 所使用的埠
+const timeoutPort int = 18950
+
+func TestMain(m *testing.M) {
+	utils.GosConfig.DisconnectTime = 0
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewAnser(define.Line, laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new LineAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.LineAnser)
+	format := base.NewLineFormat()
+	format.WriteCR = true
+	format.MaxLineLength = 32
+
+	if err = anser.SetLineFormat(format); err != nil {
+		fmt.Printf("Failed to set line format: %+v\n", err)
+		os.Exit(1)
+	}
+
+	// 以指令的形式回應
+	anser.SetWorkHandler(func(w *base.Work) {
+		command, arg, _ := strings.Cut(string(w.Body.GetData()), " ")
+
+		switch command {
+		case "echo":
+			anser.WriteLine(w.Index, arg)
+			w.Finish()
+		case "upper":
+			w.Body.Clear()
+			w.Body.AddRawData([]byte(strings.ToUpper(arg)))
+			w.Send()
+		case "twice":
+			w.Body.Clear()
+			w.Body.AddRawData([]byte(arg + arg))
+			w.Send()
+		default:
+			anser.WriteLine(w.Index, "unknown command: "+command)
+			w.Finish()
+		}
+	})
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func TestRawClient(t *testing.T) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	// 同時接受 "\r\n" 與 "\n"，並可處理分包
+	conn.Write([]byte("echo hello\r\nupp"))
+	time.Sleep(20 * time.Millisecond)
+	conn.Write([]byte("er world\n"))
+
+	for _, expected := range []string{"hello\r\n", "WORLD\r\n"} {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			t.Fatalf("Failed to read: %+v", err)
+		}
+
+		if line != expected {
+			t.Errorf("line: %q, expected: %q", line, expected)
+		}
+	}
+}
+
+func TestLineTooLong(t *testing.T) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	// 超過長度上限仍未出現分隔字元時，伺服器端立即斷線
+	conn.Write([]byte(strings.Repeat("x", 40)))
+	buffer := make([]byte, 1)
+
+	if _, err = conn.Read(buffer); err != io.EOF {
+		t.Errorf("err: %v", err)
+	}
+}
+
+// 回應的行超過長度上限而無法封裝時，伺服器端記錄錯誤並斷線
+func TestOutputTooLong(t *testing.T) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	reader := bufio.NewReader(conn)
+	conn.Write([]byte("twice abc\n"))
+
+	if line, err := reader.ReadString('\n'); err != nil || line != "abcabc\r\n" {
+		t.Errorf("line: %q, err: %v", line, err)
+	}
+
+	conn.Write([]byte("twice " + strings.Repeat("x", 20) + "\n"))
+
+	if _, err = reader.ReadByte(); err != io.EOF {
+		t.Errorf("err: %v", err)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	// 預設不超時，閒置後仍可繼續使用
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	time.Sleep(200 * time.Millisecond)
+	conn.Write([]byte("echo idle\n"))
+
+	if line, err := bufio.NewReader(conn).ReadString('\n'); err != nil || line != "idle\r\n" {
+		t.Errorf("line: %q, err: %v", line, err)
+	}
+
+	// 設置 LineReadTimeout 後，閒置超過時間即斷線
+	utils.GosConfig.LineReadTimeout = 100 * time.Millisecond
+	defer func() { utils.GosConfig.LineReadTimeout = 0 }()
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", timeoutPort))
+	a, err := ans.NewLineAnser(laddr, 1, 1)
+
+	if err != nil {
+		t.Fatalf("Failed to new LineAnser: %+v", err)
+	}
+
+	loop := testutil.Serve(a)
+	defer func() {
+		loop.Stop()
+		a.StopListen()
+	}()
+
+	idle, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", timeoutPort))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer idle.Close()
+	idle.SetDeadline(time.Now().Add(3 * time.Second))
+
+	if _, err = idle.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("err: %v", err)
+	}
+}
+
+func TestAsker(t *testing.T) {
+	replies := make(chan string, 2)
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+	intro := []byte("echo hi")
+	a, err := ask.NewAsker(define.Line, 1, laddr, 10, nil, &intro, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new LineAsker: %+v", err)
+	}
+
+	asker := a.(*ask.LineAsker)
+	asker.SetWorkHandler(func(w *base.Work) {
+		replies <- string(w.Body.GetData())
+		w.Finish()
+	})
+
+	if err = asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	expect := func(expected string) {
+		select {
+		case reply := <-replies:
+			if reply != expected {
+				t.Errorf("reply: %q, expected: %q", reply, expected)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeout waiting for %s.", expected)
+		}
+	}
+
+	// 回應的 "\r\n" 已被移除
+	expect("hi")
+	loop.Do(func() { asker.WriteLine("status") })
+	expect("unknown command: status")
+
+	// 訊息中不可包含分隔字元
+	loop.Do(func() { err = asker.WriteLine("a\nb") })
+
+	if err == nil {
+		t.Errorf("Writing a line with the delimiter should fail.")
+	}
+}
+
+func TestLineFormat(t *testing.T) {
+	format := base.NewLineFormat()
+	format.Delimiter = ';'
+	format.MaxLineLength = 4
+
+	if data, err := format.Encode([]byte("ab")); err != nil || string(data) != "ab;" {
+		t.Errorf("data: %q, err: %v", data, err)
+	}
+
+	if _, err := format.Encode([]byte("abcde")); err == nil {
+		t.Errorf("Encoding an oversized line should fail.")
+	}
+
+	if err := format.Check(5); err == nil {
+		t.Errorf("Max line length over the limit should fail.")
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/middleware"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+const port int = 18932
+
+func TestMain(m *testing.M) {
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser := a.(*ans.HttpAnser)
+	config := middleware.DefaultCorsConfig()
+	config.AllowOrigins = []string{"http://example.com"}
+	anser.Use(middleware.RequestId(), middleware.AccessLog(), middleware.Recovery(true))
+
+	r := anser.NewRouter("/api", middleware.Cors(config), middleware.Gzip(-1))
+	r.GET("/text", func(c *ghttp.Context) {
+		c.Json(ghttp.StatusOK, ghttp.H{
+			"text":       strings.Repeat("gos ", 200),
+			"request_id": middleware.GetRequestId(c),
+		})
+	})
+	r.POST("/panic", func(c *ghttp.Context) {
+		panic("boom")
+	})
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func do(t *testing.T, method string, path string, header map[string]string) (*http.Response, []byte) {
+	req, _ := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	res, err := http.DefaultTransport.RoundTrip(req)
+
+	if err != nil {
+		t.Fatalf("Failed to %s %s: %+v", method, path, err)
+	}
+
+	defer res.Body.Close()
+	bs, _ := io.ReadAll(res.Body)
+	return res, bs
+}
+
+func TestCorsPreflight(t *testing.T) {
+	res, _ := do(t, http.MethodOptions, "/api/text", map[string]string{
+		"Origin":                         "http://example.com",
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "X-Token",
+	})
+
+	if res.StatusCode != http.StatusNoContent ||
+		res.Header.Get("Access-Control-Allow-Origin") != "http://example.com" ||
+		res.Header.Get("Access-Control-Allow-Headers") != "X-Token" ||
+		res.Header.Get("Access-Control-Max-Age") != "43200" {
+		t.Errorf("code: %d, header: %+v", res.StatusCode, res.Header)
+	}
+
+	res, _ = do(t, http.MethodOptions, "/api/text", map[string]string{
+		"Origin":                        "http://evil.com",
+		"Access-Control-Request-Method": "GET",
+	})
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+
+	// 非預檢請求，仍由自動生成的 OPTIONS 回應處理
+	res, _ = do(t, http.MethodOptions, "/api/text", nil)
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("Allow") != "OPTIONS, GET" {
+		t.Errorf("code: %d, header: %+v", res.StatusCode, res.Header)
+	}
+}
+
+func TestGzipAndRequestId(t *testing.T) {
+	res, bs := do(t, http.MethodGet, "/api/text", map[string]string{
+		"Origin":                   "http://example.com",
+		"Accept-Encoding":          "gzip",
+		middleware.RequestIdHeader: "abc",
+	})
+
+	if res.StatusCode != http.StatusOK ||
+		res.Header.Get("Content-Encoding") != "gzip" ||
+		res.Header.Get("Access-Control-Allow-Origin") != "http://example.com" ||
+		res.Header.Get(middleware.RequestIdHeader) != "abc" ||
+		len(bs) >= 800 {
+		t.Errorf("code: %d, length: %d, header: %+v", res.StatusCode, len(bs), res.Header)
+	}
+
+	res, bs = do(t, http.MethodGet, "/api/text", map[string]string{
+		"Accept-Encoding": "gzip;q=0",
+	})
+	result := map[string]string{}
+	json.Unmarshal(bs, &result)
+
+	if res.Header.Get("Content-Encoding") != "" ||
+		result["request_id"] == "" ||
+		result["request_id"] != res.Header.Get(middleware.RequestIdHeader) {
+		t.Errorf("header: %+v, result: %+v", res.Header, result)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	res, bs := do(t, http.MethodPost, "/api/panic", nil)
+	result := map[string]string{}
+	json.Unmarshal(bs, &result)
+
+	if res.StatusCode != http.StatusInternalServerError || result["panic"] != "boom" || result["stack"] == "" {
+		t.Errorf("code: %d, result: %+v", res.StatusCode, result)
+	}
+}
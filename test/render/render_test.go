@@ -0,0 +1,167 @@
+package test
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/base/ghttp"
+)
+
+func body(c *ghttp.Context) string {
+	return string(c.Response.Body[:c.Response.BodyLength])
+}
+
+// 執行串流直到結束，返回所有寫出的數據
+func stream(c *ghttp.Context) string {
+	var buffer bytes.Buffer
+
+	for c.StreamStep(&buffer) {
+	}
+
+	return buffer.String()
+}
+
+func TestJsonPreservesHeaders(t *testing.T) {
+	c := ghttp.NewContext(0)
+	c.Response.Header.Set("Access-Control-Allow-Origin", "*")
+	c.Response.Header.Set("Cache-Control", "no-store")
+	c.Json(ghttp.StatusOK, ghttp.H{"ok": true})
+
+	if c.Response.Header.Get("Access-Control-Allow-Origin") != "*" ||
+		c.Response.Header.Get("Cache-Control") != "no-store" ||
+		c.Response.Header.Get("Content-Type") != ghttp.MIMEJson ||
+		c.Response.Header.Get("Content-Length") != "11" {
+		t.Errorf("header: %+v", c.Response.Header)
+	}
+}
+
+func TestString(t *testing.T) {
+	c := ghttp.NewContext(0)
+	c.String(ghttp.StatusOK, "hello %s, %d%%", "gos", 100)
+
+	if body(c) != "hello gos, 100%" || c.Response.Header.Get("Content-Type") != ghttp.MIMEPlain {
+		t.Errorf("body: %s, header: %+v", body(c), c.Response.Header)
+	}
+}
+
+func TestData(t *testing.T) {
+	c := ghttp.NewContext(0)
+	c.Data(ghttp.StatusCreated, "application/octet-stream", []byte{1, 2, 3})
+
+	if c.Code != ghttp.StatusCreated || c.Response.BodyLength != 3 || c.Response.Header.Get("Content-Length") != "3" {
+		t.Errorf("code: %d, header: %+v", c.Code, c.Response.Header)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	c := ghttp.NewContext(0)
+	c.Redirect(ghttp.StatusFound, "/login")
+
+	if c.Code != ghttp.StatusFound || c.Response.Header.Get("Location") != "/login" || c.Response.BodyLength != 0 {
+		t.Errorf("code: %d, header: %+v", c.Code, c.Response.Header)
+	}
+
+	c = ghttp.NewContext(0)
+	c.Redirect(ghttp.StatusOK, "/login")
+
+	if c.Code != ghttp.StatusInternalServerError {
+		t.Errorf("code: %d", c.Code)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "style.css")
+	os.WriteFile(path, []byte("body{}"), 0o644)
+
+	c := ghttp.NewContext(0)
+	c.File(path)
+
+	if c.Code != ghttp.StatusOK || !c.IsStreaming() || stream(c) != "body{}" ||
+		!strings.HasPrefix(c.Response.Header.Get("Content-Type"), "text/css") ||
+		c.Response.Header.Get("Content-Length") != "6" {
+		t.Errorf("code: %d, header: %+v", c.Code, c.Response.Header)
+	}
+
+	// 大於串流緩衝區的檔案分多次寫出
+	path = filepath.Join(dir, "large.txt")
+	large := strings.Repeat("0123456789", 10000)
+	os.WriteFile(path, []byte(large), 0o644)
+	c = ghttp.NewContext(0)
+	c.File(path)
+
+	if data := stream(c); data != large || c.Response.Header.Get("Content-Length") != "100000" {
+		t.Errorf("length: %d, header: %+v", len(data), c.Response.Header)
+	}
+
+	// 無法由副檔名判斷時，根據內容判斷
+	path = filepath.Join(dir, "image")
+	os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n0000"), 0o644)
+	c = ghttp.NewContext(0)
+	c.File(path)
+
+	if c.Response.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("header: %+v", c.Response.Header)
+	}
+
+	c = ghttp.NewContext(0)
+	c.File(filepath.Join(dir, "missing"))
+
+	if c.Code != ghttp.StatusNotFound {
+		t.Errorf("code: %d", c.Code)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	c := ghttp.NewContext(0)
+	c.SetHTMLTemplate(template.Must(template.New("index.html").Parse(`<p>{{ .Name }}</p>`)))
+	c.HTML(ghttp.StatusOK, "index.html", ghttp.H{"Name": "<gos>"})
+
+	if body(c) != "<p>&lt;gos&gt;</p>" || c.Response.Header.Get("Content-Type") != ghttp.MIMEHTML {
+		t.Errorf("body: %s, header: %+v", body(c), c.Response.Header)
+	}
+}
+
+func TestSetCookie(t *testing.T) {
+	c := ghttp.NewContext(0)
+	c.SetCookie(&ghttp.Cookie{
+		Name:     "session",
+		Value:    "abc;def",
+		Path:     "/",
+		Domain:   ".example.com",
+		Expires:  time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		MaxAge:   3600,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: ghttp.SameSiteLaxMode,
+	})
+	c.SetCookie(&ghttp.Cookie{Name: "theme", Value: "dark", MaxAge: -1})
+	c.SetCookie(&ghttp.Cookie{Name: "bad name", Value: "x"})
+
+	expected := []string{
+		"session=abcdef; Path=/; Domain=example.com; Expires=Wed, 02 Jan 2030 03:04:05 GMT; Max-Age=3600; HttpOnly; Secure; SameSite=Lax",
+		"theme=dark; Max-Age=0",
+	}
+
+	if strings.Join(c.Response.Header["Set-Cookie"], "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Set-Cookie: %q", c.Response.Header["Set-Cookie"])
+	}
+}
+
+func TestSetCookieHeaderLines(t *testing.T) {
+	c := ghttp.NewContext(0)
+	c.Status(ghttp.StatusOK)
+	c.SetCookie(&ghttp.Cookie{Name: "a", Value: "1", Expires: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)})
+	c.SetCookie(&ghttp.Cookie{Name: "b", Value: "2"})
+	data := string(c.ToResponseData())
+
+	if !strings.Contains(data, "\r\nSet-Cookie: a=1; Expires=Wed, 02 Jan 2030 03:04:05 GMT\r\n") ||
+		!strings.Contains(data, "\r\nSet-Cookie: b=2\r\n") {
+		t.Errorf("data: %q", data)
+	}
+}
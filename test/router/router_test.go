@@ -0,0 +1,253 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+const port int = 18931
+
+var anser *ans.HttpAnser
+
+func TestMain(m *testing.M) {
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser = a.(*ans.HttpAnser)
+	anser.Use(func(c *ghttp.Context) {
+		c.Next()
+		// 後續處理函式執行完畢後才會執行
+		c.Response.SetHeader("X-After", "done")
+	}, func(c *ghttp.Context) {
+		if strings.HasPrefix(c.Query, "/private") && c.Request.Header.Get("Authorization") == "" {
+			c.AbortWithStatusJSON(ghttp.StatusUnauthorized, ghttp.H{
+				"error": "Unauthorized",
+			})
+		}
+	})
+	register(anser.Router)
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func register(router *ans.Router) {
+	reply := func(name string) ans.HandlerFunc {
+		return func(c *ghttp.Context) {
+			c.Json(ghttp.StatusOK, ghttp.H{
+				"route":  name,
+				"params": c.Params,
+			})
+		}
+	}
+	router.GET("/", reply("root"))
+	router.GET("/user/new", reply("new"))
+	router.GET("/user/<id int>", reply("int"))
+	router.GET("/user/<name>", reply("string"))
+	router.POST("/user/<name>", reply("post"))
+	router.GET("/user/<id int>/books", reply("books"))
+	router.GET("/static/*filepath", reply("static"))
+	router.GET("/private/data", reply("private"))
+	router.DELETE("/files/<name>", reply("delete"))
+	router.PUT("/files/*path", reply("upload"))
+	router.GET("/typed/<i int>/<u uint>/<f float>", func(c *ghttp.Context) {
+		i, err1 := c.ParamInt("i")
+		u, err2 := c.ParamUint("u")
+		f, err3 := c.ParamFloat("f")
+		c.Json(ghttp.StatusOK, ghttp.H{
+			"route":  "typed",
+			"params": ghttp.H{"sum": fmt.Sprintf("%v", float64(i)+float64(u)+f), "ok": fmt.Sprint(err1 == nil && err2 == nil && err3 == nil)},
+		})
+	})
+}
+
+type result struct {
+	Route  string            `json:"route"`
+	Params map[string]string `json:"params"`
+}
+
+func request(t *testing.T, method string, path string) (int, *result) {
+	req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new request: %+v", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("Failed to %s %s: %+v", method, path, err)
+	}
+
+	defer res.Body.Close()
+	bs, _ := io.ReadAll(res.Body)
+	r := &result{}
+	json.Unmarshal(bs, r)
+	return res.StatusCode, r
+}
+
+func TestStaticBeforeParam(t *testing.T) {
+	code, r := request(t, http.MethodGet, "/user/new")
+
+	if code != http.StatusOK || r.Route != "new" {
+		t.Errorf("code: %d, route: %s", code, r.Route)
+	}
+}
+
+func TestTypedParamBeforeString(t *testing.T) {
+	code, r := request(t, http.MethodGet, "/user/42")
+
+	if code != http.StatusOK || r.Route != "int" || r.Params["id"] != "42" {
+		t.Errorf("code: %d, route: %s, params: %+v", code, r.Route, r.Params)
+	}
+
+	code, r = request(t, http.MethodGet, "/user/alice")
+
+	if code != http.StatusOK || r.Route != "string" || r.Params["name"] != "alice" {
+		t.Errorf("code: %d, route: %s, params: %+v", code, r.Route, r.Params)
+	}
+}
+
+func TestBacktrackByMethod(t *testing.T) {
+	// /user/new 只有 GET，POST 應退回由 /user/<name> 處理
+	code, r := request(t, http.MethodPost, "/user/new")
+
+	if code != http.StatusOK || r.Route != "post" {
+		t.Errorf("code: %d, route: %s", code, r.Route)
+	}
+}
+
+func TestNestedParam(t *testing.T) {
+	code, r := request(t, http.MethodGet, "/user/7/books")
+
+	if code != http.StatusOK || r.Route != "books" || r.Params["id"] != "7" {
+		t.Errorf("code: %d, route: %s, params: %+v", code, r.Route, r.Params)
+	}
+}
+
+func TestCatchAll(t *testing.T) {
+	code, r := request(t, http.MethodGet, "/static/css/app.css")
+
+	if code != http.StatusOK || r.Route != "static" || r.Params["filepath"] != "css/app.css" {
+		t.Errorf("code: %d, route: %s, params: %+v", code, r.Route, r.Params)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	code, _ := request(t, http.MethodGet, "/unknown/path")
+
+	if code != http.StatusNotFound {
+		t.Errorf("code: %d", code)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	code, _ := request(t, http.MethodDelete, "/user/42")
+
+	if code != http.StatusMethodNotAllowed {
+		t.Errorf("code: %d", code)
+	}
+}
+
+func TestAllowFromAllMatches(t *testing.T) {
+	cases := []struct {
+		path  string
+		allow string
+	}{
+		// /user/<id int> 與 /user/<name>
+		{"/user/42", "OPTIONS, GET, POST"},
+		// /files/<name> 與 /files/*path
+		{"/files/a.txt", "OPTIONS, DELETE, PUT"},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("http://127.0.0.1:%d%s", port, c.path), nil)
+		res, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			t.Fatalf("Failed to PATCH %s: %+v", c.path, err)
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusMethodNotAllowed || res.Header.Get("Allow") != c.allow {
+			t.Errorf("path: %s, code: %d, Allow: %q, expected: %q", c.path, res.StatusCode, res.Header.Get("Allow"), c.allow)
+		}
+	}
+}
+
+func TestTypedAccessors(t *testing.T) {
+	code, r := request(t, http.MethodGet, "/typed/-2/3/0.5")
+
+	if code != http.StatusOK || r.Params["sum"] != "1.5" || r.Params["ok"] != "true" {
+		t.Errorf("code: %d, params: %+v", code, r.Params)
+	}
+}
+
+func TestParamsPerRequest(t *testing.T) {
+	request(t, http.MethodGet, "/user/42")
+	_, r := request(t, http.MethodGet, "/user/alice")
+
+	if _, ok := r.Params["id"]; ok {
+		t.Errorf("Param id leaks from previous request: %+v", r.Params)
+	}
+
+	// 路徑參數優先於同名的 GET 參數
+	_, r = request(t, http.MethodGet, "/user/bob?name=mallory")
+
+	if r.Params["name"] != "bob" {
+		t.Errorf("Path param is overridden: %+v", r.Params)
+	}
+}
+
+func TestMiddlewareAbort(t *testing.T) {
+	code, r := request(t, http.MethodGet, "/private/data")
+
+	if code != http.StatusUnauthorized || r.Route != "" {
+		t.Errorf("code: %d, route: %s", code, r.Route)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/private/data", port), nil)
+	req.Header.Set("Authorization", "token")
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("Failed to GET /private/data: %+v", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+}
+
+func TestMiddlewareAfterNext(t *testing.T) {
+	for _, path := range []string{"/user/42", "/unknown/path"} {
+		res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+
+		if err != nil {
+			t.Fatalf("Failed to GET %s: %+v", path, err)
+		}
+
+		res.Body.Close()
+
+		if res.Header.Get("X-After") != "done" {
+			t.Errorf("path: %s, header: %+v", path, res.Header)
+		}
+	}
+}
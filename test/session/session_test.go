@@ -0,0 +1,264 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/middleware"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+const port int = 18935
+
+var anser *ans.HttpAnser
+var memoryStore *ghttp.MemoryStore
+
+func TestMain(m *testing.M) {
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser = a.(*ans.HttpAnser)
+	memoryStore = ghttp.NewMemoryStore(time.Hour)
+	cookieStore, err := ghttp.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"), []byte("abcdef0123456789"))
+
+	if err != nil {
+		fmt.Printf("Failed to new CookieStore: %+v\n", err)
+		os.Exit(1)
+	}
+
+	register(anser.NewRouter("/memory", middleware.Sessions("sid", memoryStore)))
+	register(anser.NewRouter("/cookie", middleware.Sessions("session", cookieStore)))
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func register(router *ans.Router) {
+	router.POST("/login", func(c *ghttp.Context) {
+		session := c.Session()
+		session.Regenerate()
+		session.Set("user", c.Request.Header.Get("X-User"))
+		c.Json(ghttp.StatusOK, ghttp.H{"ok": true})
+	})
+	router.GET("/me", func(c *ghttp.Context) {
+		user, ok := c.Session().Get("user")
+
+		if !ok {
+			c.Json(ghttp.StatusUnauthorized, ghttp.H{"error": "Unauthorized"})
+			return
+		}
+
+		c.String(ghttp.StatusOK, "%v", user)
+	})
+	router.POST("/logout", func(c *ghttp.Context) {
+		c.Session().Destroy()
+		c.Json(ghttp.StatusOK, ghttp.H{"ok": true})
+	})
+}
+
+func send(t *testing.T, client *http.Client, method string, path string, user string) (*http.Response, string) {
+	req, _ := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		t.Fatalf("Failed to %s %s: %+v", method, path, err)
+	}
+
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return res, string(data)
+}
+
+func testLoginFlow(t *testing.T, prefix string) {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	if res, _ := send(t, client, http.MethodGet, prefix+"/me", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("code: %d", res.StatusCode)
+	}
+
+	res, _ := send(t, client, http.MethodPost, prefix+"/login", "alice")
+	setCookie := res.Header.Get("Set-Cookie")
+
+	if res.StatusCode != http.StatusOK || !strings.Contains(setCookie, "HttpOnly") ||
+		!strings.Contains(setCookie, "SameSite=Lax") || !strings.Contains(setCookie, "Path=/") {
+		t.Fatalf("code: %d, Set-Cookie: %s", res.StatusCode, setCookie)
+	}
+
+	res, body := send(t, client, http.MethodGet, prefix+"/me", "")
+
+	if res.StatusCode != http.StatusOK || body != "alice" {
+		t.Fatalf("code: %d, body: %s", res.StatusCode, body)
+	}
+
+	// 未修改 Session 時，不重新設置 Cookie
+	if res.Header.Get("Set-Cookie") != "" {
+		t.Errorf("Set-Cookie: %s", res.Header.Get("Set-Cookie"))
+	}
+
+	res, _ = send(t, client, http.MethodPost, prefix+"/logout", "")
+
+	if !strings.Contains(res.Header.Get("Set-Cookie"), "Max-Age=0") {
+		t.Errorf("Set-Cookie: %s", res.Header.Get("Set-Cookie"))
+	}
+
+	if res, _ = send(t, client, http.MethodGet, prefix+"/me", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testLoginFlow(t, "/memory")
+}
+
+func TestCookieStore(t *testing.T) {
+	testLoginFlow(t, "/cookie")
+}
+
+// 竄改或偽造的 Cookie 不被接受
+func TestForgedCookie(t *testing.T) {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	res, _ := send(t, client, http.MethodPost, "/cookie/login", "bob")
+	value := res.Cookies()[0].Value
+	tampered := []byte(value)
+	tampered[10] ^= 1
+
+	for path, cookie := range map[string]string{
+		"/cookie/me": "session=" + string(tampered),
+		"/memory/me": "sid=not-issued-by-server",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+		req.Header.Set("Cookie", cookie)
+		res, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			t.Fatalf("Failed to GET %s: %+v", path, err)
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("path: %s, code: %d", path, res.StatusCode)
+		}
+	}
+}
+
+// 登入時更換 Session 唯一碼，舊的唯一碼失效
+func TestRegenerate(t *testing.T) {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	res, _ := send(t, client, http.MethodPost, "/memory/login", "carol")
+	oldId := res.Cookies()[0].Value
+	res, _ = send(t, client, http.MethodPost, "/memory/login", "carol")
+	newId := res.Cookies()[0].Value
+
+	if oldId == newId {
+		t.Fatalf("Session id is not regenerated.")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/memory/me", port), nil)
+	req.Header.Set("Cookie", "sid="+oldId)
+	res, _ = http.DefaultClient.Do(req)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	store := ghttp.NewMemoryStore(50 * time.Millisecond)
+	c := ghttp.NewContext(0)
+	session, _ := store.Get(c, "sid")
+	session.Set("k", "v")
+
+	if err := store.Save(c, session); err != nil || store.Len() != 1 {
+		t.Fatalf("err: %+v, len: %d", err, store.Len())
+	}
+
+	cookie, _ := ghttp.ParseSetCookie(c.Response.Header.Get("Set-Cookie"))
+
+	if cookie.MaxAge != 0 && cookie.MaxAge != -1 {
+		t.Errorf("MaxAge of sub-second ttl: %d", cookie.MaxAge)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	c2 := ghttp.NewContext(1)
+	c2.Request.Header.Set("Cookie", "sid="+session.ID)
+	session, err := store.Get(c2, "sid")
+
+	if err == nil || !session.IsNew || store.Len() != 0 {
+		t.Errorf("err: %+v, IsNew: %v, len: %d", err, session.IsNew, store.Len())
+	}
+}
+
+func TestRequestCookies(t *testing.T) {
+	c := ghttp.NewContext(0)
+	c.Request.Header.Add("Cookie", `a=1; b="quoted value"; in valid=x`)
+	c.Request.Header.Add("Cookie", "c=3")
+	cookies := c.Request.Cookies()
+
+	if len(cookies) != 3 || cookies[1].Value != "quoted value" || cookies[2].Name != "c" {
+		t.Fatalf("cookies: %+v", cookies)
+	}
+
+	if cookie, err := c.Request.Cookie("b"); err != nil || cookie.Value != "quoted value" {
+		t.Errorf("cookie: %+v, err: %+v", cookie, err)
+	}
+
+	if _, err := c.Request.Cookie("missing"); err != ghttp.ErrNoCookie {
+		t.Errorf("err: %+v", err)
+	}
+}
+
+func TestParseSetCookie(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	origin := &ghttp.Cookie{
+		Name:     "token",
+		Value:    "abc",
+		Path:     "/api",
+		Domain:   "example.com",
+		Expires:  expires,
+		MaxAge:   3600,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: ghttp.SameSiteStrictMode,
+	}
+	cookie, err := ghttp.ParseSetCookie(origin.String())
+
+	if err != nil || *cookie != *origin {
+		t.Errorf("cookie: %+v, err: %+v", cookie, err)
+	}
+
+	if _, err = ghttp.ParseSetCookie("=novalue"); err == nil {
+		t.Errorf("Invalid cookie should fail.")
+	}
+
+	r := ghttp.NewContext(0).Response
+	r.Header.Add("Set-Cookie", "a=1; Max-Age=0")
+	r.Header.Add("Set-Cookie", "b=2; Path=/; SameSite=None; Secure")
+	cookies := r.ResponseCookies()
+
+	if len(cookies) != 2 || cookies[0].MaxAge != -1 || cookies[1].SameSite != ghttp.SameSiteNoneMode || !cookies[1].Secure {
+		t.Errorf("cookies: %+v", cookies)
+	}
+}
@@ -0,0 +1,164 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+const port int = 18939
+
+var streams []*ghttp.SSEStream
+
+var loop *testutil.Loop
+var disconnected chan bool
+
+func TestMain(m *testing.M) {
+	utils.GosConfig.DisconnectTime = 0
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	disconnected = make(chan bool, 10)
+	anser := a.(*ans.HttpAnser)
+	anser.GET("/events", func(c *ghttp.Context) {
+		stream := c.SSE()
+		stream.KeepAliveInterval = 50 * time.Millisecond
+		stream.OnClose(func() {
+			disconnected <- true
+		})
+		stream.Send(ghttp.SSEEvent{Id: "1", Event: "hello", Data: "line1\nline2", Retry: 3 * time.Second})
+		streams = append(streams, stream)
+	})
+	anser.GET("/finite", func(c *ghttp.Context) {
+		stream := c.SSE()
+		stream.Data("a")
+		stream.Event("done", c.LastEventId())
+		stream.Close()
+	})
+	loop = testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+// 讀取一個事件(至空行為止)
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	lines := []string{}
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			t.Fatalf("Failed to read event: %+v, lines: %v", err, lines)
+		}
+
+		line = strings.TrimRight(line, "\n")
+
+		if line == "" {
+			return lines
+		}
+
+		lines = append(lines, line)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/events", port))
+
+	if err != nil {
+		t.Fatalf("Failed to get: %+v", err)
+	}
+
+	if res.Header.Get("Content-Type") != ghttp.MIMEEventStream || res.Header.Get("Cache-Control") != "no-cache" {
+		t.Errorf("Header: %+v", res.Header)
+	}
+
+	reader := bufio.NewReader(res.Body)
+	lines := readEvent(t, reader)
+	expected := "id: 1|event: hello|retry: 3000|data: line1|data: line2"
+
+	if strings.Join(lines, "|") != expected {
+		t.Errorf("lines: %v", lines)
+	}
+
+	// 之後的迴圈中送出的事件
+	loop.Do(func() {
+		streams[len(streams)-1].Data("later")
+	})
+
+	for {
+		lines = readEvent(t, reader)
+
+		// 送出事件前可能先收到 keep-alive 註解
+		if lines[0] == ": keep-alive" {
+			continue
+		}
+
+		if strings.Join(lines, "|") != "data: later" {
+			t.Errorf("lines: %v", lines)
+		}
+		break
+	}
+
+	// 閒置時送出 keep-alive 註解
+	if lines = readEvent(t, reader); lines[0] != ": keep-alive" {
+		t.Errorf("lines: %v", lines)
+	}
+
+	// 客戶端斷線後通知應用層
+	res.Body.Close()
+
+	select {
+	case <-disconnected:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for disconnect.")
+	}
+
+	var closed bool
+	loop.Do(func() {
+		closed = streams[len(streams)-1].IsClosed() && streams[len(streams)-1].Data("x") != nil
+	})
+
+	if !closed {
+		t.Errorf("Stream should be closed after disconnect.")
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/finite", port), nil)
+	req.Header.Set("Last-Event-ID", "41")
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("Failed to get: %+v", err)
+	}
+
+	defer res.Body.Close()
+	reader := bufio.NewReader(res.Body)
+
+	if lines := readEvent(t, reader); strings.Join(lines, "|") != "data: a" {
+		t.Errorf("lines: %v", lines)
+	}
+
+	if lines := readEvent(t, reader); strings.Join(lines, "|") != "event: done|data: 41" {
+		t.Errorf("lines: %v", lines)
+	}
+
+	// 伺服器結束串流後關閉連線
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Errorf("Stream should end after Close.")
+	}
+}
@@ -0,0 +1,380 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+const port int = 18934
+
+var anser *ans.HttpAnser
+var dir string
+
+// 由 countingFS 讀取的 bytes 數
+var readBytes int64
+
+// 統計檔案內容被讀取了多少 bytes
+type countingFS struct {
+	fstest.MapFS
+}
+
+func (f countingFS) Open(name string) (fs.File, error) {
+	file, err := f.MapFS.Open(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingFile{File: file, seeker: file.(io.Seeker), readerAt: file.(io.ReaderAt)}, nil
+}
+
+type countingFile struct {
+	fs.File
+	seeker   io.Seeker
+	readerAt io.ReaderAt
+}
+
+func (f *countingFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	atomic.AddInt64(&readBytes, int64(n))
+	return n, err
+}
+
+func (f *countingFile) ReadAt(p []byte, offset int64) (int, error) {
+	n, err := f.readerAt.ReadAt(p, offset)
+	atomic.AddInt64(&readBytes, int64(n))
+	return n, err
+}
+
+func (f *countingFile) Seek(offset int64, whence int) (int64, error) {
+	return f.seeker.Seek(offset, whence)
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	dir, err = os.MkdirTemp("", "gos-static")
+
+	if err != nil {
+		fmt.Printf("Failed to create temp dir: %+v\n", err)
+		os.Exit(1)
+	}
+
+	os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("0123456789"), 0o644)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>home</h1>"), 0o644)
+	os.WriteFile(filepath.Join(dir, "large.txt"), []byte(strings.Repeat("0123456789", 10000)), 0o644)
+	os.Mkdir(filepath.Join(dir, "css"), 0o755)
+	os.WriteFile(filepath.Join(dir, "css", "style.css"), []byte("body{}"), 0o644)
+	os.WriteFile(filepath.Join(os.TempDir(), "gos-secret.txt"), []byte("secret"), 0o644)
+
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	anser = a.(*ans.HttpAnser)
+	anser.Static("/assets", dir)
+	anser.StaticFS("/embed", fstest.MapFS{
+		"app.js": &fstest.MapFile{Data: []byte("console.log(1)")},
+	})
+	anser.StaticWithConfig("/app", fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte("<div id=app></div>")},
+		"main.js":    &fstest.MapFile{Data: []byte("main()")},
+	}, ans.StaticConfig{Index: "index.html", SPAFallback: true})
+	anser.StaticFS("/counted", countingFS{fstest.MapFS{
+		"large.txt": &fstest.MapFile{Data: []byte(strings.Repeat("0123456789", 100)), ModTime: time.Now()},
+	}})
+	// 沒有修改時間(如 embed.FS)，ETag 根據檔案內容生成
+	anser.StaticFS("/hashed", countingFS{fstest.MapFS{
+		"large.txt": &fstest.MapFile{Data: []byte(strings.Repeat("0123456789", 100))},
+	}})
+	testutil.Serve(anser)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Remove(filepath.Join(os.TempDir(), "gos-secret.txt"))
+	os.Exit(code)
+}
+
+func request(t *testing.T, method string, path string, header map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new request: %+v", err)
+	}
+
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("Failed to %s %s: %+v", method, path, err)
+	}
+
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return res, string(data)
+}
+
+func TestServeFile(t *testing.T) {
+	res, body := request(t, http.MethodGet, "/assets/hello.txt", nil)
+
+	if res.StatusCode != http.StatusOK || body != "0123456789" ||
+		!strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain") ||
+		res.Header.Get("ETag") == "" || res.Header.Get("Last-Modified") == "" ||
+		res.Header.Get("Accept-Ranges") != "bytes" {
+		t.Errorf("code: %d, body: %s, header: %+v", res.StatusCode, body, res.Header)
+	}
+
+	res, body = request(t, http.MethodGet, "/assets/css/style.css", nil)
+
+	if res.StatusCode != http.StatusOK || body != "body{}" || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/css") {
+		t.Errorf("code: %d, body: %s, header: %+v", res.StatusCode, body, res.Header)
+	}
+
+	// 目錄請求回應 index.html
+	res, body = request(t, http.MethodGet, "/assets", nil)
+
+	if res.StatusCode != http.StatusOK || body != "<h1>home</h1>" || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("code: %d, body: %s, header: %+v", res.StatusCode, body, res.Header)
+	}
+
+	res, _ = request(t, http.MethodGet, "/assets/missing.txt", nil)
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+}
+
+func TestHead(t *testing.T) {
+	res, body := request(t, http.MethodHead, "/assets/hello.txt", nil)
+
+	if res.StatusCode != http.StatusOK || body != "" || res.ContentLength != 10 {
+		t.Errorf("code: %d, body: %s, length: %d", res.StatusCode, body, res.ContentLength)
+	}
+
+	// 連線仍可繼續使用
+	res, body = request(t, http.MethodGet, "/assets/hello.txt", nil)
+
+	if res.StatusCode != http.StatusOK || body != "0123456789" {
+		t.Errorf("code: %d, body: %s", res.StatusCode, body)
+	}
+}
+
+func TestFS(t *testing.T) {
+	res, body := request(t, http.MethodGet, "/embed/app.js", nil)
+
+	if res.StatusCode != http.StatusOK || body != "console.log(1)" ||
+		res.Header.Get("ETag") == "" || res.Header.Get("Last-Modified") != "" {
+		t.Errorf("code: %d, body: %s, header: %+v", res.StatusCode, body, res.Header)
+	}
+
+	res, _ = request(t, http.MethodGet, "/embed/app.js", map[string]string{"If-None-Match": res.Header.Get("ETag")})
+
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+
+	res, _ = request(t, http.MethodGet, "/embed/other.js", nil)
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+}
+
+func TestConditional(t *testing.T) {
+	res, _ := request(t, http.MethodGet, "/assets/hello.txt", nil)
+	etag := res.Header.Get("ETag")
+	lastModified := res.Header.Get("Last-Modified")
+
+	res, body := request(t, http.MethodGet, "/assets/hello.txt", map[string]string{"If-None-Match": `"other", ` + etag})
+
+	if res.StatusCode != http.StatusNotModified || body != "" || res.Header.Get("ETag") != etag {
+		t.Errorf("code: %d, body: %s, header: %+v", res.StatusCode, body, res.Header)
+	}
+
+	res, _ = request(t, http.MethodGet, "/assets/hello.txt", map[string]string{"If-None-Match": `"other"`})
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+
+	res, _ = request(t, http.MethodGet, "/assets/hello.txt", map[string]string{"If-Modified-Since": lastModified})
+
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	res, _ = request(t, http.MethodGet, "/assets/hello.txt", map[string]string{"If-Modified-Since": past})
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("code: %d", res.StatusCode)
+	}
+}
+
+func TestRange(t *testing.T) {
+	cases := []struct {
+		header       string
+		code         int
+		body         string
+		contentRange string
+	}{
+		{"bytes=2-5", http.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"bytes=7-", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=-3", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=8-100", http.StatusPartialContent, "89", "bytes 8-9/10"},
+		{"bytes=10-", http.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		{"bytes=5-2", http.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		// 不支援的多重範圍，回應完整的檔案
+		{"bytes=0-1,3-4", http.StatusOK, "0123456789", ""},
+	}
+
+	for _, tc := range cases {
+		res, body := request(t, http.MethodGet, "/assets/hello.txt", map[string]string{"Range": tc.header})
+
+		if res.StatusCode != tc.code || body != tc.body || res.Header.Get("Content-Range") != tc.contentRange {
+			t.Errorf("Range: %s, code: %d, body: %s, Content-Range: %s", tc.header, res.StatusCode, body, res.Header.Get("Content-Range"))
+		}
+	}
+
+	// If-Range 不相符時，回應完整的檔案
+	res, body := request(t, http.MethodGet, "/assets/hello.txt", map[string]string{"Range": "bytes=0-1", "If-Range": `"stale"`})
+
+	if res.StatusCode != http.StatusOK || body != "0123456789" {
+		t.Errorf("code: %d, body: %s", res.StatusCode, body)
+	}
+}
+
+func TestSPAFallback(t *testing.T) {
+	res, body := request(t, http.MethodGet, "/app/main.js", nil)
+
+	if res.StatusCode != http.StatusOK || body != "main()" {
+		t.Errorf("code: %d, body: %s", res.StatusCode, body)
+	}
+
+	res, body = request(t, http.MethodGet, "/app/users/42", nil)
+
+	if res.StatusCode != http.StatusOK || body != "<div id=app></div>" || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("code: %d, body: %s, header: %+v", res.StatusCode, body, res.Header)
+	}
+}
+
+// 以原始請求送出未經客戶端正規化的路徑
+func rawRequest(t *testing.T, path string) string {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: 127.0.0.1\r\nConnection: close\r\n\r\n", path)
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+func TestPathTraversal(t *testing.T) {
+	rel, _ := filepath.Rel(dir, filepath.Join(os.TempDir(), "gos-secret.txt"))
+	paths := []string{
+		"/assets/" + filepath.ToSlash(rel),
+		"/assets/css/../../gos-secret.txt",
+		"/assets/%2e%2e/gos-secret.txt",
+		"/assets/..%2fgos-secret.txt",
+		"/assets/..%5cgos-secret.txt",
+	}
+
+	for _, path := range paths {
+		if line := rawRequest(t, path); strings.Contains(line, "200") {
+			t.Errorf("path: %s, status: %s", path, line)
+		}
+	}
+}
+
+// 條件式請求與 HEAD 請求不讀取檔案內容，範圍請求只讀取所需的範圍
+func TestReadOnlyNeeded(t *testing.T) {
+	res, _ := request(t, http.MethodHead, "/counted/large.txt", nil)
+	etag := res.Header.Get("ETag")
+	cases := []struct {
+		method string
+		header map[string]string
+		code   int
+		read   int64
+	}{
+		{http.MethodHead, nil, http.StatusOK, 0},
+		{http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified, 0},
+		{http.MethodGet, map[string]string{"Range": "bytes=10-19"}, http.StatusPartialContent, 10},
+		{http.MethodGet, nil, http.StatusOK, 1000},
+	}
+
+	for _, tc := range cases {
+		atomic.StoreInt64(&readBytes, 0)
+		res, body := request(t, tc.method, "/counted/large.txt", tc.header)
+		read := atomic.LoadInt64(&readBytes)
+
+		if res.StatusCode != tc.code || read != tc.read {
+			t.Errorf("method: %s, header: %v, code: %d, read: %d, expected: %d, %d", tc.method, tc.header, res.StatusCode, read, tc.code, tc.read)
+		}
+
+		if tc.method == http.MethodHead && (body != "" || res.Header.Get("Content-Length") != "1000") {
+			t.Errorf("HEAD body: %q, Content-Length: %s", body, res.Header.Get("Content-Length"))
+		}
+	}
+}
+
+// 根據檔案內容生成的 ETag 只在第一次請求時讀取檔案
+func TestHashedETagCache(t *testing.T) {
+	var etag string
+
+	for i, expected := range []int64{1000, 0, 0} {
+		atomic.StoreInt64(&readBytes, 0)
+		res, _ := request(t, http.MethodHead, "/hashed/large.txt", nil)
+		read := atomic.LoadInt64(&readBytes)
+
+		if i == 0 {
+			etag = res.Header.Get("ETag")
+		}
+
+		if res.StatusCode != http.StatusOK || read != expected || etag == "" || res.Header.Get("ETag") != etag {
+			t.Errorf("%d-th code: %d, read: %d, ETag: %s, expected: %d, %s", i, res.StatusCode, read, res.Header.Get("ETag"), expected, etag)
+		}
+	}
+}
+
+// 大於串流緩衝區的檔案分多次寫出，寫出後仍可繼續使用同一連線
+func TestStreamLargeFile(t *testing.T) {
+	expected := strings.Repeat("0123456789", 10000)
+
+	for i := 0; i < 2; i++ {
+		res, body := request(t, http.MethodGet, "/assets/large.txt", nil)
+
+		if res.StatusCode != http.StatusOK || body != expected || res.Header.Get("Content-Length") != "100000" {
+			t.Errorf("code: %d, length: %d, header: %+v", res.StatusCode, len(body), res.Header)
+		}
+	}
+
+	res, body := request(t, http.MethodGet, "/assets/large.txt", map[string]string{"Range": "bytes=40000-80009"})
+
+	if res.StatusCode != http.StatusPartialContent || body != expected[40000:80010] {
+		t.Errorf("code: %d, length: %d", res.StatusCode, len(body))
+	}
+}
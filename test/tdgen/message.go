@@ -0,0 +1,49 @@
+package test
+
+import "time"
+
+//go:generate go run ../../cmd/tdgen -type=Player,Login,LoginSwapped -output=message_transdata.go
+
+type Level int32
+
+type Blob []byte
+
+type Item struct {
+	Id    int32
+	Count uint16 `td:",varint"`
+}
+
+type Node struct {
+	Value int8
+	Next  *Node
+}
+
+type Player struct {
+	Name    string `td:"2"`
+	Id      int64  `td:"1,varint"`
+	Level   Level  `td:",varint"`
+	Rank    int
+	Hp      float32
+	Online  bool
+	Token   string `td:"-"`
+	secret  string
+	Avatar  Blob
+	Items   []Item
+	Scores  [3]uint32 `td:",varint"`
+	Friends map[string]int64
+	Guild   *string
+	Path    *Node
+	Login   time.Time
+	History map[int32]time.Time
+}
+
+type Login struct {
+	Account  string
+	Password string
+}
+
+// 與 Login 的欄位相同，但順序不同
+type LoginSwapped struct {
+	Password string
+	Account  string
+}
@@ -0,0 +1,189 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+// 測試連線時比對 TransDataSchema 的 Tcp0Anser 所使用的埠
+const port int = 18951
+
+// 與 Player 的欄位相同，但沒有產生的方法，因此 Marshal 將使用反射
+type reflectPlayer Player
+
+func newPlayer() *Player {
+	guild := "gos"
+	return &Player{
+		Name:    "player",
+		Id:      -9527,
+		Level:   300,
+		Rank:    -1,
+		Hp:      99.5,
+		Online:  true,
+		Avatar:  Blob{1, 2, 3},
+		Items:   []Item{{Id: 1, Count: 5}, {Id: 2, Count: 500}},
+		Scores:  [3]uint32{7, 8, 9},
+		Friends: map[string]int64{"a": 1},
+		Guild:   &guild,
+		Path:    &Node{Value: -1, Next: &Node{Value: 2}},
+		Login:   time.Date(2023, 5, 6, 7, 8, 9, 10, time.UTC),
+		History: map[int32]time.Time{1: time.Unix(1, 2).UTC()},
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	player := newPlayer()
+	td := base.NewTransData()
+	player.MarshalTransData(td)
+	generated := td.GetData()
+
+	// 數據格式與反射的 Marshal 相同
+	td = base.NewTransData()
+
+	if err := td.Marshal((*reflectPlayer)(player)); err != nil {
+		t.Fatalf("Failed to marshal: %+v", err)
+	}
+
+	if !bytes.Equal(td.GetData(), generated) {
+		t.Errorf("generated: %v, reflect: %v", generated, td.GetData())
+	}
+
+	td = base.LoadTransData(generated)
+	td.ResetIndex()
+	result := &Player{}
+
+	// Unmarshal 將直接使用產生的方法
+	if err := td.Unmarshal(result); err != nil {
+		t.Fatalf("Failed to unmarshal: %+v", err)
+	}
+
+	if !reflect.DeepEqual(result, player) {
+		t.Errorf("result: %+v, expected: %+v", result, player)
+	}
+
+	if td.GetRemaining() != 0 {
+		t.Errorf("remaining: %d", td.GetRemaining())
+	}
+}
+
+func TestGeneratedError(t *testing.T) {
+	td := base.NewTransData()
+	newPlayer().MarshalTransData(td)
+	data := td.GetData()
+
+	// 任何位置被截斷都應返回錯誤，而非讀取到錯誤的數值
+	for i := 0; i < len(data); i++ {
+		td = base.LoadTransData(data[:i])
+		td.ResetIndex()
+
+		if err := (&Player{}).UnmarshalTransData(td); err == nil {
+			t.Fatalf("Unmarshaling data truncated at %d should fail.", i)
+		}
+	}
+
+	// 數值超出欄位型別的範圍
+	td = base.NewTransData()
+	td.AddInt32(1)
+	td.AddUVarint(70000)
+	td.ResetIndex()
+
+	if err := (&Item{}).UnmarshalTransData(td); err == nil {
+		t.Errorf("Unmarshaling an overflowed varint should fail.")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	login := &Login{}
+	swapped := &LoginSwapped{}
+
+	// 欄位相同但順序不同，雜湊值也不同
+	if login.TransDataSchema() == swapped.TransDataSchema() {
+		t.Errorf("Schemas with different field orders should not be equal.")
+	}
+
+	if TransDataSchema == login.TransDataSchema() {
+		t.Errorf("The package schema should combine all structs.")
+	}
+
+}
+
+// 以 TransDataSchema 作為 introduction，連線時即可發現訊息格式不一致的對象
+func TestSchemaHandshake(t *testing.T) {
+	const schemaKind byte = 0
+	const echoKind byte = 1
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewTcp0Anser(laddr, 2, 10)
+
+	if err != nil {
+		t.Fatalf("Failed to new Tcp0Anser: %+v", err)
+	}
+
+	anser := a.(*ans.Tcp0Anser)
+	dispatcher := base.NewDispatcher()
+	dispatcher.Handle(schemaKind, 0, func(c *base.Command) {
+		if c.Body.PopUInt64() != TransDataSchema || c.Body.Err() != nil {
+			anser.Disconnect(c.Index)
+		}
+		c.Finish()
+	})
+	dispatcher.Handle(echoKind, 0, func(c *base.Command) {
+		c.SendTransData()
+	})
+	anser.SetWorkHandler(dispatcher.Dispatch)
+	loop := testutil.Serve(anser)
+	defer func() {
+		loop.Stop()
+		anser.StopListen()
+	}()
+
+	connect := func(schema uint64) net.Conn {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+		if err != nil {
+			t.Fatalf("Failed to dial: %+v", err)
+		}
+
+		conn.SetDeadline(time.Now().Add(3 * time.Second))
+		td := base.NewTransData()
+		td.AddByte(schemaKind)
+		td.AddUInt16(0)
+		td.AddUInt64(schema)
+		conn.Write(td.FormData())
+		return conn
+	}
+
+	// introduction 相符時，維持連線
+	conn := connect(TransDataSchema)
+	defer conn.Close()
+	td := base.NewTransData()
+	td.AddByte(echoKind)
+	td.AddUInt16(0)
+	td.AddString("hi")
+	conn.Write(td.FormData())
+	reply := make([]byte, 4+3+4+2)
+
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("Failed to read: %+v", err)
+	}
+
+	if message := string(reply[11:]); message != "hi" {
+		t.Errorf("message: %s", message)
+	}
+
+	// introduction 不符時，中斷連線
+	mismatched := connect(TransDataSchema + 1)
+	defer mismatched.Close()
+
+	if _, err = mismatched.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("err: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package testutil
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+)
+
+// ====================================================================================================
+// Loop
+// 測試用的主迴圈，於單一協程中重複執行 Handler(與 gos.Run 相同)
+// Anser 與 Asker 皆非協程安全，其他協程須透過 Do 將操作(如 Write、SendTransData)交由迴圈協程執行
+// ====================================================================================================
+type Loop struct {
+	handler func()
+	// 每次迴圈的間隔，為 0 時僅讓出執行權
+	interval time.Duration
+	// 待迴圈協程執行的操作
+	tasks chan func()
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// 於新的協程中，每隔 1 毫秒執行一次 handler
+func Start(handler func()) *Loop {
+	return StartWithInterval(handler, time.Millisecond)
+}
+
+// 於新的協程中，每隔 interval 執行一次 handler
+func StartWithInterval(handler func(), interval time.Duration) *Loop {
+	l := &Loop{
+		handler:  handler,
+		interval: interval,
+		tasks:    make(chan func()),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// 開始監聽，並啟動 anser 的主迴圈
+func Serve(anser ans.IAnswer) *Loop {
+	go anser.Listen()
+	return Start(anser.Handler)
+}
+
+func (l *Loop) run() {
+	defer close(l.done)
+
+	for {
+		// 兩次 Handler 之間，執行其他協程交付的操作
+		for draining := true; draining; {
+			select {
+			case <-l.stop:
+				return
+			case task := <-l.tasks:
+				task()
+			default:
+				draining = false
+			}
+		}
+
+		l.handler()
+
+		if l.interval > 0 {
+			time.Sleep(l.interval)
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
+
+// 於迴圈協程中執行 task，並等待其執行完畢
+func (l *Loop) Do(task func()) {
+	finished := make(chan struct{})
+	l.tasks <- func() {
+		defer close(finished)
+		task()
+	}
+	<-finished
+}
+
+// 停止迴圈，並等待迴圈協程結束
+func (l *Loop) Stop() {
+	close(l.stop)
+	<-l.done
+}
@@ -0,0 +1,367 @@
+package test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos"
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+const httpsPort int = 18936
+const tcp0Port int = 18937
+const registerPort int = 18947
+
+var dir string
+var ca *x509.Certificate
+var caKey *ecdsa.PrivateKey
+var caPool *x509.CertPool
+var clientCert tls.Certificate
+
+// 生成由測試用 CA 簽發的憑證，serial 用於辨識憑證是否已被重新載入
+func issue(cn string, serial int64, isServer bool) (certPEM []byte, keyPEM []byte) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if isServer {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+
+	der, _ := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func writeServerCert(serial int64) {
+	certPEM, keyPEM := issue("server", serial, true)
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	os.WriteFile(certFile, certPEM, 0o600)
+	os.WriteFile(keyFile, keyPEM, 0o600)
+
+	// 確保修改時間不同於前一次寫入
+	modTime := time.Now().Add(time.Duration(serial) * time.Second)
+	os.Chtimes(certFile, modTime, modTime)
+	os.Chtimes(keyFile, modTime, modTime)
+}
+
+func setup() error {
+	var err error
+	dir, err = os.MkdirTemp("", "gos-tls")
+
+	if err != nil {
+		return err
+	}
+
+	caKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gos test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+
+	if err != nil {
+		return err
+	}
+
+	ca, _ = x509.ParseCertificate(der)
+	caFile := filepath.Join(dir, "ca.crt")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+
+	if caPool, err = base.LoadCertPool(caFile); err != nil {
+		return err
+	}
+
+	writeServerCert(100)
+	certPEM, keyPEM := issue("client-a", 200, false)
+	clientCert, err = tls.X509KeyPair(certPEM, keyPEM)
+	return err
+}
+
+var reloader *base.CertReloader
+
+func TestMain(m *testing.M) {
+	if err := setup(); err != nil {
+		fmt.Printf("Failed to setup certificates: %+v\n", err)
+		os.Exit(1)
+	}
+
+	var err error
+	reloader, err = base.NewCertReloader(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+
+	if err != nil {
+		fmt.Printf("Failed to new CertReloader: %+v\n", err)
+		os.Exit(1)
+	}
+
+	reloader.CheckInterval = 0
+
+	// HTTPS: 客戶端憑證為選擇性提供
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", httpsPort))
+	a, err := ans.NewHttpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	httpAnser := a.(*ans.HttpAnser)
+	httpAnser.UseTLS(&tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		ClientAuth:     tls.VerifyClientCertIfGiven,
+		ClientCAs:      caPool,
+	})
+	httpAnser.GET("/whoami", func(c *ghttp.Context) {
+		cn := ""
+
+		if cert := c.PeerCertificate(); cert != nil {
+			cn = cert.Subject.CommonName
+		}
+
+		c.Json(ghttp.StatusOK, ghttp.H{"tls": c.TLS != nil, "cn": cn})
+	})
+	testutil.Serve(httpAnser)
+
+	// Tcp0: 要求客戶端憑證(mTLS)，回應客戶端憑證的 CN
+	laddr, _ = net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", tcp0Port))
+	a, err = ans.NewTcp0Anser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new Tcp0Anser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	tcp0Anser := a.(*ans.Tcp0Anser)
+	tcp0Anser.UseTLS(&tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		ClientCAs:      caPool,
+	})
+	tcp0Anser.SetWorkHandler(func(w *base.Work) {
+		message := w.Body.PopString()
+		cn := ""
+
+		if cert := w.PeerCertificate(); cert != nil {
+			cn = cert.Subject.CommonName
+		}
+
+		w.Body.Clear()
+		w.Body.AddString(message + ":" + cn)
+		w.SendTransData()
+	})
+	testutil.Serve(tcp0Anser)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type whoami struct {
+	TLS bool   `json:"tls"`
+	CN  string `json:"cn"`
+}
+
+func get(t *testing.T, config *tls.Config) (*whoami, *tls.ConnectionState, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	res, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/whoami", httpsPort))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	result := &whoami{}
+
+	if err = json.Unmarshal(data, result); err != nil {
+		t.Fatalf("Failed to unmarshal %s: %+v", data, err)
+	}
+
+	return result, res.TLS, nil
+}
+
+func TestHttps(t *testing.T) {
+	result, _, err := get(t, &tls.Config{RootCAs: caPool})
+
+	if err != nil || !result.TLS || result.CN != "" {
+		t.Fatalf("result: %+v, err: %+v", result, err)
+	}
+
+	result, _, err = get(t, &tls.Config{RootCAs: caPool, Certificates: []tls.Certificate{clientCert}})
+
+	if err != nil || !result.TLS || result.CN != "client-a" {
+		t.Fatalf("result: %+v, err: %+v", result, err)
+	}
+
+	// 不信任伺服器憑證的客戶端無法連線
+	if _, _, err = get(t, &tls.Config{}); err == nil {
+		t.Errorf("Untrusted server certificate should fail.")
+	}
+
+	// 明文的請求無法被處理
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpsPort))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /whoami HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+	// 交握失敗的連線不會有任何回應
+	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+
+	if strings.Contains(line, "200") {
+		t.Errorf("Plain HTTP should fail, status: %s", line)
+	}
+}
+
+func TestCertReload(t *testing.T) {
+	_, state, err := get(t, &tls.Config{RootCAs: caPool})
+
+	if err != nil {
+		t.Fatalf("err: %+v", err)
+	}
+
+	serial := state.PeerCertificates[0].SerialNumber.Int64()
+	writeServerCert(serial + 1)
+	_, state, err = get(t, &tls.Config{RootCAs: caPool})
+
+	if err != nil || state.PeerCertificates[0].SerialNumber.Int64() != serial+1 {
+		t.Errorf("serial: %d, err: %+v", state.PeerCertificates[0].SerialNumber.Int64(), err)
+	}
+}
+
+func newAsker(t *testing.T, site int32, config *tls.Config, replies chan string) *ask.Tcp0Asker {
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: tcp0Port}
+	a, err := ask.NewTcp0Asker(site, laddr, 1, 10, nil, nil, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new Tcp0Asker: %+v", err)
+	}
+
+	asker := a.(*ask.Tcp0Asker)
+	asker.UseTLS(config)
+	asker.SetWorkHandler(func(w *base.Work) {
+		replies <- w.Body.PopString()
+
+		if w.PeerCertificate() == nil || w.PeerCertificate().Subject.CommonName != "server" {
+			replies <- "missing server certificate"
+		}
+
+		w.Finish()
+	})
+	return asker
+}
+
+func TestTcp0MutualTLS(t *testing.T) {
+	replies := make(chan string, 2)
+	asker := newAsker(t, 1, &tls.Config{RootCAs: caPool, Certificates: []tls.Certificate{clientCert}}, replies)
+
+	if err := asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	td := base.NewTransData()
+	td.AddString("hello")
+	data := td.FormData()
+	loop.Do(func() { asker.Write(&data, int32(len(data))) })
+
+	select {
+	case reply := <-replies:
+		if reply != "hello:client-a" {
+			t.Errorf("reply: %s", reply)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout")
+	}
+
+	// 未提供客戶端憑證時，交握失敗
+	asker = newAsker(t, 2, &tls.Config{RootCAs: caPool}, replies)
+	err := asker.Connect()
+
+	if err == nil {
+		// TLS 1.3 的客戶端於伺服器驗證憑證前即完成交握，錯誤將於首次讀取時發生
+		conn, dialErr := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", tcp0Port), &tls.Config{RootCAs: caPool})
+
+		if dialErr == nil {
+			conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+			_, err = conn.Read(make([]byte, 1))
+			conn.Close()
+		} else {
+			err = dialErr
+		}
+	}
+
+	if err == nil {
+		t.Errorf("Connection without client certificate should fail.")
+	}
+}
+
+// 設定 TLS 失敗時不應註冊(且須釋放 port)，已以明文註冊的 port 與 serverId 不可再設定 TLS
+func TestRegisterTLS(t *testing.T) {
+	if _, err := gos.ListenTLS(define.Http, int32(registerPort), nil); err == nil {
+		t.Fatal("ListenTLS with nil config should fail.")
+	}
+
+	if _, err := gos.Listen(define.Http, int32(registerPort)); err != nil {
+		t.Fatalf("Failed to listen after ListenTLS failed: %+v", err)
+	}
+
+	defer gos.StopListen()
+
+	if _, err := gos.ListenTLS(define.Http, int32(registerPort), &tls.Config{GetCertificate: reloader.GetCertificate}); err == nil {
+		t.Error("ListenTLS on a port registered without TLS should fail.")
+	}
+
+	if _, err := gos.BindTLS(1, "127.0.0.1", registerPort, define.Tcp0, nil, nil, nil, nil); err == nil {
+		t.Fatal("BindTLS with nil config should fail.")
+	}
+
+	if _, err := gos.Bind(1, "127.0.0.1", registerPort, define.Tcp0, nil, nil, nil); err != nil {
+		t.Fatalf("Failed to bind after BindTLS failed: %+v", err)
+	}
+
+	if _, err := gos.BindTLS(1, "127.0.0.1", registerPort, define.Tcp0, nil, nil, nil, &tls.Config{RootCAs: caPool}); err == nil {
+		t.Error("BindTLS on a server id registered without TLS should fail.")
+	}
+}
@@ -1,7 +1,10 @@
 package test
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/j32u4ukh/gos/base"
 )
@@ -129,3 +132,253 @@ func TestByteArray(t *testing.T) {
 		}
 	}
 }
+
+func TestVarint(t *testing.T) {
+	td := base.NewTransData()
+	td.AddVarint(-1)
+	td.AddUVarint(300)
+
+	if td.GetLength() != 3 {
+		t.Errorf("length: %d", td.GetLength())
+	}
+
+	td.ResetIndex()
+
+	if v := td.PopVarint(); v != -1 {
+		t.Errorf("varint: %d", v)
+	}
+
+	if v := td.PopUVarint(); v != 300 {
+		t.Errorf("uvarint: %d", v)
+	}
+}
+
+type Item struct {
+	Id    int32
+	Count uint16 `td:",varint"`
+}
+
+type Node struct {
+	Value int8
+	Next  *Node
+}
+
+type Player struct {
+	Name    string `td:"2"`
+	Id      int64  `td:"1,varint"`
+	Level   int
+	Hp      float32
+	Online  bool
+	Token   string `td:"-"`
+	secret  string
+	Avatar  []byte
+	Items   []Item
+	Scores  [3]uint32 `td:",varint"`
+	Friends map[string]int64
+	Guild   *string
+	Path    *Node
+	Login   time.Time
+}
+
+func TestMarshal(t *testing.T) {
+	guild := "gos"
+	player := &Player{
+		Name:    "player",
+		Id:      -9527,
+		Level:   10,
+		Hp:      99.5,
+		Online:  true,
+		Token:   "token",
+		secret:  "secret",
+		Avatar:  []byte{1, 2, 3},
+		Items:   []Item{{Id: 1, Count: 5}, {Id: 2, Count: 500}},
+		Scores:  [3]uint32{7, 8, 9},
+		Friends: map[string]int64{"a": 1, "b": -2},
+		Guild:   &guild,
+		Path:    &Node{Value: -1, Next: &Node{Value: 2}},
+		Login:   time.Date(2023, 5, 6, 7, 8, 9, 10, time.UTC),
+	}
+	td := base.NewTransData()
+
+	if err := td.Marshal(player); err != nil {
+		t.Fatalf("Failed to marshal: %+v", err)
+	}
+
+	// 依標籤指定的順序寫入
+	td.ResetIndex()
+
+	if id := td.PopVarint(); id != -9527 {
+		t.Errorf("id: %d", id)
+	}
+
+	if name := td.PopString(); name != "player" {
+		t.Errorf("name: %s", name)
+	}
+
+	td.ResetIndex()
+	result := &Player{}
+
+	if err := td.Unmarshal(result); err != nil {
+		t.Fatalf("Failed to unmarshal: %+v", err)
+	}
+
+	if result.Token != "" || result.secret != "" {
+		t.Errorf("Skipped fields should not be written: %+v", result)
+	}
+
+	player.Token = ""
+	player.secret = ""
+
+	if !result.Login.Equal(player.Login) {
+		t.Errorf("login: %v", result.Login)
+	}
+
+	result.Login = player.Login
+
+	if !reflect.DeepEqual(result, player) {
+		t.Errorf("result: %+v, expected: %+v", result, player)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	td := base.NewTransData()
+	td.Marshal(Item{Id: 1, Count: 1})
+
+	// 數據不足
+	data := td.GetData()
+	td = base.LoadTransData(data[:len(data)-1])
+
+	if err := td.Unmarshal(&Item{}); err == nil {
+		t.Errorf("Unmarshaling short data should fail.")
+	}
+
+	// 長度超過剩餘的數據量
+	td = base.NewTransData()
+	td.AddInt32(100)
+	td.ResetIndex()
+
+	if err := td.Unmarshal(&struct{ Name string }{}); err == nil {
+		t.Errorf("Unmarshaling an invalid length should fail.")
+	}
+
+	// 字串長度超過上限
+	td = base.NewTransData()
+	td.SetMaxLength(2)
+	td.Marshal(&struct{ Name string }{Name: "abc"})
+	td.ResetIndex()
+
+	if err := td.Unmarshal(&struct{ Name string }{}); err == nil || td.Err() == nil {
+		t.Errorf("Unmarshaling an oversized string should fail.")
+	}
+
+	if err := td.Unmarshal(Item{}); err == nil {
+		t.Errorf("Unmarshaling into a non-pointer should fail.")
+	}
+
+	type Invalid struct {
+		A int32 `td:"1"`
+		B int32 `td:"1"`
+	}
+
+	if err := td.Marshal(&Invalid{}); err == nil {
+		t.Errorf("Duplicate orders should fail.")
+	}
+
+	if err := td.Marshal(&struct{ C chan int }{}); err == nil {
+		t.Errorf("Unsupported types should fail.")
+	}
+}
+
+func TestSafePop(t *testing.T) {
+	td := base.NewTransData()
+	td.AddInt16(1)
+	td.ResetIndex()
+
+	// 數據不足時返回零值並記錄錯誤
+	if v := td.PopInt32(); v != 0 || td.Err() == nil {
+		t.Fatalf("v: %d, err: %v", v, td.Err())
+	}
+
+	// 錯誤發生後，之後的讀取皆返回零值
+	if v := td.PopInt16(); v != 0 {
+		t.Errorf("Pop after an error should return zero, got %d.", v)
+	}
+
+	td.ResetIndex()
+
+	if v := td.PopInt16(); v != 1 || td.Err() != nil {
+		t.Errorf("v: %d, err: %v", v, td.Err())
+	}
+
+	// 長度超過剩餘的數據量
+	td.Clear()
+	td.AddInt32(100)
+	td.AddRawData([]byte("abc"))
+	td.ResetIndex()
+
+	if v := td.PopString(); v != "" || td.Err() == nil {
+		t.Errorf("v: %q, err: %v", v, td.Err())
+	}
+
+	// 長度超過上限
+	td.Clear()
+	td.SetMaxLength(4)
+	td.AddString("abcde")
+	td.ResetIndex()
+
+	if v := td.PopByteArray(); v != nil || td.Err() == nil {
+		t.Errorf("v: %v, err: %v", v, td.Err())
+	}
+
+	// 預設不限制長度
+	large := strings.Repeat("x", 128*1024)
+	td = base.NewTransData()
+	td.AddString(large)
+	td.ResetIndex()
+
+	if v := td.PopString(); v != large || td.Err() != nil {
+		t.Errorf("length: %d, err: %v", len(v), td.Err())
+	}
+
+	// 長度為 -1 的 byte 陣列為 nil，字串則不合法
+	td.Clear()
+	td.AddInt32(-1)
+	td.ResetIndex()
+
+	if v := td.PopByteArray(); v != nil || td.Err() != nil {
+		t.Errorf("v: %v, err: %v", v, td.Err())
+	}
+
+	td.ResetIndex()
+
+	if td.PopString(); td.Err() == nil {
+		t.Errorf("A negative string length should fail.")
+	}
+
+	// 元素個數超過剩餘的數據量
+	td.Clear()
+	td.AddInt32(1 << 30)
+	td.ResetIndex()
+
+	if v := td.PopLength(); v != 0 || td.Err() == nil {
+		t.Errorf("v: %d, err: %v", v, td.Err())
+	}
+
+	// 不完整的 varint
+	td.Clear()
+	td.AddByte(0x80)
+	td.ResetIndex()
+
+	if td.PopUVarint(); td.Err() == nil {
+		t.Errorf("An incomplete varint should fail.")
+	}
+
+	// 不合法的 JSON
+	td.Clear()
+	td.AddByteArray([]byte("{"))
+	td.ResetIndex()
+
+	if td.PopJson(); td.Err() == nil {
+		t.Errorf("Malformed json should fail.")
+	}
+}
@@ -0,0 +1,307 @@
+package test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+const port int = 18940
+
+// 測試 UdpListener 虛擬連線數上限所使用的埠
+const limitPort int = 18952
+
+var anser *ans.UdpAnser
+
+// 伺服器收到的訊息(cid:payload)
+var received chan string
+
+var loop *testutil.Loop
+
+func TestMain(m *testing.M) {
+	utils.GosConfig.UdpReadTimeout = 1500 * time.Millisecond
+	utils.GosConfig.UdpResendInterval = 50 * time.Millisecond
+	utils.GosConfig.DisconnectTime = 0
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewUdpAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new UdpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	received = make(chan string, 20)
+	anser = a.(*ans.UdpAnser)
+
+	// 回應收到的訊息
+	anser.SetWorkHandler(func(w *base.Work) {
+		received <- fmt.Sprintf("%d:%s", w.Index, w.Body.GetData())
+		w.Send()
+	})
+	loop = testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func drain() {
+	for {
+		select {
+		case <-received:
+		default:
+			return
+		}
+	}
+}
+
+func TestAskerEcho(t *testing.T) {
+	drain()
+	replies := make(chan string, 10)
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+	intro := []byte("intro")
+	a, err := ask.NewUdpAsker(1, laddr, 10, nil, &intro)
+
+	if err != nil {
+		t.Fatalf("Failed to new UdpAsker: %+v", err)
+	}
+
+	asker := a.(*ask.UdpAsker)
+	asker.SetWorkHandler(func(w *base.Work) {
+		replies <- string(w.Body.GetData())
+		w.Finish()
+	})
+
+	if err := asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	askerLoop := testutil.Start(asker.Handler)
+	defer askerLoop.Stop()
+
+	// 自我介紹以可靠訊息送出
+	if reply := <-replies; reply != "intro" {
+		t.Errorf("reply: %s", reply)
+	}
+
+	askerLoop.Do(func() {
+		asker.WriteUnreliable([]byte("fast"))
+		asker.WriteReliable([]byte("safe"))
+	})
+
+	for _, expected := range []string{"fast", "safe"} {
+		select {
+		case reply := <-replies:
+			if reply != expected {
+				t.Errorf("reply: %s, expected: %s", reply, expected)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeout waiting for %s.", expected)
+		}
+	}
+
+	if len(received) != 3 {
+		t.Errorf("received: %d", len(received))
+	}
+
+	// 閒置時以心跳包維持連線
+	time.Sleep(2 * time.Second)
+	askerLoop.Do(func() { asker.WriteReliable([]byte("still here")) })
+
+	select {
+	case reply := <-replies:
+		if reply != "still here" {
+			t.Errorf("reply: %s", reply)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for reply.")
+	}
+}
+
+// 以原始 UDP socket 模擬封包遺失、重複與亂序
+type rawPeer struct {
+	t    *testing.T
+	conn *net.UDPConn
+}
+
+func dial(t *testing.T) *rawPeer {
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: port})
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	return &rawPeer{t: t, conn: conn}
+}
+
+func (p *rawPeer) send(kind byte, seq uint32, payload string) {
+	// 去除長度前綴
+	p.conn.Write(base.EncodeUdpDatagram(kind, seq, []byte(payload))[2:])
+}
+
+func (p *rawPeer) recv() (kind byte, seq uint32, payload string) {
+	buffer := make([]byte, 2048)
+	p.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := p.conn.Read(buffer)
+
+	if err != nil {
+		p.t.Fatalf("Failed to read: %+v", err)
+	}
+
+	kind = buffer[0]
+
+	if kind == base.UdpReliable || kind == base.UdpAck {
+		return kind, binary.LittleEndian.Uint32(buffer[1:5]), string(buffer[5:n])
+	}
+
+	return kind, 0, string(buffer[1:n])
+}
+
+// 等待下一個 datagram，未等待可靠訊息時，忽略尚未確認前已送出的重送
+func (p *rawPeer) expect(kind byte, seq uint32, payload string) {
+	k, s, data := p.recv()
+
+	for kind != base.UdpReliable && k == base.UdpReliable {
+		k, s, data = p.recv()
+	}
+
+	if k != kind || s != seq || data != payload {
+		p.t.Errorf("kind: %d, seq: %d, payload: %s, expected: %d, %d, %s", k, s, data, kind, seq, payload)
+	}
+}
+
+func TestReliableOrdering(t *testing.T) {
+	drain()
+	peer := dial(t)
+	defer peer.conn.Close()
+
+	// 序號 1 先到達，須等待序號 0 後依序交付
+	peer.send(base.UdpReliable, 1, "second")
+	peer.expect(base.UdpAck, 1, "")
+	peer.send(base.UdpReliable, 0, "first")
+	peer.expect(base.UdpAck, 0, "")
+
+	var cid string
+
+	for i, expected := range []string{"first", "second"} {
+		select {
+		case message := <-received:
+			var id int32
+			var payload string
+			fmt.Sscanf(message, "%d:%s", &id, &payload)
+
+			if payload != expected {
+				t.Errorf("%d-th message: %s, expected: %s", i, payload, expected)
+			}
+
+			cid = fmt.Sprint(id)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %s.", expected)
+		}
+	}
+
+	// 回應沿用可靠訊息，並依序編號；先不確認，等待重送
+	peer.expect(base.UdpReliable, 0, "first")
+	peer.expect(base.UdpReliable, 1, "second")
+	peer.expect(base.UdpReliable, 0, "first")
+	peer.send(base.UdpAck, 0, "")
+	peer.send(base.UdpAck, 1, "")
+
+	// 重複的訊息只回應確認，不再交付
+	peer.send(base.UdpReliable, 0, "first")
+	peer.expect(base.UdpAck, 0, "")
+
+	// 不可靠訊息直接交付，回應也不可靠
+	peer.send(base.UdpUnreliable, 0, "ping")
+	peer.expect(base.UdpUnreliable, 0, "ping")
+
+	select {
+	case message := <-received:
+		if message != cid+":ping" {
+			t.Errorf("message: %s", message)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timeout waiting for ping.")
+	}
+}
+
+func TestPeerTimeout(t *testing.T) {
+	drain()
+	peer := dial(t)
+	defer peer.conn.Close()
+
+	peer.send(base.UdpPing, 0, "")
+	peer.expect(base.UdpPong, 0, "")
+	peer.send(base.UdpUnreliable, 0, "hi")
+	peer.expect(base.UdpUnreliable, 0, "hi")
+
+	var id int32
+	fmt.Sscanf(<-received, "%d:", &id)
+
+	connected := func() (result bool) {
+		loop.Do(func() { result = anser.RemoteAddr(id) != nil })
+		return result
+	}
+
+	if !connected() {
+		t.Fatalf("Peer %d should be connected.", id)
+	}
+
+	// 閒置超過 UdpReadTimeout 後斷線
+	time.Sleep(2500 * time.Millisecond)
+
+	if connected() {
+		t.Errorf("Peer %d should be disconnected.", id)
+	}
+
+	var err error
+	loop.Do(func() { err = anser.WriteUnreliable(id, []byte("late")) })
+
+	if err == nil {
+		t.Errorf("Write to a disconnected peer should fail.")
+	}
+}
+
+// 虛擬連線數達上限時，忽略未知位置的 datagram；關閉的虛擬連線將從 UdpListener 移除
+func TestListenerPeerLimit(t *testing.T) {
+	listener, err := base.ListenUdp(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: limitPort}, 10, 3)
+
+	if err != nil {
+		t.Fatalf("Failed to listen: %+v", err)
+	}
+
+	defer listener.Close()
+
+	for i := 0; i < 6; i++ {
+		conn, err := net.DialUDP("udp", nil, listener.Addr().(*net.UDPAddr))
+
+		if err != nil {
+			t.Fatalf("Failed to dial: %+v", err)
+		}
+
+		defer conn.Close()
+		conn.Write([]byte{base.UdpPing})
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := listener.Len(); n != 3 {
+		t.Fatalf("peers: %d", n)
+	}
+
+	// 如 Anser 的連線數已達上限時，關閉所接受的連線
+	for i := 0; i < 3; i++ {
+		conn, _ := listener.Accept()
+		conn.Close()
+	}
+
+	if n := listener.Len(); n != 0 {
+		t.Errorf("peers: %d", n)
+	}
+}
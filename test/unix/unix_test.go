@@ -0,0 +1,285 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos"
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/define"
+	"github.com/j32u4ukh/gos/test/testutil"
+	"github.com/j32u4ukh/gos/utils"
+)
+
+var dir string
+var tcp0Path string
+var httpPath string
+
+func TestMain(m *testing.M) {
+	var err error
+	dir, err = os.MkdirTemp("", "gos-unix")
+
+	if err != nil {
+		fmt.Printf("Failed to create temp dir: %+v\n", err)
+		os.Exit(1)
+	}
+
+	utils.GosConfig.UnixSocketMode = 0600
+	tcp0Path = filepath.Join(dir, "tcp0.sock")
+	httpPath = filepath.Join(dir, "http.sock")
+
+	// 模擬前次未正常關閉所遺留的 socket 檔案
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: tcp0Path, Net: "unix"})
+
+	if err != nil {
+		fmt.Printf("Failed to create stale socket: %+v\n", err)
+		os.Exit(1)
+	}
+
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	// Tcp0: 回應收到的字串
+	a, err := ans.NewTcp0Anser(&net.UnixAddr{Name: tcp0Path, Net: "unix"}, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new Tcp0Anser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	tcp0Anser := a.(*ans.Tcp0Anser)
+	tcp0Anser.SetWorkHandler(func(w *base.Work) {
+		message := w.Body.PopString()
+		w.Body.Clear()
+		w.Body.AddString("echo:" + message)
+		w.SendTransData()
+	})
+	tcp0Loop := testutil.Serve(tcp0Anser)
+
+	// Http
+	a, err = ans.NewHttpAnser(&net.UnixAddr{Name: httpPath, Net: "unix"}, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new HttpAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	httpAnser := a.(*ans.HttpAnser)
+	httpAnser.GET("/ping", func(c *ghttp.Context) {
+		c.String(ghttp.StatusOK, "pong")
+	})
+	httpLoop := testutil.Serve(httpAnser)
+
+	code := m.Run()
+	tcp0Loop.Stop()
+	httpLoop.Stop()
+	tcp0Anser.StopListen()
+	httpAnser.StopListen()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestTcp0(t *testing.T) {
+	replies := make(chan string, 1)
+	a, err := ask.NewTcp0Asker(1, &net.UnixAddr{Name: tcp0Path, Net: "unix"}, 1, 10, nil, nil, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new Tcp0Asker: %+v", err)
+	}
+
+	asker := a.(*ask.Tcp0Asker)
+	asker.SetWorkHandler(func(w *base.Work) {
+		replies <- w.Body.PopString()
+		w.Finish()
+	})
+
+	if ip, port := asker.GetAddress(); ip != tcp0Path || port != 0 {
+		t.Errorf("address: %s:%d", ip, port)
+	}
+
+	if err := asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	td := base.NewTransData()
+	td.AddString("hello")
+	data := td.FormData()
+	loop.Do(func() { asker.Write(&data, int32(len(data))) })
+
+	select {
+	case reply := <-replies:
+		if reply != "echo:hello" {
+			t.Errorf("reply: %s", reply)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for reply.")
+	}
+}
+
+func TestHttp(t *testing.T) {
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", httpPath)
+		},
+	}}
+	res, err := client.Get("http://localhost/ping")
+
+	if err != nil {
+		t.Fatalf("Failed to get: %+v", err)
+	}
+
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+
+	if res.StatusCode != http.StatusOK || string(body) != "pong" {
+		t.Errorf("status: %d, body: %s", res.StatusCode, body)
+	}
+}
+
+// 透過 gos.ListenUnix 監聽的 Anser，可由 gos.SendToUnixClient 主動傳送數據
+func TestSendToUnixClient(t *testing.T) {
+	path := filepath.Join(dir, "gos.sock")
+	a, err := gos.ListenUnix(define.Tcp0, path)
+
+	if err != nil {
+		t.Fatalf("Failed to listen: %+v", err)
+	}
+
+	defer a.StopListen()
+	cids := make(chan int32, 1)
+	a.(*ans.Tcp0Anser).SetWorkHandler(func(w *base.Work) {
+		cids <- w.Index
+		w.Finish()
+	})
+	go a.Listen()
+	anserLoop := testutil.Start(gos.RunAns)
+	defer anserLoop.Stop()
+
+	replies := make(chan string, 1)
+	k, err := ask.NewTcp0Asker(1, &net.UnixAddr{Name: path, Net: "unix"}, 1, 10, nil, nil, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new Tcp0Asker: %+v", err)
+	}
+
+	asker := k.(*ask.Tcp0Asker)
+	asker.SetWorkHandler(func(w *base.Work) {
+		replies <- w.Body.PopString()
+		w.Finish()
+	})
+
+	if err = asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	askerLoop := testutil.Start(asker.Handler)
+	defer askerLoop.Stop()
+
+	td := base.NewTransData()
+	td.AddString("hello")
+	data := td.FormData()
+	askerLoop.Do(func() { asker.Write(&data, int32(len(data))) })
+	var cid int32
+
+	select {
+	case cid = <-cids:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for the client.")
+	}
+
+	td.Clear()
+	td.AddString("push")
+	data = td.FormData()
+	anserLoop.Do(func() { err = gos.SendToUnixClient(path, cid, &data, int32(len(data))) })
+
+	if err != nil {
+		t.Fatalf("Failed to send: %+v", err)
+	}
+
+	select {
+	case reply := <-replies:
+		if reply != "push" {
+			t.Errorf("reply: %s", reply)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for reply.")
+	}
+
+	if err = gos.SendToUnixClient(filepath.Join(dir, "none.sock"), cid, &data, int32(len(data))); err == nil {
+		t.Errorf("Sending to an unknown path should fail.")
+	}
+}
+
+func TestSocketMode(t *testing.T) {
+	info, err := os.Stat(tcp0Path)
+
+	if err != nil {
+		t.Fatalf("Failed to stat: %+v", err)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("mode: %v", info.Mode())
+	}
+}
+
+func TestListenConflict(t *testing.T) {
+	// 仍有人監聽的 socket 檔案不可被取代
+	if _, err := ans.NewTcp0Anser(&net.UnixAddr{Name: tcp0Path, Net: "unix"}, 1, 1); err == nil {
+		t.Errorf("Listen on a socket in use should fail.")
+	}
+
+	// 已存在的一般檔案不可被移除
+	path := filepath.Join(dir, "regular")
+	os.WriteFile(path, []byte("data"), 0600)
+
+	if _, err := ans.NewTcp0Anser(&net.UnixAddr{Name: path, Net: "unix"}, 1, 1); err == nil {
+		t.Errorf("Listen on a regular file should fail.")
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Regular file should be kept: %+v", err)
+	}
+}
+
+func TestStopListen(t *testing.T) {
+	path := filepath.Join(dir, "stop.sock")
+	a, err := ans.NewTcp0Anser(&net.UnixAddr{Name: path, Net: "unix"}, 1, 1)
+
+	if err != nil {
+		t.Fatalf("Failed to new Tcp0Anser: %+v", err)
+	}
+
+	done := make(chan bool)
+
+	go func() {
+		a.Listen()
+		close(done)
+	}()
+
+	if err = a.StopListen(); err != nil {
+		t.Fatalf("Failed to stop listening: %+v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Listen should return after StopListen.")
+	}
+
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Socket file should be removed: %+v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j32u4ukh/gos/ans"
+	"github.com/j32u4ukh/gos/ask"
+	"github.com/j32u4ukh/gos/base"
+	"github.com/j32u4ukh/gos/base/ghttp"
+	"github.com/j32u4ukh/gos/test/testutil"
+)
+
+const port int = 18938
+
+var anser *ans.WebSocketAnser
+var closes chan uint16
+
+func TestMain(m *testing.M) {
+	laddr, _ := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
+	a, err := ans.NewWebSocketAnser(laddr, 10, 10)
+
+	if err != nil {
+		fmt.Printf("Failed to new WebSocketAnser: %+v\n", err)
+		os.Exit(1)
+	}
+
+	closes = make(chan uint16, 10)
+	anser = a.(*ans.WebSocketAnser)
+	anser.HandlePath("/ws")
+	anser.Subprotocols = []string{"chat"}
+	anser.CheckOrigin = func(r *ghttp.Request) bool {
+		return r.Header.Get("Origin") != "http://evil.example"
+	}
+	anser.OnClose = func(cid int32, code uint16, reason string) {
+		closes <- code
+	}
+
+	// 回應收到的訊息
+	anser.SetWorkHandler(func(w *base.Work) {
+		w.Send()
+	})
+	testutil.Serve(anser)
+	os.Exit(m.Run())
+}
+
+func TestAskerEcho(t *testing.T) {
+	replies := make(chan string, 3)
+	laddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port}
+	a, err := ask.NewWebSocketAsker(1, laddr, 10, "/ws?room=1", nil)
+
+	if err != nil {
+		t.Fatalf("Failed to new WebSocketAsker: %+v", err)
+	}
+
+	asker := a.(*ask.WebSocketAsker)
+	asker.Subprotocols = []string{"json", "chat"}
+	asker.SetWorkHandler(func(w *base.Work) {
+		replies <- string(w.Body.GetData())
+		w.Finish()
+	})
+
+	if err := asker.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %+v", err)
+	}
+
+	if asker.Subprotocol != "chat" {
+		t.Errorf("Subprotocol: %s", asker.Subprotocol)
+	}
+
+	loop := testutil.Start(asker.Handler)
+	defer loop.Stop()
+
+	large := strings.Repeat("abcdefgh", 20000)
+	messages := []string{"hello", "", large}
+
+	for _, message := range messages {
+		loop.Do(func() { asker.WriteText(message) })
+
+		select {
+		case reply := <-replies:
+			if reply != message {
+				t.Errorf("reply length: %d, expected: %d", len(reply), len(message))
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeout waiting for reply.")
+		}
+	}
+}
+
+// 以原始連線完成交握，返回回應的狀態行
+func dial(t *testing.T, path string, header string) (net.Conn, *bufio.Reader, string) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: 127.0.0.1\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n%s\r\n", path, header)
+	reader := bufio.NewReader(conn)
+	status, _ := reader.ReadString('\n')
+	accept := ""
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		if err != nil || line == "\r\n" {
+			break
+		}
+
+		if strings.HasPrefix(line, "Sec-WebSocket-Accept:") {
+			accept = strings.TrimSpace(strings.TrimPrefix(line, "Sec-WebSocket-Accept:"))
+		}
+	}
+
+	if strings.HasPrefix(status, "HTTP/1.1 101") && accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept: %s", accept)
+	}
+
+	return conn, reader, strings.TrimSpace(status)
+}
+
+// 讀取一個未遮罩的幀
+func readFrame(t *testing.T, reader *bufio.Reader) (opcode byte, payload []byte) {
+	header := make([]byte, 2)
+
+	if _, err := io.ReadFull(reader, header); err != nil {
+		t.Fatalf("Failed to read frame: %+v", err)
+	}
+
+	length := int(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(reader, ext)
+		length = int(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(reader, ext)
+		length = int(binary.BigEndian.Uint64(ext))
+	}
+
+	if header[1]&0x80 != 0 {
+		t.Errorf("Server frame should not be masked.")
+	}
+
+	payload = make([]byte, length)
+	io.ReadFull(reader, payload)
+	return header[0] & 0x0F, payload
+}
+
+// 生成遮罩後的幀，fin 為 false 時為分段的幀
+func frame(opcode byte, payload []byte, fin bool) []byte {
+	data := base.EncodeWebSocketFrame(opcode, payload, true)
+
+	if !fin {
+		data[0] &^= 0x80
+	}
+
+	return data
+}
+
+func TestFragmentation(t *testing.T) {
+	conn, reader, status := dial(t, "/ws", "")
+	defer conn.Close()
+
+	if status != "HTTP/1.1 101 Switching Protocols" {
+		t.Fatalf("status: %s", status)
+	}
+
+	// 分段訊息之間可穿插控制幀
+	conn.Write(frame(base.WsBinary, []byte("hel"), false))
+	conn.Write(frame(base.WsPing, []byte("p"), true))
+	conn.Write(frame(base.WsContinuation, []byte("lo "), false))
+	conn.Write(frame(base.WsContinuation, []byte("world"), true))
+
+	opcode, payload := readFrame(t, reader)
+
+	if opcode != base.WsPong || string(payload) != "p" {
+		t.Errorf("opcode: %d, payload: %s", opcode, payload)
+	}
+
+	opcode, payload = readFrame(t, reader)
+
+	if opcode != base.WsBinary || string(payload) != "hello world" {
+		t.Errorf("opcode: %d, payload: %s", opcode, payload)
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	conn, reader, _ := dial(t, "/ws", "")
+	defer conn.Close()
+	conn.Write(frame(base.WsClose, base.EncodeClosePayload(base.CloseGoingAway, "bye"), true))
+	opcode, payload := readFrame(t, reader)
+	code, _, err := base.ParseClosePayload(payload)
+
+	if opcode != base.WsClose || code != base.CloseGoingAway || err != nil {
+		t.Errorf("opcode: %d, code: %d, err: %+v", opcode, code, err)
+	}
+
+	expectClose(t, base.CloseGoingAway)
+}
+
+func TestUnmaskedFrame(t *testing.T) {
+	conn, reader, _ := dial(t, "/ws", "")
+	defer conn.Close()
+	conn.Write(base.EncodeWebSocketFrame(base.WsText, []byte("hi"), false))
+	opcode, payload := readFrame(t, reader)
+	code, _, _ := base.ParseClosePayload(payload)
+
+	if opcode != base.WsClose || code != base.CloseProtocolError {
+		t.Errorf("opcode: %d, code: %d", opcode, code)
+	}
+
+	expectClose(t, base.CloseProtocolError)
+}
+
+func TestInvalidText(t *testing.T) {
+	conn, reader, _ := dial(t, "/ws", "")
+	defer conn.Close()
+	conn.Write(frame(base.WsText, []byte{0xff, 0xfe}, true))
+	_, payload := readFrame(t, reader)
+	code, _, _ := base.ParseClosePayload(payload)
+
+	if code != base.CloseInvalidPayload {
+		t.Errorf("code: %d", code)
+	}
+
+	expectClose(t, base.CloseInvalidPayload)
+}
+
+func TestRejectedHandshake(t *testing.T) {
+	tests := []struct {
+		path   string
+		header string
+		status string
+	}{
+		{"/other", "", "HTTP/1.1 404 Not Found"},
+		{"/ws", "Origin: http://evil.example\r\n", "HTTP/1.1 403 Forbidden"},
+	}
+
+	for _, tt := range tests {
+		conn, _, status := dial(t, tt.path, tt.header)
+		conn.Close()
+
+		if status != tt.status {
+			t.Errorf("path: %s, status: %s", tt.path, status)
+		}
+	}
+
+	// 缺少 Sec-WebSocket-Key
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: 127.0.0.1\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	status, _ := bufio.NewReader(conn).ReadString('\n')
+
+	if !strings.HasPrefix(status, "HTTP/1.1 400") {
+		t.Errorf("status: %s", status)
+	}
+}
+
+// 交握請求中不含換行的標頭超過 HttpMaxHeaderSize 時，回應 431，而非停止讀取
+func TestHandshakeTooLarge(t *testing.T) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+	if err != nil {
+		t.Fatalf("Failed to dial: %+v", err)
+	}
+
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nX-Long: %s", strings.Repeat("x", 20*1024))
+	status, _ := bufio.NewReader(conn).ReadString('\n')
+
+	if !strings.HasPrefix(status, "HTTP/1.1 431") {
+		t.Errorf("status: %s", status)
+	}
+}
+
+func expectClose(t *testing.T, expected uint16) {
+	select {
+	case code := <-closes:
+		if code != expected {
+			t.Errorf("OnClose code: %d, expected: %d", code, expected)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("Timeout waiting for OnClose.")
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"os"
 	"time"
 
 	"github.com/j32u4ukh/gos/define"
@@ -10,6 +11,38 @@ var GosConfig *Config
 
 type Config struct {
 	HttpAnserReadTimeout time.Duration
+	// keep-alive 連線的閒置超時(超過此時間未收到下一個請求，則關閉連線)
+	HttpKeepAliveTimeout time.Duration
+	// 單一 keep-alive 連線最多可處理的請求數
+	HttpMaxKeepAliveRequests int32
+	// 請求 Body 的長度上限(超過時回應 413)
+	HttpMaxBodySize int32
+	// 請求第一行與 Header 的總長度上限(超過時回應 431)，須小於連線的讀取緩衝(ConnBufferSize 個 MTU)
+	HttpMaxHeaderSize int32
+	// 請求 Body 保存於記憶體中的長度上限(超過的 Body 將暫存於檔案中)
+	HttpBodyMemoryLimit int32
+	// WebSocket 的閒置超時(閒置超過一半時間時，伺服器端將送出 Ping)
+	WebSocketReadTimeout time.Duration
+	// WebSocket 訊息的長度上限(超過時以 1009 關閉連線)
+	WebSocketMaxMessageSize int64
+	// Server-Sent Events 閒置超過此時間時，送出註解以維持連線
+	SSEKeepAliveInterval time.Duration
+	// UDP 虛擬連線的閒置超時(超過此時間未收到對方的 datagram，則視為斷線)
+	UdpReadTimeout time.Duration
+	// UDP 可靠訊息未收到確認時的重送間隔
+	UdpResendInterval time.Duration
+	// UDP 可靠訊息的重送次數上限(超過時視為斷線)
+	UdpMaxResends int32
+	// Unix domain socket 檔案的權限
+	UnixSocketMode os.FileMode
+	// Line 協定一行的長度上限(超過時斷線)
+	LineMaxLength int32
+	// Line 協定的閒置超時(0 表示不超時，適用於人工操作的管理介面)
+	LineReadTimeout time.Duration
+	// 自定義協定(base.RegisterCodec)的閒置超時(0 表示不超時)
+	CodecReadTimeout time.Duration
+	// TransData 讀取字串與 byte 陣列時的預設長度上限(超過時記錄錯誤，0 表示不限制)
+	TransDataMaxLength   int32
 	AnswerReadBuffer     int32
 	ConnBufferSize       int32
 	DisconnectTime       time.Duration
@@ -20,21 +53,46 @@ type Config struct {
 
 func init() {
 	GosConfig = &Config{
-		HttpAnserReadTimeout: 5000 * time.Millisecond,
-		AnswerReadBuffer:     64 * 1024,
-		ConnBufferSize:       10,
-		DisconnectTime:       time.Duration(3),
+		HttpAnserReadTimeout:     5000 * time.Millisecond,
+		HttpKeepAliveTimeout:     5000 * time.Millisecond,
+		HttpMaxKeepAliveRequests: 100,
+		HttpMaxBodySize:          32 * 1024 * 1024,
+		HttpMaxHeaderSize:        8 * 1024,
+		HttpBodyMemoryLimit:      1024 * 1024,
+		WebSocketReadTimeout:     60 * time.Second,
+		WebSocketMaxMessageSize:  16 * 1024 * 1024,
+		SSEKeepAliveInterval:     15 * time.Second,
+		UdpReadTimeout:           10 * time.Second,
+		UdpResendInterval:        200 * time.Millisecond,
+		UdpMaxResends:            10,
+		UnixSocketMode:           0660,
+		LineMaxLength:            4096,
+		LineReadTimeout:          0,
+		CodecReadTimeout:         5000 * time.Millisecond,
+		TransDataMaxLength:       0,
+		AnswerReadBuffer:         64 * 1024,
+		ConnBufferSize:           10,
+		DisconnectTime:           time.Duration(3),
 		AnswerConnectNumbers: map[define.SocketType]int32{
-			define.Tcp0: 10,
-			define.Http: 10,
+			define.Tcp0:      10,
+			define.Http:      10,
+			define.WebSocket: 10,
+			define.Udp:       10,
+			define.Line:      10,
 		},
 		AnswerWorkNumbers: map[define.SocketType]int32{
-			define.Tcp0: 10,
-			define.Http: 10,
+			define.Tcp0:      10,
+			define.Http:      10,
+			define.WebSocket: 10,
+			define.Udp:       10,
+			define.Line:      10,
 		},
 		AskerWorkNumbers: map[define.SocketType]int32{
-			define.Tcp0: 10,
-			define.Http: 10,
+			define.Tcp0:      10,
+			define.Http:      10,
+			define.WebSocket: 10,
+			define.Udp:       10,
+			define.Line:      10,
 		},
 	}
 }